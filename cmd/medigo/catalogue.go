@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Loads the drug catalogue or interaction rules from CSV, existing entries are updated
+func runCatalogue(args []string) error {
+
+	if len(args) == 0 {
+		return errors.New("catalogue needs a subcommand - drugs or interactions")
+	}
+
+	flags := flag.NewFlagSet("catalogue "+args[0], flag.ExitOnError)
+	input := flags.String("i", "", "CSV file to read, stdin when empty")
+	flags.Parse(args[1:])
+
+	var in io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	switch args[0] {
+	case "drugs":
+		drugs, err := models.ParseDrugCSV(in)
+		if err != nil {
+			return err
+		}
+
+		db, err := connectDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		result, err := store.NewStore(db.DB, nil).ImportDrugs(drugs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d new drugs and updated %d\n", result.Inserted, result.Updated)
+	case "interactions":
+		interactions, err := models.ParseInteractionCSV(in)
+		if err != nil {
+			return err
+		}
+
+		db, err := connectDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		result, err := store.NewStore(db.DB, nil).ImportDrugInteractions(interactions)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d new interaction rules and updated %d\n", result.Inserted, result.Updated)
+	default:
+		return fmt.Errorf("unknown catalogue subcommand %q", args[0])
+	}
+	return nil
+}
@@ -1,31 +1,17 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	_ "time/tzdata" // CLINIC_TIMEZONE must resolve on images without zoneinfo
 
-	"github.com/gorilla/mux"
 	"github.com/harshitrajsinha/medi-go/config"
 	driver "github.com/harshitrajsinha/medi-go/internal/db"
-	middleware "github.com/harshitrajsinha/medi-go/internal/middleware"
-	apiRoutesV1 "github.com/harshitrajsinha/medi-go/internal/routes/api/v1"
-	"github.com/harshitrajsinha/medi-go/internal/store"
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
-	"github.com/rs/cors"
-	"golang.org/x/crypto/bcrypt"
 )
 
-var db *driver.DBClient
-var rdb *redis.Client
-
 const dbDriver string = "postgres"
 
 type RedisConfig struct {
@@ -34,188 +20,116 @@ type RedisConfig struct {
 	Pass string
 }
 
-func init() {
+const usage = `Usage: medigo <command> [arguments]
+
+Commands:
+  serve                     start the API server (default)
+  migrate up                apply pending schema migrations
+  migrate down [-steps n]   revert the latest n migrations (default 1)
+  migrate status            list migrations and whether they are applied
+  seed                      load sample data (development only)
+  user create               create a doctor or staff account
+  user reset-password       set a new password for an account
+  export [-o file]          write accounts, patients, visits and their clinical, scheduling & billing
+                            records as JSON, without sessions, MFA secrets, API keys & attachment files
+  import [-i file]          load data written by export
+  catalogue drugs [-i file]         load the drug catalogue from CSV
+  catalogue interactions [-i file]  load drug interaction rules from CSV
+
+Run 'medigo <command> -h' for the flags of a command.
+`
 
-	var err error
+func main() {
 
 	_ = godotenv.Load() // load env from .env file to program's environment
 
-	var dbConnStr string
-	var redisConfig RedisConfig
-
-	switch os.Getenv("ENVIRONMENT") {
-	case "development":
-		{
-			// local database
-			localDBConfig, err := config.DBConfig()
-			if err != nil {
-				log.Fatal(err)
-			}
-			dbConnStr = fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=disable&connect_timeout=30", localDBConfig.User, localDBConfig.Pass, localDBConfig.Host, localDBConfig.Port, localDBConfig.Name)
-
-			// local redis (docker)
-			redisConfig.Host = "redis"
-			redisConfig.Pass = ""
-			redisConfig.Port = "6379"
-		}
-	case "production":
-		{
-			// cloud database
-			neonDbConfig, err := config.NeonDBConfig()
-			if err != nil {
-				log.Fatal(err)
-			}
-			dbConnStr = neonDbConfig.NeonConnStr
-
-			// cloud redis
-			redisConfiguartion, err := config.RedisConfig()
-			if err != nil {
-				log.Println(err)
-			}
-
-			redisConfig.Host = redisConfiguartion.Host
-			redisConfig.Pass = redisConfiguartion.Pass
-			redisConfig.Port = redisConfiguartion.Port
-		}
-	}
-	// Get database client
-	db, err = driver.InitDB(dbDriver, dbConnStr)
-	if err != nil {
-		log.Fatalf("Failed to connect to DB: %v", err)
+	command, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
 	}
 
-	// Load data into database
-	err = db.LoadDataToDatabase("schema.sql")
-	if err != nil {
-		log.Fatalf("Failed to SQL file %v", err)
-	} else {
-		log.Println("SQL file executed successfully!")
+	var err error
+	switch command {
+	case "serve":
+		err = runServe(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "seed":
+		err = runSeed(args)
+	case "user":
+		err = runUser(args)
+	case "export":
+		err = runExport(args)
+	case "import":
+		err = runImport(args)
+	case "catalogue":
+		err = runCatalogue(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
 
-	// setup redis connection
-	rdb, err = driver.InitRedis(redisConfig.Host, redisConfig.Pass, redisConfig.Port)
 	if err != nil {
-		log.Printf("Failed to connect to redis: %v", err)
+		fmt.Fprintln(os.Stderr, "medigo:", err)
+		os.Exit(1)
 	}
-
 }
 
-func main() {
-
-	var allowedOrigin string
-	defer db.Close()
-
-	// Setup mux server for routing
-	router := mux.NewRouter()
-
-	// Dependency Injection for modularity
-	patientStore := store.NewStore(db.DB, rdb)
-	apiRoutes := apiRoutesV1.NewAPIRoutes(patientStore)
-
-	// endpoint to check server health
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Server is functioning"})
-
-	}).Methods(http.MethodGet)
-
-	router.Use(middleware.OriginValidator)
-
-	// Public routes for patient details
-	router.HandleFunc("/api/v1/patients/{token_id}", apiRoutes.GetPatientByTokenID).Methods(http.MethodGet)
-
-	router.HandleFunc("/api/v1/login", apiRoutes.LoginHandler).Methods(http.MethodPost)
-	protectedRouter := router.PathPrefix("/api/v1").Subrouter() // creating subrouter for path "/" that will require authentication
-	protectedRouter.Use(middleware.AuthMiddleware)
-
-	// Protected Routes
-	protectedRouter.HandleFunc("/patients", apiRoutes.GetAllPatients).Methods(http.MethodGet)
-	protectedRouter.HandleFunc("/patients", apiRoutes.CreatePatient).Methods(http.MethodPost)
-	protectedRouter.HandleFunc("/patients/{token_id}", apiRoutes.UpdatePatient).Methods(http.MethodPut)
-	protectedRouter.HandleFunc("/patients/{token_id}", apiRoutes.UpdatePatientPartial).Methods(http.MethodPatch)
-	protectedRouter.HandleFunc("/patients/{token_id}", apiRoutes.DeletePatient).Methods(http.MethodDelete)
-	protectedRouter.HandleFunc("/doctors/{doctor_id}", apiRoutes.GetAllPatientsByDocID).Methods(http.MethodGet)
+// Connects to the database of the current ENVIRONMENT
+func connectDatabase() (*driver.DBClient, error) {
 
-	// Enable CORS
-	allowedOriginWebsite, err := config.AllowedOrigin()
-	if err != nil {
-		fmt.Println(err)
-		allowedOrigin = ""
-	}
-	allowedOrigin = allowedOriginWebsite.AllowedOrigin
-
-	handler := cors.New(cors.Options{
-		AllowedOrigins:   []string{allowedOrigin},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
-	}).Handler(router)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8000"
-	}
+	var dbConnStr string
 
-	server := &http.Server{
-		Addr:         ":" + port,
-		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	switch os.Getenv("ENVIRONMENT") {
+	case "development":
+		// local database
+		localDBConfig, err := config.DBConfig()
+		if err != nil {
+			return nil, err
+		}
+		dbConnStr = fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=disable&connect_timeout=30", localDBConfig.User, localDBConfig.Pass, localDBConfig.Host, localDBConfig.Port, localDBConfig.Name)
+	case "production":
+		// cloud database
+		neonDbConfig, err := config.NeonDBConfig()
+		if err != nil {
+			return nil, err
+		}
+		dbConnStr = neonDbConfig.NeonConnStr
+	default:
+		return nil, errors.New("ENVIRONMENT must be one of following - ['development', 'production']")
 	}
 
-	done := make(chan struct{})
-
-	// Delegate server startup to listen for interrupt signal and server shutdown
-	go func() {
-		log.Printf("Server starting on port %s", port)
-		log.Fatal(server.ListenAndServe())
-		close(done) // signal server has exited
-	}()
-
-	shutdownCtx, shutdownCancel := gracefulShutdown()
-	defer shutdownCancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	db, err := driver.InitDB(dbDriver, dbConnStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
-
-	<-done // Wait until ListenAndServe() exits
-	log.Println("Server exited")
-
+	return db, nil
 }
 
-func gracefulShutdown() (context.Context, context.CancelFunc) {
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
-
-	log.Println("Shutting down server...")
-
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	return shutdownCtx, shutdownCancel
-}
+// Connects to redis of the current ENVIRONMENT
+func connectRedis() (*redis.Client, error) {
 
-// function to generate hashed passwords and verify it
-func _() {
+	var redisConfig RedisConfig
 
-	password := []byte("mountain.lucy@medigo")
+	switch os.Getenv("ENVIRONMENT") {
+	case "development":
+		// local redis (docker)
+		redisConfig.Host = "redis"
+		redisConfig.Pass = ""
+		redisConfig.Port = "6379"
+	case "production":
+		// cloud redis
+		redisConfiguartion, err := config.RedisConfig()
+		if err != nil {
+			return nil, err
+		}
 
-	// Hashing the password
-	hashedPassword, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
-	if err != nil {
-		panic(err)
+		redisConfig.Host = redisConfiguartion.Host
+		redisConfig.Pass = redisConfiguartion.Pass
+		redisConfig.Port = redisConfiguartion.Port
 	}
 
-	fmt.Println("Hashed:", string(hashedPassword))
-
-	// Verifying a password
-	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte("mountain.lucy@medigo"))
-	if err != nil {
-		fmt.Println("Invalid password")
-	} else {
-		fmt.Println("Password is correct")
-	}
+	return driver.InitRedis(redisConfig.Host, redisConfig.Pass, redisConfig.Port)
 }
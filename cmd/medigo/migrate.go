@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// Applies, reverts or lists schema migrations
+func runMigrate(args []string) error {
+
+	if len(args) == 0 {
+		return errors.New("usage: medigo migrate <up|down|status>")
+	}
+
+	db, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp()
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is already up to date")
+		}
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		flags.Parse(args[1:])
+
+		reverted, err := db.MigrateDown(*steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations to revert")
+		}
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "status":
+		status, err := db.MigrationStatus()
+		if err != nil {
+			return err
+		}
+
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+		for _, state := range status {
+			appliedAt, note := "pending", ""
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if state.Modified {
+				note = "modified after it was applied"
+			}
+			fmt.Fprintf(table, "%04d\t%s\t%s\t%s\n", state.Version, state.Name, appliedAt, note)
+		}
+		return table.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected one of up, down or status", args[0])
+	}
+
+	return nil
+}
+
+// Loads sample data, refused outside development
+func runSeed(args []string) error {
+
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	flags.Parse(args)
+
+	db, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = db.SeedDatabase(os.Getenv("ENVIRONMENT")); err != nil {
+		return err
+	}
+	fmt.Println("Seed data loaded successfully!")
+	return nil
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/config"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/filestore"
+	middleware "github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	apiRoutesV1 "github.com/harshitrajsinha/medi-go/internal/routes/api/v1"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+	"github.com/rs/cors"
+	"golang.org/x/time/rate"
+)
+
+// Starts the API server, applying pending migrations first unless -skip-migrate is set
+func runServe(args []string) error {
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	skipMigrate := flags.Bool("skip-migrate", false, "do not apply pending migrations on startup")
+	flags.Parse(args)
+
+	var allowedOrigin string
+
+	// Get database client
+	db, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Bring database schema up to date
+	if !*skipMigrate {
+		applied, err := db.MigrateUp()
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		log.Printf("Database schema up to date, %d migration(s) applied", len(applied))
+	}
+
+	// Sample data is only loaded for local development
+	if os.Getenv("ENVIRONMENT") == "development" {
+		if err = db.SeedDatabase(os.Getenv("ENVIRONMENT")); err != nil {
+			return fmt.Errorf("failed to load seed data: %w", err)
+		}
+		log.Println("Seed data loaded successfully!")
+	}
+
+	// Load JWT signing keys
+	if err = loadSigningKeys(); err != nil {
+		return fmt.Errorf("failed to load JWT signing keys: %w", err)
+	}
+
+	// Load secret of report verification codes
+	if err = loadReportSecret(); err != nil {
+		return fmt.Errorf("failed to load report secret: %w", err)
+	}
+
+	// setup redis connection
+	rdb, err := connectRedis()
+	if err != nil {
+		log.Printf("Failed to connect to redis: %v", err)
+	}
+
+	// Setup mux server for routing
+	router := mux.NewRouter()
+
+	// Dependency Injection for modularity
+	patientStore := store.NewStore(db.DB, rdb)
+	apiRoutes := apiRoutesV1.NewAPIRoutes(patientStore)
+
+	// Access tokens are checked against the store's revocation denylist
+	auth.SetRevocationChecker(patientStore)
+
+	// API keys of integrations are resolved against the store
+	auth.SetAPIKeyAuthenticator(patientStore)
+
+	// Storage for patient attachments
+	fileStorage, err := openFileStorage()
+	if err != nil {
+		return fmt.Errorf("failed to open file storage: %w", err)
+	}
+	patientStore.SetFileStorage(fileStorage)
+
+	// endpoint to check server health
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Server is functioning"})
+
+	}).Methods(http.MethodGet)
+
+	router.Use(middleware.OriginValidator)
+
+	// Public keys for other services to verify MediGo tokens
+	router.HandleFunc("/.well-known/jwks.json", apiRoutes.JWKSHandler).Methods(http.MethodGet)
+
+	// Public route for patient details, verified by contact number and rate limited per client IP
+	publicLimiter := middleware.NewRateLimiter(rate.Every(2*time.Second), 5)
+	router.HandleFunc("/api/v1/patients/{token_id}/verify", publicLimiter.LimitByIP(apiRoutes.VerifyPatientAccess)).Methods(http.MethodPost)
+
+	router.HandleFunc("/api/v1/login", apiRoutes.LoginHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/login/mfa", apiRoutes.LoginMFAHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/login/mfa/enroll", apiRoutes.LoginMFAEnrollHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/token/refresh", apiRoutes.RefreshTokenHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/bootstrap", apiRoutes.BootstrapAdmin).Methods(http.MethodPost)
+	protectedRouter := router.PathPrefix("/api/v1").Subrouter() // creating subrouter for path "/" that will require authentication
+	protectedRouter.Use(middleware.AuthMiddleware)
+
+	// Role policies for protected routes
+	anyStaff := middleware.RequireRoles(models.RoleDoctor, models.RoleReceptionist, models.RoleAdmin)
+	receptionistOnly := middleware.RequireRoles(models.RoleReceptionist)
+	adminOnly := middleware.RequireRoles(models.RoleAdmin)
+	doctorOnly := middleware.RequireRoles(models.RoleDoctor)
+	clinicalStaff := middleware.RequireRoles(models.RoleDoctor, models.RoleReceptionist)
+	billingStaff := middleware.RequireRoles(models.RoleReceptionist, models.RoleAdmin)
+
+	// Scopes opening routes to API keys, routes without a scope deny API keys
+	readPatients := middleware.RequireScope(models.ScopePatientsRead)
+	writePatients := middleware.RequireScope(models.ScopePatientsWrite)
+	readDoctors := middleware.RequireScope(models.ScopeDoctorsRead)
+	readQueue := middleware.RequireScope(models.ScopeQueueRead)
+
+	// Protected Routes
+	protectedRouter.HandleFunc("/patients", readPatients(anyStaff(apiRoutes.GetAllPatients))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients", writePatients(receptionistOnly(apiRoutes.CreatePatient))).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/patients/{token_id}", readPatients(anyStaff(apiRoutes.GetPatientByTokenID))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}", writePatients(anyStaff(apiRoutes.UpdatePatient))).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/patients/{token_id}", writePatients(anyStaff(apiRoutes.UpdatePatientPartial))).Methods(http.MethodPatch)
+	protectedRouter.HandleFunc("/patients/{token_id}", receptionistOnly(apiRoutes.DeletePatient)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/patients/{token_id}/report.pdf", readPatients(anyStaff(apiRoutes.GetPatientReportPDF))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/reports/verify/{code}", readPatients(anyStaff(apiRoutes.VerifyReport))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/doctors", readDoctors(anyStaff(apiRoutes.GetAllDoctors))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}", readPatients(anyStaff(apiRoutes.GetAllPatientsByDocID))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/logout", anyStaff(apiRoutes.LogoutHandler)).Methods(http.MethodPost)
+
+	// Visit routes
+	protectedRouter.HandleFunc("/patients/{token_id}/encounters", readPatients(anyStaff(apiRoutes.GetPatientEncounters))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/encounters", clinicalStaff(apiRoutes.OpenEncounter)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/encounters", readPatients(anyStaff(apiRoutes.GetAllEncounters))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/encounters/{encounter_id}", readPatients(anyStaff(apiRoutes.GetEncounter))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/encounters/{encounter_id}", doctorOnly(apiRoutes.UpdateEncounter)).Methods(http.MethodPatch)
+	protectedRouter.HandleFunc("/encounters/{encounter_id}/close", clinicalStaff(apiRoutes.CloseEncounter)).Methods(http.MethodPost)
+
+	// Prescription routes
+	protectedRouter.HandleFunc("/patients/{token_id}/prescriptions", readPatients(anyStaff(apiRoutes.GetPatientPrescriptions))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/prescriptions", doctorOnly(apiRoutes.CreatePrescription)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/prescriptions/{prescription_id}", readPatients(anyStaff(apiRoutes.GetPrescription))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/prescriptions/{prescription_id}/print", anyStaff(apiRoutes.PrintPrescription)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/prescriptions/{prescription_id}/void", doctorOnly(apiRoutes.VoidPrescription)).Methods(http.MethodPost)
+
+	// Vitals routes
+	protectedRouter.HandleFunc("/patients/{token_id}/vitals", readPatients(anyStaff(apiRoutes.GetPatientVitals))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/vitals", clinicalStaff(apiRoutes.RecordVitals)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/vitals/ranges", anyStaff(apiRoutes.GetVitalRanges)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/vitals/ranges", adminOnly(apiRoutes.SetVitalRanges)).Methods(http.MethodPut)
+
+	// Lab routes
+	protectedRouter.HandleFunc("/patients/{token_id}/lab-orders", readPatients(anyStaff(apiRoutes.GetPatientLabOrders))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/lab-orders", doctorOnly(apiRoutes.CreateLabOrder)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/lab-orders", readPatients(anyStaff(apiRoutes.GetAllLabOrders))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/lab-orders/{lab_order_id}", readPatients(anyStaff(apiRoutes.GetLabOrder))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/lab-orders/{lab_order_id}/collect", clinicalStaff(apiRoutes.CollectLabOrder)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/lab-orders/{lab_order_id}/results", clinicalStaff(apiRoutes.RecordLabResults)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/lab-orders/{lab_order_id}/review", doctorOnly(apiRoutes.ReviewLabOrder)).Methods(http.MethodPost)
+
+	// Clinical note routes
+	protectedRouter.HandleFunc("/patients/{token_id}/notes", readPatients(anyStaff(apiRoutes.GetPatientNotes))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/notes", doctorOnly(apiRoutes.CreateNote)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/notes/{note_id}", readPatients(anyStaff(apiRoutes.GetNote))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/notes/{note_id}", doctorOnly(apiRoutes.UpdateNote)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/notes/{note_id}/sign", doctorOnly(apiRoutes.SignNote)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/notes/{note_id}/amendments", doctorOnly(apiRoutes.AmendNote)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/notes/{note_id}/versions", readPatients(anyStaff(apiRoutes.GetNoteVersions))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/notes/{note_id}/versions/{version:[0-9]+}", readPatients(anyStaff(apiRoutes.GetNoteVersion))).Methods(http.MethodGet)
+
+	// Attachment routes
+	protectedRouter.HandleFunc("/patients/{token_id}/attachments", readPatients(anyStaff(apiRoutes.GetPatientAttachments))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/attachments", clinicalStaff(apiRoutes.UploadAttachment)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/attachments/{attachment_id}", readPatients(anyStaff(apiRoutes.DownloadAttachment))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/attachments/{attachment_id}", clinicalStaff(apiRoutes.DeleteAttachment)).Methods(http.MethodDelete)
+
+	// Billing routes
+	protectedRouter.HandleFunc("/fees", anyStaff(apiRoutes.GetFees)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/fees", adminOnly(apiRoutes.CreateFee)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/fees/{fee_id}", adminOnly(apiRoutes.UpdateFee)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/encounters/{encounter_id}/invoices", billingStaff(apiRoutes.CreateInvoice)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/patients/{token_id}/invoices", billingStaff(apiRoutes.GetPatientInvoices)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/invoices", billingStaff(apiRoutes.GetAllInvoices)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/invoices/{invoice_id}", billingStaff(apiRoutes.GetInvoice)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/invoices/{invoice_id}/payments", billingStaff(apiRoutes.RecordPayment)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/invoices/{invoice_id}/void", billingStaff(apiRoutes.VoidInvoice)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/billing/cash-closing", billingStaff(apiRoutes.GetCashClosing)).Methods(http.MethodGet)
+
+	// Drug catalogue routes
+	protectedRouter.HandleFunc("/drugs", anyStaff(apiRoutes.GetDrugs)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/drugs/import", adminOnly(apiRoutes.ImportDrugs)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/drug-interactions/import", adminOnly(apiRoutes.ImportDrugInteractions)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/patients/{token_id}/medication-check", doctorOnly(apiRoutes.CheckPatientMedications)).Methods(http.MethodPost)
+
+	// Medical history routes
+	protectedRouter.HandleFunc("/patients/{token_id}/history", readPatients(anyStaff(apiRoutes.GetMedicalHistory))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/{record_type:allergies|conditions|surgeries|family-history}", readPatients(anyStaff(apiRoutes.GetHistoryRecords))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/patients/{token_id}/{record_type:allergies|conditions|surgeries|family-history}", clinicalStaff(apiRoutes.AddHistoryRecord)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/patients/{token_id}/{record_type:allergies|conditions|surgeries|family-history}/{record_id}", clinicalStaff(apiRoutes.UpdateHistoryRecord)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/patients/{token_id}/{record_type:allergies|conditions|surgeries|family-history}/{record_id}", clinicalStaff(apiRoutes.DeleteHistoryRecord)).Methods(http.MethodDelete)
+
+	// Scheduling routes
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/working-hours", anyStaff(apiRoutes.GetWorkingHours)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/working-hours", anyStaff(apiRoutes.SetWorkingHours)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/leave", anyStaff(apiRoutes.GetDoctorLeave)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/leave", anyStaff(apiRoutes.AddDoctorLeave)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/leave/{leave_id}", anyStaff(apiRoutes.DeleteDoctorLeave)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/slots", readDoctors(anyStaff(apiRoutes.GetDoctorSlots))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/agenda", readPatients(anyStaff(apiRoutes.GetDoctorAgenda))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/holidays", anyStaff(apiRoutes.GetHolidays)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/holidays", adminOnly(apiRoutes.AddHoliday)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/holidays/{date}", adminOnly(apiRoutes.DeleteHoliday)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/appointments", readPatients(anyStaff(apiRoutes.GetAllAppointments))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/appointments", writePatients(receptionistOnly(apiRoutes.BookAppointment))).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/appointments/{appointment_id}/reschedule", writePatients(receptionistOnly(apiRoutes.RescheduleAppointment))).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/appointments/{appointment_id}/cancel", writePatients(receptionistOnly(apiRoutes.CancelAppointment))).Methods(http.MethodPost)
+
+	// OPD queue routes
+	protectedRouter.HandleFunc("/queue", readPatients(anyStaff(apiRoutes.GetQueue))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/queue", writePatients(receptionistOnly(apiRoutes.IssueQueueToken))).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/queue/stream", readQueue(anyStaff(apiRoutes.QueueStream))).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/queue/next", doctorOnly(apiRoutes.CallNextPatient)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/queue/{queue_entry_id}/status", clinicalStaff(apiRoutes.UpdateQueueStatus)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/doctors/{doctor_id}/queue-prefix", adminOnly(apiRoutes.SetQueuePrefix)).Methods(http.MethodPut)
+
+	// Two-factor authentication routes
+	protectedRouter.HandleFunc("/mfa/enroll", anyStaff(apiRoutes.EnrollMFA)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/mfa/confirm", anyStaff(apiRoutes.ConfirmMFA)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/mfa/disable", anyStaff(apiRoutes.DisableMFA)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/mfa/recovery-codes", anyStaff(apiRoutes.RegenerateRecoveryCodes)).Methods(http.MethodPost)
+
+	// Account management routes
+	protectedRouter.HandleFunc("/accounts/{account_type:doctors|staff}", adminOnly(apiRoutes.GetAllAccounts)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/accounts/{account_type:doctors|staff}", adminOnly(apiRoutes.CreateAccount)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/accounts/{account_type:doctors|staff}/{account_id}", adminOnly(apiRoutes.UpdateAccount)).Methods(http.MethodPatch)
+	protectedRouter.HandleFunc("/accounts/{account_type:doctors|staff}/{account_id}/deactivate", adminOnly(apiRoutes.DeactivateAccount)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/accounts/{account_type:doctors|staff}/{account_id}/reactivate", adminOnly(apiRoutes.ReactivateAccount)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/accounts/{account_type:doctors|staff}/{account_id}/mfa", adminOnly(apiRoutes.SetAccountMFARequirement)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/accounts/{account_type:doctors|staff}/{account_id}/mfa", adminOnly(apiRoutes.ResetAccountMFA)).Methods(http.MethodDelete)
+
+	// API key management routes
+	protectedRouter.HandleFunc("/api-keys", adminOnly(apiRoutes.GetAllAPIKeys)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/api-keys", adminOnly(apiRoutes.CreateAPIKey)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/api-keys/{key_id}", adminOnly(apiRoutes.RevokeAPIKey)).Methods(http.MethodDelete)
+
+	// Login security routes
+	protectedRouter.HandleFunc("/security/login-attempts", adminOnly(apiRoutes.GetLoginAttempts)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/security/lockouts", adminOnly(apiRoutes.GetLoginLockouts)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/security/unlock", adminOnly(apiRoutes.UnlockLogin)).Methods(http.MethodPost)
+
+	// Enable CORS
+	allowedOriginWebsite, err := config.AllowedOrigin()
+	if err != nil {
+		fmt.Println(err)
+		allowedOrigin = ""
+	}
+	allowedOrigin = allowedOriginWebsite.AllowedOrigin
+
+	handler := cors.New(cors.Options{
+		AllowedOrigins:   []string{allowedOrigin},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key"},
+		AllowCredentials: true,
+	}).Handler(router)
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8000"
+	}
+
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Reload JWT signing keys on SIGHUP to rotate keys without downtime
+	go reloadSigningKeysOnSignal()
+
+	done := make(chan struct{})
+
+	// Delegate server startup to listen for interrupt signal and server shutdown
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		log.Fatal(server.ListenAndServe())
+		close(done) // signal server has exited
+	}()
+
+	shutdownCtx, shutdownCancel := gracefulShutdown()
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	<-done // Wait until ListenAndServe() exits
+	log.Println("Server exited")
+
+	return nil
+}
+
+func gracefulShutdown() (context.Context, context.CancelFunc) {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down server...")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	return shutdownCtx, shutdownCancel
+}
+
+// Loads JWT signing keys from JWT_KEYS_DIR, outside production a throwaway key is used when it is unset
+func loadSigningKeys() error {
+	jwtConfig, err := config.JWTConfig()
+	if err != nil {
+		return err
+	}
+
+	if jwtConfig.KeysDir == "" && os.Getenv("ENVIRONMENT") != "production" {
+		return auth.UseEphemeralSigningKey()
+	}
+	return auth.LoadSigningKeys(jwtConfig.KeysDir, jwtConfig.ActiveKID)
+}
+
+// Loads the secret report verification codes are signed with, outside production a throwaway secret is used when it is unset
+func loadReportSecret() error {
+	reportConfig, err := config.ReportConfig()
+	if err != nil {
+		return err
+	}
+
+	if reportConfig.Secret == "" && os.Getenv("ENVIRONMENT") != "production" {
+		return auth.UseEphemeralReportSecret()
+	}
+	return auth.SetReportSecret(reportConfig.Secret)
+}
+
+// Opens storage for attachments named by STORAGE_BACKEND, a local directory or an S3 compatible bucket
+func openFileStorage() (filestore.FileStorage, error) {
+	storageConfig, err := config.StorageConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch storageConfig.Backend {
+	case "local":
+		return filestore.NewLocalStorage(storageConfig.Dir)
+	case "s3":
+		s3Config, err := config.S3Config()
+		if err != nil {
+			return nil, err
+		}
+		return filestore.NewS3Storage(s3Config.Endpoint, s3Config.Region, s3Config.Bucket, s3Config.AccessKey, s3Config.SecretKey, s3Config.PathStyle)
+	default:
+		return nil, errors.New("STORAGE_BACKEND must be one of following - ['local', 's3']")
+	}
+}
+
+func reloadSigningKeysOnSignal() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	for range hangup {
+		jwtConfig, err := config.JWTConfig()
+		if err == nil && jwtConfig.KeysDir != "" {
+			err = auth.LoadSigningKeys(jwtConfig.KeysDir, jwtConfig.ActiveKID)
+		}
+		if err != nil {
+			log.Printf("Failed to reload JWT signing keys, keeping current keys: %v", err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Writes accounts, patients, visits and their clinical, scheduling and billing records as JSON, the output holds PHI and password hashes.
+// Sessions, MFA secrets, API keys and attachment files are not exported.
+func runExport(args []string) error {
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "", "file to write, stdout when empty")
+	flags.Parse(args)
+
+	db, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	data, err := store.NewStore(db.DB, nil).ExportData()
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(data); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d doctors, %d staff members, %d patients, %d visits and the records of %d other tables\n",
+		len(data.Doctors), len(data.Staff), len(data.Patients), len(data.Encounters), len(data.Records))
+	return nil
+}
+
+// Loads a file written by export, existing rows are left untouched
+func runImport(args []string) error {
+
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	input := flags.String("i", "", "file to read, stdin when empty")
+	flags.Parse(args)
+
+	var in io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	var data store.DataExport
+	if err := json.NewDecoder(in).Decode(&data); err != nil {
+		return fmt.Errorf("invalid export file: %w", err)
+	}
+
+	db, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	result, err := store.NewStore(db.DB, nil).ImportData(&data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d of %d doctors, %d of %d staff members, %d of %d patients and %d of %d visits, the rest already existed\n",
+		result.Doctors, len(data.Doctors), result.Staff, len(data.Staff), result.Patients, len(data.Patients), result.Encounters, len(data.Encounters))
+
+	tables := make([]string, 0, len(result.Records))
+	for table := range result.Records {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Printf("Imported %d new %s rows\n", result.Records[table], table)
+	}
+	return nil
+}
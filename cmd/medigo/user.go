@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Creates accounts and resets passwords
+func runUser(args []string) error {
+
+	if len(args) == 0 {
+		return errors.New("usage: medigo user <create|reset-password>")
+	}
+
+	switch args[0] {
+	case "create":
+		return runUserCreate(args[1:])
+	case "reset-password":
+		return runUserResetPassword(args[1:])
+	}
+	return fmt.Errorf("unknown user command %q, expected one of create or reset-password", args[0])
+}
+
+// Reads the password from the first line of stdin so it stays out of shell history
+func readPassword() (string, error) {
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func runUserCreate(args []string) error {
+
+	flags := flag.NewFlagSet("user create", flag.ExitOnError)
+	accountType := flags.String("type", models.AccountTypeStaff, "account type, 'doctors' or 'staff'")
+	fullname := flags.String("name", "", "full name of the account holder")
+	email := flags.String("email", "", "login email")
+	role := flags.String("role", "", "'receptionist' or 'admin' for staff accounts")
+	specialization := flags.String("specialization", "", "specialization of doctor accounts")
+	flags.Parse(args)
+
+	accountReq := models.Account{
+		Fullname:       strings.TrimSpace(*fullname),
+		Email:          strings.ToLower(strings.TrimSpace(*email)),
+		Role:           strings.TrimSpace(*role),
+		Specialization: strings.TrimSpace(*specialization),
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	accountReq.Password = password
+
+	if err = models.ValidateAccountReq(*accountType, accountReq); err != nil {
+		return err
+	}
+
+	passwordHash, err := auth.HashPassword(accountReq.Password)
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	accountID, err := store.NewStore(db.DB, nil).CreateAccount(*accountType, &accountReq, passwordHash)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Account %s created in %s\n", accountID, *accountType)
+	return nil
+}
+
+func runUserResetPassword(args []string) error {
+
+	flags := flag.NewFlagSet("user reset-password", flag.ExitOnError)
+	accountType := flags.String("type", models.AccountTypeStaff, "account type, 'doctors' or 'staff'")
+	email := flags.String("email", "", "login email of the account")
+	flags.Parse(args)
+
+	if strings.TrimSpace(*email) == "" {
+		return errors.New("-email is required")
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	// reuse account validation for the password rules
+	body, _ := json.Marshal(map[string]string{"password": password})
+	if err = models.ValidateAccountPatchReq(*accountType, body); err != nil {
+		return err
+	}
+
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	accountID, err := store.NewStore(db.DB, nil).ResetPassword(*accountType, strings.TrimSpace(*email), passwordHash)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Password reset for account %s, existing sessions were signed out\n", accountID)
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -28,6 +29,53 @@ type origin struct {
 	AllowedOrigin string `envconfig:"ALLOWED_ORIGIN"`
 }
 
+type tokenTTL struct {
+	AccessTokenTTL  time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"15m"`
+	RefreshTokenTTL time.Duration `envconfig:"REFRESH_TOKEN_TTL" default:"12h"`
+}
+
+type bootstrap struct {
+	BootstrapToken string `envconfig:"BOOTSTRAP_TOKEN"`
+}
+
+type proxy struct {
+	TrustProxy bool `envconfig:"TRUST_PROXY" default:"false"`
+	ProxyHops  int  `envconfig:"TRUSTED_PROXY_HOPS" default:"1"` // reverse proxies in front of the server, each appends to X-Forwarded-For
+}
+
+type mfa struct {
+	Issuer string `envconfig:"MFA_ISSUER" default:"MediGo"`
+}
+
+type jwtKeys struct {
+	KeysDir   string `envconfig:"JWT_KEYS_DIR"`
+	ActiveKID string `envconfig:"JWT_ACTIVE_KID"`
+}
+
+type clinic struct {
+	Name     string `envconfig:"CLINIC_NAME" default:"MediGo"`
+	Timezone string `envconfig:"CLINIC_TIMEZONE" default:"Asia/Kolkata"`
+}
+
+type report struct {
+	Secret string `envconfig:"REPORT_SECRET"` // signs verification codes printed on patient reports
+}
+
+type fileStorage struct {
+	Backend       string `envconfig:"STORAGE_BACKEND" default:"local"` // local or s3
+	Dir           string `envconfig:"STORAGE_DIR" default:"uploads"`
+	MaxUploadSize int64  `envconfig:"MAX_UPLOAD_SIZE" default:"10485760"` // bytes
+}
+
+type s3Storage struct {
+	Endpoint  string `envconfig:"S3_ENDPOINT"` // e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Region    string `envconfig:"S3_REGION" default:"us-east-1"`
+	Bucket    string `envconfig:"S3_BUCKET"`
+	AccessKey string `envconfig:"S3_ACCESS_KEY"`
+	SecretKey string `envconfig:"S3_SECRET_KEY"`
+	PathStyle bool   `envconfig:"S3_PATH_STYLE" default:"true"` // MinIO serves buckets as paths
+}
+
 // helper to avoid repetition
 func loadConfig[T any](cfg *T, desc string) error {
 	if err := envconfig.Process("", cfg); err != nil { // load env from program's environment to declared struct
@@ -55,3 +103,48 @@ func AllowedOrigin() (*origin, error) {
 	var c origin
 	return &c, loadConfig(&c, "origin website")
 }
+
+func TokenConfig() (*tokenTTL, error) {
+	var c tokenTTL
+	return &c, loadConfig(&c, "token lifetime")
+}
+
+func BootstrapConfig() (*bootstrap, error) {
+	var c bootstrap
+	return &c, loadConfig(&c, "admin bootstrap token")
+}
+
+func ProxyConfig() (*proxy, error) {
+	var c proxy
+	return &c, loadConfig(&c, "reverse proxy")
+}
+
+func MFAConfig() (*mfa, error) {
+	var c mfa
+	return &c, loadConfig(&c, "two-factor authentication")
+}
+
+func JWTConfig() (*jwtKeys, error) {
+	var c jwtKeys
+	return &c, loadConfig(&c, "jwt signing keys")
+}
+
+func ClinicConfig() (*clinic, error) {
+	var c clinic
+	return &c, loadConfig(&c, "clinic")
+}
+
+func ReportConfig() (*report, error) {
+	var c report
+	return &c, loadConfig(&c, "patient report")
+}
+
+func StorageConfig() (*fileStorage, error) {
+	var c fileStorage
+	return &c, loadConfig(&c, "file storage")
+}
+
+func S3Config() (*s3Storage, error) {
+	var c s3Storage
+	return &c, loadConfig(&c, "s3 storage")
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Prefix of every API key, makes leaked keys easy to spot in logs and scanners
+const apiKeyPrefix = "mgo_"
+
+// Account an API key acts for, limited to the key's scopes
+type APIKeyPrincipal struct {
+	KeyID   uuid.UUID
+	OwnerID uuid.UUID
+	Email   string
+	Role    string
+	Scopes  []string
+}
+
+// Resolves API key hashes to their principal, nil principal means unknown, revoked or expired
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(keyHash string, clientIP string) (*APIKeyPrincipal, error)
+}
+
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// Registers the lookup used while verifying API keys
+func SetAPIKeyAuthenticator(authenticator APIKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// Returns a new API key and the hash to persist for it
+func GenerateAPIKey() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	apiKey := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return apiKey, HashToken(apiKey), nil
+}
+
+func VerifyAPIKey(apiKey string, clientIP string) (*APIKeyPrincipal, error) {
+
+	if apiKeyAuthenticator == nil {
+		return nil, errors.New("api keys are not enabled")
+	}
+
+	principal, err := apiKeyAuthenticator.AuthenticateAPIKey(HashToken(strings.TrimSpace(apiKey)), clientIP)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, errors.New("invalid, revoked or expired api key")
+	}
+	return principal, nil
+}
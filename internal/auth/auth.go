@@ -1,56 +1,82 @@
 package auth
 
 import (
-	"os"
+	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/config"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type Claims struct {
-	Email string `json:"email"`
-	jwt.StandardClaims
-}
-
 type CustomClaims struct {
-	Email  string    `json:"email"`
-	UserID uuid.UUID `json:"userid"`
+	Email    string    `json:"email"`
+	UserID   uuid.UUID `json:"userid"`
+	Role     string    `json:"role"`
+	FamilyID uuid.UUID `json:"fid"` // refresh token family the access token was issued from
 	jwt.StandardClaims
 }
 
-func generateKey() []byte {
-	jwtKeyString := os.Getenv("JWT_KEY")
-	return []byte(jwtKeyString)
-}
-
 func CheckPassowrd(hashedPassword string, password string) error {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err
 }
 
-func GenerateToken(email string, userId uuid.UUID) (string, error) {
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// Spends the same time as CheckPassowrd so unknown emails cannot be told apart by response time
+func CheckDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+// Returns lifetime of access and refresh tokens, falling back to defaults
+func TokenLifetime() (time.Duration, time.Duration) {
+	accessTTL, refreshTTL := 15*time.Minute, 12*time.Hour
+
+	tokenConfig, err := config.TokenConfig()
+	if err == nil {
+		accessTTL, refreshTTL = tokenConfig.AccessTokenTTL, tokenConfig.RefreshTokenTTL
+	}
+	return accessTTL, refreshTTL
+}
+
+func GenerateToken(email string, userId uuid.UUID, role string, familyID uuid.UUID) (string, error) {
 
-	expiration := time.Now().Add(30 * time.Minute) // Expiration set as 30 minute
+	accessTTL, _ := TokenLifetime()
+	expiration := time.Now().Add(accessTTL)
 
 	claims := &CustomClaims{
-		Email:  email,
-		UserID: userId,
+		Email:    email,
+		UserID:   userId,
+		Role:     role,
+		FamilyID: familyID,
 		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
 			ExpiresAt: expiration.Unix(),
 			IssuedAt:  time.Now().Unix(),
 			Subject:   email,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Load JWT key
-	jwtKeyString := os.Getenv("JWT_KEY")
-
-	signedToken, err := token.SignedString([]byte(jwtKeyString))
+	// Sign with the active key of the key ring
+	signedToken, err := signClaims(claims)
 	if err != nil {
 		return "", err
 	}
@@ -58,17 +84,39 @@ func GenerateToken(email string, userId uuid.UUID) (string, error) {
 	return signedToken, nil
 }
 
-func VerifyToken(authHeader string) (*Claims, error) {
+func VerifyToken(authHeader string) (*CustomClaims, error) {
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return generateKey(), nil
-	})
+	claims := &CustomClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey)
 
-	if !token.Valid {
+	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// challenge tokens of the MFA login step are not access tokens
+	if claims.Audience != "" {
+		return nil, errors.New("token is not an access token")
+	}
+
+	// tokens issued before roles were added to the claims cannot be authorized
+	if claims.Role == "" {
+		return nil, errors.New("token does not carry a role")
+	}
+
+	// check token, its family and its user against the denylist
+	if revocationChecker != nil {
+		revoked, err := revocationChecker.IsTokenRevoked(claims.Id, claims.FamilyID.String(), claims.UserID.String())
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, errors.New("token has been revoked")
+		}
+	}
 
 	return claims, nil
 }
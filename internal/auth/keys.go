@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Key files in the keys directory are named after their key ID:
+//
+//	<kid>.pem      PKCS#8 (or PKCS#1 RSA) private key, can sign and verify
+//	<kid>.pub.pem  PKIX public key of a retired key, only verifies tokens it signed earlier
+//
+// Rotation: add the new private key, point JWT_ACTIVE_KID at it and keep the previous
+// key (private or public only) until tokens signed with it have expired.
+type signingKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+type keyRing struct {
+	mu     sync.RWMutex
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var signingKeys keyRing
+
+// Loads signing keys from dir, activeKID selects the signing key (defaults to the last private key by name)
+func LoadSigningKeys(dir string, activeKID string) error {
+
+	if dir == "" {
+		return errors.New("JWT_KEYS_DIR is not set")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read jwt keys dir: %w", err)
+	}
+
+	keys := make(map[string]*signingKey)
+	var privateKIDs []string
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".pem") {
+			continue
+		}
+
+		pemData, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("read jwt key %s: %w", name, err)
+		}
+
+		var key *signingKey
+		if kid, isPublic := strings.CutSuffix(name, ".pub.pem"); isPublic {
+			key, err = parsePublicKey(kid, pemData)
+		} else {
+			key, err = parsePrivateKey(strings.TrimSuffix(name, ".pem"), pemData)
+			if err == nil {
+				privateKIDs = append(privateKIDs, key.kid)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("parse jwt key %s: %w", name, err)
+		}
+		if _, exists := keys[key.kid]; exists && key.privateKey == nil {
+			continue // private key of the same kid already verifies
+		}
+		keys[key.kid] = key
+	}
+
+	if len(privateKIDs) == 0 {
+		return fmt.Errorf("no private signing key found in %s", dir)
+	}
+
+	if activeKID == "" {
+		sort.Strings(privateKIDs)
+		activeKID = privateKIDs[len(privateKIDs)-1]
+	}
+	active, exists := keys[activeKID]
+	if !exists || active.privateKey == nil {
+		return fmt.Errorf("active jwt key %q has no private key in %s", activeKID, dir)
+	}
+
+	signingKeys.mu.Lock()
+	signingKeys.keys = keys
+	signingKeys.active = active
+	signingKeys.mu.Unlock()
+
+	log.Printf("Loaded %d jwt keys, signing with kid %s (%s)", len(keys), active.kid, active.method.Alg())
+	return nil
+}
+
+// Generates a throwaway Ed25519 signing key, tokens do not survive a restart
+func UseEphemeralSigningKey() error {
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	key := &signingKey{kid: "ephemeral", method: jwt.SigningMethodEdDSA, privateKey: privateKey, publicKey: publicKey}
+
+	signingKeys.mu.Lock()
+	signingKeys.keys = map[string]*signingKey{key.kid: key}
+	signingKeys.active = key
+	signingKeys.mu.Unlock()
+
+	log.Println("Using ephemeral jwt signing key, set JWT_KEYS_DIR to persist tokens across restarts")
+	return nil
+}
+
+func parsePrivateKey(kid string, pemData []byte) (*signingKey, error) {
+
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		if privateKey.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, privateKey: privateKey, publicKey: &privateKey.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, privateKey: privateKey, publicKey: privateKey.Public()}, nil
+	}
+	return nil, errors.New("only RSA and Ed25519 keys are supported")
+}
+
+func parsePublicKey(kid string, pemData []byte) (*signingKey, error) {
+
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch publicKey := parsed.(type) {
+	case *rsa.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, publicKey: publicKey}, nil
+	case ed25519.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, publicKey: publicKey}, nil
+	}
+	return nil, errors.New("only RSA and Ed25519 keys are supported")
+}
+
+// Signs claims with the active key, recording its kid in the token header
+func signClaims(claims jwt.Claims) (string, error) {
+
+	signingKeys.mu.RLock()
+	active := signingKeys.active
+	signingKeys.mu.RUnlock()
+
+	if active == nil {
+		return "", errors.New("no jwt signing key loaded")
+	}
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.privateKey)
+}
+
+// Resolves verification key from the token's kid, rejecting algorithms the key was not made for
+func verificationKey(token *jwt.Token) (interface{}, error) {
+
+	kid, _ := token.Header["kid"].(string)
+
+	signingKeys.mu.RLock()
+	key, exists := signingKeys.keys[kid]
+	signingKeys.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown jwt key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+	return key.publicKey, nil
+}
+
+// Returns JSON Web Key Set of every key that can verify tokens
+func JWKS() map[string]interface{} {
+
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+
+	kids := make([]string, 0, len(signingKeys.keys))
+	for kid := range signingKeys.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := make([]map[string]string, 0, len(kids))
+	for _, kid := range kids {
+		key := signingKeys.keys[kid]
+		jwk := map[string]string{"kid": kid, "use": "sig", "alg": key.method.Alg()}
+
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		jwks = append(jwks, jwk)
+	}
+
+	return map[string]interface{}{"keys": jwks}
+}
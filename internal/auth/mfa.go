@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+// Audience of challenge tokens issued between password and OTP step of login
+const mfaAudience = "mfa"
+
+// Issues a short lived token proving the password step of login succeeded
+func GenerateMFAToken(email string, userId uuid.UUID, role string) (string, error) {
+
+	claims := &CustomClaims{
+		Email:  email,
+		UserID: userId,
+		Role:   role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Audience:  mfaAudience,
+			ExpiresAt: time.Now().Add(5 * time.Minute).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Subject:   email,
+		},
+	}
+
+	return signClaims(claims)
+}
+
+// Verifies a token issued by GenerateMFAToken, access tokens are not accepted
+func VerifyMFAToken(tokenString string) (*CustomClaims, error) {
+
+	claims := &CustomClaims{}
+	token, err := jwt.ParseWithClaims(strings.TrimSpace(tokenString), claims, verificationKey)
+
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.Audience != mfaAudience {
+		return nil, errors.New("invalid mfa token")
+	}
+
+	return claims, nil
+}
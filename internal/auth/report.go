@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"log"
+	"sync"
+)
+
+// Shortest secret accepted for signing report verification codes
+const minReportSecretLength = 32
+
+var reportSecret struct {
+	mu  sync.RWMutex
+	key []byte
+}
+
+// Sets the secret report verification codes are signed with
+func SetReportSecret(secret string) error {
+	if len(secret) < minReportSecretLength {
+		return errors.New("report secret must be at least 32 characters")
+	}
+
+	reportSecret.mu.Lock()
+	reportSecret.key = []byte(secret)
+	reportSecret.mu.Unlock()
+	return nil
+}
+
+// Generates a throwaway report secret, codes issued before a restart still verify but cannot be reproduced
+func UseEphemeralReportSecret() error {
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	reportSecret.mu.Lock()
+	reportSecret.key = key
+	reportSecret.mu.Unlock()
+
+	log.Println("Using ephemeral report secret, set REPORT_SECRET to keep verification codes stable across restarts")
+	return nil
+}
+
+// Returns the HMAC-SHA256 of data keyed with the report secret
+func SignReport(data []byte) ([]byte, error) {
+
+	reportSecret.mu.RLock()
+	defer reportSecret.mu.RUnlock()
+
+	if reportSecret.key == nil {
+		return nil, errors.New("report secret is not set")
+	}
+	mac := hmac.New(sha256.New, reportSecret.key)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
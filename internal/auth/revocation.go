@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Denylist consulted by VerifyToken for revoked token IDs
+type RevocationChecker interface {
+	IsTokenRevoked(tokenIDs ...string) (bool, error)
+}
+
+var revocationChecker RevocationChecker
+
+// Registers the denylist used while verifying access tokens
+func SetRevocationChecker(checker RevocationChecker) {
+	revocationChecker = checker
+}
+
+// Returns a new opaque refresh token and the hash to persist for it
+func GenerateRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// Hash of an opaque token, only the hash is ever stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
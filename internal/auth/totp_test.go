@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// base32 of the RFC 6238 SHA1 test secret "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+
+	// RFC 6238 appendix B SHA1 vectors, last six of the eight digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := totpCode(rfc6238Secret, test.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("totpCode(%d) error = %v", test.unix, err)
+		}
+		if code != test.code {
+			t.Errorf("totpCode(%d) = %s, want %s", test.unix, code, test.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+
+	// code of step 37037036, shown from 1111111080 to 1111111109
+	const issued int64 = 1111111109
+	const step = issued / totpPeriod
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		now    int64
+		valid  bool
+	}{
+		{"current step", rfc6238Secret, "081804", issued, true},
+		{"one step late", rfc6238Secret, "081804", issued + totpPeriod, true},
+		{"one step early", rfc6238Secret, "081804", issued - totpPeriod, true},
+		{"two steps late", rfc6238Secret, "081804", issued + 2*totpPeriod, false},
+		{"two steps early", rfc6238Secret, "081804", issued - 2*totpPeriod, false},
+		{"spaces ignored", rfc6238Secret, " 081 804 ", issued, true},
+		{"lower case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "081804", issued, true},
+		{"wrong code", rfc6238Secret, "081805", issued, false},
+		{"eight digit code", rfc6238Secret, "07081804", issued, false},
+		{"short code", rfc6238Secret, "81804", issued, false},
+		{"invalid secret", "not base32!", "081804", issued, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, ok := ValidateTOTP(test.secret, test.code, time.Unix(test.now, 0))
+			if ok != test.valid {
+				t.Fatalf("ValidateTOTP() ok = %v, want %v", ok, test.valid)
+			}
+			if ok && matched != step {
+				t.Errorf("ValidateTOTP() step = %d, want %d", matched, step)
+			}
+		})
+	}
+}
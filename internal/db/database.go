@@ -3,6 +3,7 @@ package driver
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -52,12 +53,18 @@ func InitDB(dbDriver string, connString string) (*DBClient, error) {
 
 }
 
-// Function to load data to database via schema file
-func (rec *DBClient) LoadDataToDatabase(filename string) error {
+var ErrSeedNotAllowed = errors.New("sample data can only be loaded when ENVIRONMENT is 'development'")
+
+// Function to load sample data to database, refuses to run outside development
+func (rec *DBClient) SeedDatabase(environment string) error {
+
+	if environment != "development" {
+		return ErrSeedNotAllowed
+	}
 
 	// Read file content
-	sqlFile, err := store.SchemaFS.ReadFile(filename)
-	fmt.Println("...loading schema file")
+	sqlFile, err := store.SeedFS.ReadFile("seed.sql")
+	fmt.Println("...loading seed file")
 	if err != nil {
 		return err
 	}
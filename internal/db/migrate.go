@@ -0,0 +1,298 @@
+package driver
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Key of the advisory lock held while migrating, keeps concurrent instances from migrating twice
+const migrationLockKey int64 = 7246351
+
+// Migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+type MigrationState struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+	Modified  bool       `json:"modified"` // up file changed after it was applied
+}
+
+// Reads embedded migrations ordered by version
+func loadMigrations() ([]Migration, error) {
+
+	entries, err := fs.ReadDir(store.MigrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+
+		content, err := store.MigrationsFS.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has mismatched names %q and %q", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Runs fn on a single connection holding the migration lock
+func (rec *DBClient) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+
+	conn, err := rec.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); unlockErr != nil {
+			log.Println("Error while releasing migration lock ", unlockErr)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migration (
+		version BIGINT NOT NULL UNIQUE PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// Returns checksums of applied migrations by version
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]MigrationState, map[int64]string, error) {
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migration")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[int64]MigrationState)
+	checksums := make(map[int64]string)
+	for rows.Next() {
+		var state MigrationState
+		var appliedAt time.Time
+		var sum string
+		if err := rows.Scan(&state.Version, &state.Name, &sum, &appliedAt); err != nil {
+			return nil, nil, err
+		}
+		state.Applied = true
+		state.AppliedAt = &appliedAt
+		states[state.Version] = state
+		checksums[state.Version] = sum
+	}
+	return states, checksums, rows.Err()
+}
+
+// Runs a migration script and updates the tracking table in one transaction
+func runMigration(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	script := migration.down
+	if up {
+		script = migration.up
+	}
+
+	if _, err = tx.ExecContext(ctx, script); err == nil {
+		if up {
+			_, err = tx.ExecContext(ctx, "INSERT INTO schema_migration (version, name, checksum) VALUES ($1, $2, $3)", migration.Version, migration.Name, checksum(migration.up))
+		} else {
+			_, err = tx.ExecContext(ctx, "DELETE FROM schema_migration WHERE version=$1", migration.Version)
+		}
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Println("Transaction rollback error: ", rbErr)
+		}
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// Applies every pending migration in version order
+func (rec *DBClient) MigrateUp() ([]Migration, error) {
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var applied []Migration
+	err = rec.withMigrationLock(ctx, func(conn *sql.Conn) error {
+
+		states, checksums, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := states[migration.Version]; ok {
+				if checksums[migration.Version] != checksum(migration.up) {
+					log.Printf("Warning: migration %d_%s was modified after it was applied", migration.Version, migration.Name)
+				}
+				continue
+			}
+			if err := runMigration(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Reverts the latest applied migrations, one per step
+func (rec *DBClient) MigrateDown(steps int) ([]Migration, error) {
+
+	if steps <= 0 {
+		return nil, errors.New("number of migrations to revert must be positive")
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var reverted []Migration
+	err = rec.withMigrationLock(ctx, func(conn *sql.Conn) error {
+
+		states, _, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := states[migration.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			log.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Lists every known migration and whether it has been applied
+func (rec *DBClient) MigrationStatus() ([]MigrationState, error) {
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+	defer cancel()
+
+	var status []MigrationState
+	err = rec.withMigrationLock(ctx, func(conn *sql.Conn) error {
+
+		states, checksums, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			state, ok := states[migration.Version]
+			if !ok {
+				state = MigrationState{Version: migration.Version, Name: migration.Name}
+			} else {
+				state.Modified = checksums[migration.Version] != checksum(migration.up)
+				delete(states, migration.Version)
+			}
+			status = append(status, state)
+		}
+
+		// applied migrations whose files are no longer embedded
+		for _, state := range states {
+			status = append(status, state)
+		}
+		sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+		return nil
+	})
+
+	return status, err
+}
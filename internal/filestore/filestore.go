@@ -0,0 +1,16 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrFileNotFound = errors.New("file not found in storage")
+
+// Storage for uploaded files, keys are slash separated paths chosen by the caller
+type FileStorage interface {
+	Put(ctx context.Context, key string, content []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error // deleting a missing file is not an error
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/harshitrajsinha/medi-go/internal/auth"
 )
 
@@ -13,12 +15,35 @@ import (
 
 type Key string
 
-const contextKey Key = "email"
+const (
+	contextKey  Key = "email"
+	userIDKey   Key = "userid"
+	userRoleKey Key = "role"
+	claimsKey   Key = "claims"
+	apiKeyKey   Key = "apikey"
+	scopeKey    Key = "scope"
+)
 
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		authHeader := r.Header.Get("Authorization")
+
+		// integrations authenticate with an API key instead of a user session
+		if apiKey := apiKeyFromRequest(r); apiKey != "" {
+			principal, err := auth.VerifyAPIKey(apiKey, ClientIP(r))
+			if err != nil {
+				response(w, http.StatusUnauthorized, "Invalid API key", fmt.Sprintf("Invalid API key: %v", err))
+				return
+			}
+			ctx := context.WithValue(r.Context(), contextKey, principal.Email)
+			ctx = context.WithValue(ctx, userIDKey, principal.OwnerID)
+			ctx = context.WithValue(ctx, userRoleKey, principal.Role)
+			ctx = context.WithValue(ctx, apiKeyKey, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		if authHeader == "" {
 			response(w, http.StatusUnauthorized, "Authorization header required", "Authorization header missing")
 			return
@@ -31,16 +56,61 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		ctx := context.WithValue(r.Context(), contextKey, claims.Email)
+		ctx = context.WithValue(ctx, userIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, userRoleKey, claims.Role)
+		ctx = context.WithValue(ctx, claimsKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 
 	})
 
 }
 
+// Returns email of the authenticated user
+func EmailFromContext(ctx context.Context) string {
+	email, _ := ctx.Value(contextKey).(string)
+	return email
+}
+
+// Returns ID of the authenticated user
+func UserIDFromContext(ctx context.Context) uuid.UUID {
+	userID, _ := ctx.Value(userIDKey).(uuid.UUID)
+	return userID
+}
+
+// Returns role of the authenticated user
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(userRoleKey).(string)
+	return role
+}
+
+// Returns verified token claims of the authenticated user
+func ClaimsFromContext(ctx context.Context) *auth.CustomClaims {
+	claims, _ := ctx.Value(claimsKey).(*auth.CustomClaims)
+	return claims
+}
+
+// Returns the API key the request was authenticated with, nil for user sessions
+func APIKeyFromContext(ctx context.Context) *auth.APIKeyPrincipal {
+	principal, _ := ctx.Value(apiKeyKey).(*auth.APIKeyPrincipal)
+	return principal
+}
+
+// Reads an API key from the X-API-Key header or an "ApiKey" authorization scheme
+func apiKeyFromRequest(r *http.Request) string {
+	if apiKey := strings.TrimSpace(r.Header.Get("X-API-Key")); apiKey != "" {
+		return apiKey
+	}
+	scheme, credential, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "ApiKey") {
+		return strings.TrimSpace(credential)
+	}
+	return ""
+}
+
 func response(w http.ResponseWriter, code int, message string, logMessage string) {
 
-	w.WriteHeader(http.StatusUnauthorized)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(struct {
 		Code    int    `json:"code"`
 		Message string `json:"message"`
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Middleware to restrict a route to the listed roles.
+// Must be used on routes that are already behind AuthMiddleware.
+// API keys are denied unless an outer RequireScope granted access.
+func RequireRoles(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+
+			if principal := APIKeyFromContext(r.Context()); principal != nil {
+				if granted, _ := r.Context().Value(scopeKey).(bool); !granted {
+					response(w, http.StatusForbidden, "API keys are not allowed to perform this action", fmt.Sprintf("API key %s denied access to %s %s", principal.KeyID, r.Method, r.URL.Path))
+					return
+				}
+			}
+
+			role := RoleFromContext(r.Context())
+			if !HasRole(role, roles...) {
+				response(w, http.StatusForbidden, "You are not allowed to perform this action", fmt.Sprintf("Role '%s' denied access to %s %s", role, r.Method, r.URL.Path))
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// Middleware to open a route to API keys holding the scope.
+// User sessions pass through untouched, their role is checked by RequireRoles.
+func RequireScope(scope string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+
+			principal := APIKeyFromContext(r.Context())
+			if principal == nil {
+				next(w, r)
+				return
+			}
+
+			if !HasRole(scope, principal.Scopes...) {
+				response(w, http.StatusForbidden, "API key is missing the '"+scope+"' scope", fmt.Sprintf("API key %s missing scope '%s' for %s %s", principal.KeyID, scope, r.Method, r.URL.Path))
+				return
+			}
+
+			next(w, r.WithContext(context.WithValue(r.Context(), scopeKey, true)))
+		}
+	}
+}
+
+// Checks if role is one of the allowed roles
+func HasRole(role string, allowed ...string) bool {
+	for _, value := range allowed {
+		if role == value {
+			return true
+		}
+	}
+	return false
+}
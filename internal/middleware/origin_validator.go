@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/harshitrajsinha/medi-go/config"
 )
@@ -12,12 +13,19 @@ func OriginValidator(next http.Handler) http.Handler {
 
 		var allowedOrigin string
 
-		if r.URL.Path == "/" {
+		// health check and well-known endpoints are called by servers without an Origin
+		if r.URL.Path == "/" || strings.HasPrefix(r.URL.Path, "/.well-known/") {
 			next.ServeHTTP(w, r)
 			return
 		}
 		origin := r.Header.Get("Origin")
 
+		// integrations call server to server with an API key and send no Origin
+		if origin == "" && apiKeyFromRequest(r) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		allowedOriginWebsite, err := config.AllowedOrigin()
 		if err != nil {
 			fmt.Println(err)
@@ -34,7 +42,7 @@ func OriginValidator(next http.Handler) http.Handler {
 		// Setting CORS headers only for allowed origins
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		// Handle preflight OPTIONS requests
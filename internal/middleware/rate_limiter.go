@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harshitrajsinha/medi-go/config"
+	"golang.org/x/time/rate"
+)
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Rate limiter keeping a separate token bucket per caller key
+type RateLimiter struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+	limit    rate.Limit
+	burst    int
+	lastGC   time.Time
+}
+
+// Constructor method for per-caller rate limiter
+func NewRateLimiter(limit rate.Limit, burst int) *RateLimiter {
+	return &RateLimiter{visitors: make(map[string]*visitor), limit: limit, burst: burst, lastGC: time.Now()}
+}
+
+// Reports whether caller identified by key may proceed
+func (rl *RateLimiter) Allow(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+
+	// drop callers that have been idle long enough to have a full bucket again
+	if now.Sub(rl.lastGC) > time.Minute {
+		idle := time.Duration(float64(rl.burst)/float64(rl.limit)*float64(time.Second)) + time.Minute
+		for k, v := range rl.visitors {
+			if now.Sub(v.lastSeen) > idle {
+				delete(rl.visitors, k)
+			}
+		}
+		rl.lastGC = now
+	}
+
+	v, exists := rl.visitors[key]
+	if !exists {
+		v = &visitor{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.visitors[key] = v
+	}
+	v.lastSeen = now
+
+	return v.limiter.Allow()
+}
+
+// Middleware to rate limit a route per client IP
+func (rl *RateLimiter) LimitByIP(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !rl.Allow(ClientIP(r)) {
+			response(w, http.StatusTooManyRequests, "Too Many Requests", "Too Many Requests from "+ClientIP(r))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Returns IP of the client, X-Forwarded-For is only honoured when TRUST_PROXY is set
+func ClientIP(r *http.Request) string {
+
+	proxyConfig, err := config.ProxyConfig()
+	if err == nil && proxyConfig.TrustProxy {
+		if clientIP := forwardedClientIP(r.Header.Values("X-Forwarded-For"), proxyConfig.ProxyHops); clientIP != "" {
+			return clientIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Returns the address the outermost trusted proxy saw. Entries left of it are sent by the client and can be forged,
+// so the entry is counted from the right, each of the hops proxies appends one.
+func forwardedClientIP(headers []string, hops int) string {
+
+	var entries []string
+	for _, header := range headers {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+
+	if hops < 1 {
+		hops = 1
+	}
+	if hops > len(entries) {
+		return entries[0]
+	}
+	return entries[len(entries)-hops]
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		trustProxy   string
+		hops         string
+		forwardedFor []string
+		want         string
+	}{
+		{"proxy not trusted", "false", "1", []string{"203.0.113.7"}, "192.0.2.1"},
+		{"no header", "true", "1", nil, "192.0.2.1"},
+		{"single proxy", "true", "1", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed entry", "true", "1", []string{"10.9.9.9, 203.0.113.7"}, "203.0.113.7"},
+		{"spoofed entry in separate header", "true", "1", []string{"10.9.9.9", "203.0.113.7"}, "203.0.113.7"},
+		{"two proxies", "true", "2", []string{"10.9.9.9, 203.0.113.7, 198.51.100.2"}, "203.0.113.7"},
+		{"fewer entries than hops", "true", "3", []string{"203.0.113.7, 198.51.100.2"}, "203.0.113.7"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY", test.trustProxy)
+			t.Setenv("TRUSTED_PROXY_HOPS", test.hops)
+
+			r := httptest.NewRequest("POST", "/api/v1/patients/123456/verify", nil)
+			r.RemoteAddr = "192.0.2.1:54321"
+			for _, forwardedFor := range test.forwardedFor {
+				r.Header.Add("X-Forwarded-For", forwardedFor)
+			}
+
+			if got := ClientIP(r); got != test.want {
+				t.Errorf("ClientIP() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLimitByIPIgnoresSpoofedForwardedFor(t *testing.T) {
+
+	t.Setenv("TRUST_PROXY", "true")
+	t.Setenv("TRUSTED_PROXY_HOPS", "1")
+
+	limiter := NewRateLimiter(1, 2)
+	allowed := 0
+	for _, spoofed := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		r := httptest.NewRequest("POST", "/api/v1/patients/123456/verify", nil)
+		r.Header.Set("X-Forwarded-For", spoofed+", 203.0.113.7")
+		if limiter.Allow(ClientIP(r)) {
+			allowed++
+		}
+	}
+
+	if allowed != 2 {
+		t.Errorf("allowed %d requests with forged X-Forwarded-For entries, want 2", allowed)
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/mail"
+)
+
+// Account types that can be managed, matching the doctor and staff tables
+const (
+	AccountTypeDoctor = "doctors"
+	AccountTypeStaff  = "staff"
+)
+
+type Account struct {
+	Fullname       string `json:"fullname"`
+	Email          string `json:"email"`
+	Password       string `json:"password"`
+	Role           string `json:"role"`
+	Specialization string `json:"specialization"`
+}
+
+func validateEmail(email string) error {
+	if _, err := mail.ParseAddress(email); err == nil {
+		return nil
+	}
+	return errors.New("email must be a valid email address")
+}
+
+func validatePassword(password string) error {
+	if len(password) >= 8 && len(password) <= 72 { // bcrypt only uses the first 72 bytes
+		return nil
+	}
+	return errors.New("password must be between 8 and 72 characters")
+}
+
+// doctors always have the doctor role, staff can be receptionists or admins
+func validateAccountRole(accountType string, role string) error {
+	switch accountType {
+	case AccountTypeDoctor:
+		if role == "" || role == RoleDoctor {
+			return nil
+		}
+		return errors.New("role of a doctor account must be 'doctor'")
+	case AccountTypeStaff:
+		if role == RoleReceptionist || role == RoleAdmin {
+			return nil
+		}
+		return errors.New("role must be one of following - ['receptionist', 'admin']")
+	}
+	return errors.New("account type must be one of following - ['doctors', 'staff']")
+}
+
+func ValidateAccountReq(accountType string, accountRequest Account) error {
+	var err error
+
+	if err = validateName(accountRequest.Fullname); err != nil {
+		return err
+	}
+
+	if err = validateEmail(accountRequest.Email); err != nil {
+		return err
+	}
+
+	if err = validatePassword(accountRequest.Password); err != nil {
+		return err
+	}
+
+	if err = validateAccountRole(accountType, accountRequest.Role); err != nil {
+		return err
+	}
+
+	if accountType == AccountTypeStaff && accountRequest.Specialization != "" {
+		return errors.New("specialization is only applicable to doctors")
+	}
+
+	return nil
+}
+
+func ValidateAccountPatchReq(accountType string, request []byte) error {
+	var err error
+	var accountRequest Account
+	var data map[string]interface{}
+
+	_ = json.Unmarshal(request, &accountRequest)
+	_ = json.Unmarshal(request, &data)
+
+	if _, exists := data["fullname"]; exists {
+		if err = validateName(accountRequest.Fullname); err != nil {
+			return err
+		}
+	}
+	if _, exists := data["email"]; exists {
+		if err = validateEmail(accountRequest.Email); err != nil {
+			return err
+		}
+	}
+	if _, exists := data["password"]; exists {
+		if err = validatePassword(accountRequest.Password); err != nil {
+			return err
+		}
+	}
+	if _, exists := data["role"]; exists {
+		if err = validateAccountRole(accountType, accountRequest.Role); err != nil {
+			return err
+		}
+	}
+	if _, exists := data["specialization"]; exists && accountType == AccountTypeStaff {
+		return errors.New("specialization is only applicable to doctors")
+	}
+
+	return nil
+}
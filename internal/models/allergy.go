@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+type Allergy struct {
+	Substance string `json:"substance"` // drug name, generic name or drug class
+	Reaction  string `json:"reaction"`
+	Severity  string `json:"severity"`
+	NotedOn   string `json:"noted_on"` // YYYY-MM-DD, empty when unknown
+}
+
+// ['mild', 'moderate', 'severe']
+func ValidateAllergyReq(allergyRequest Allergy) error {
+
+	if strings.TrimSpace(allergyRequest.Substance) == "" {
+		return errors.New("substance must not be empty")
+	}
+
+	if err := validateHistoryDate("noted_on", allergyRequest.NotedOn); err != nil {
+		return err
+	}
+
+	for _, value := range [3]string{"mild", "moderate", "severe"} {
+		if allergyRequest.Severity == value {
+			return nil
+		}
+	}
+	return errors.New("severity must be one of following - ['mild', 'moderate', 'severe']")
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permissions that can be granted to API keys
+const (
+	ScopePatientsRead  = "patients:read"
+	ScopePatientsWrite = "patients:write"
+	ScopeDoctorsRead   = "doctors:read"
+	ScopeQueueRead     = "queue:read" // waiting-room displays
+)
+
+var apiKeyScopes = []string{ScopePatientsRead, ScopePatientsWrite, ScopeDoctorsRead, ScopeQueueRead}
+
+type APIKey struct {
+	Name      string     `json:"name"`
+	OwnerID   uuid.UUID  `json:"owner_id"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		valid := false
+		for _, value := range apiKeyScopes {
+			if scope == value {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return errors.New("scopes must be among following - ['" + strings.Join(apiKeyScopes, "', '") + "']")
+		}
+	}
+	return nil
+}
+
+func ValidateAPIKeyReq(apiKeyRequest APIKey) error {
+
+	if strings.TrimSpace(apiKeyRequest.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+
+	if apiKeyRequest.OwnerID == uuid.Nil {
+		return errors.New("owner_id must be a doctor or staff account ID")
+	}
+
+	if err := validateScopes(apiKeyRequest.Scopes); err != nil {
+		return err
+	}
+
+	if apiKeyRequest.ExpiresAt == nil || !apiKeyRequest.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be a future timestamp")
+	}
+
+	return nil
+}
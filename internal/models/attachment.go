@@ -0,0 +1,63 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Content types accepted for attachments, detected from the file content rather than the client's header
+var AttachmentContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+type Attachment struct {
+	Category    string `json:"category"` // ['prescription', 'report', 'imaging', 'id', 'other']
+	Description string `json:"description"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size_bytes"`
+	Checksum    string `json:"checksum_sha256"`
+}
+
+// Keeps the base name of an uploaded file without control or quote characters, so it is safe in headers
+func CleanAttachmentFilename(filename string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, filename)
+	if len(filename) > 255 {
+		filename = filename[len(filename)-255:]
+	}
+	return strings.TrimSpace(filename)
+}
+
+func ValidateAttachmentReq(attachmentRequest Attachment) error {
+
+	switch attachmentRequest.Category {
+	case "prescription", "report", "imaging", "id", "other":
+	default:
+		return errors.New("category must be one of following - ['prescription', 'report', 'imaging', 'id', 'other']")
+	}
+
+	if len(attachmentRequest.Description) > 500 {
+		return errors.New("description must be at most 500 characters")
+	}
+
+	if attachmentRequest.Filename == "" || attachmentRequest.Filename == "." || attachmentRequest.Filename == "/" {
+		return errors.New("file must have a name")
+	}
+
+	if !AttachmentContentTypes[attachmentRequest.ContentType] {
+		return fmt.Errorf("file type %s is not allowed, upload a PDF, JPEG, PNG or WebP file", attachmentRequest.ContentType)
+	}
+
+	return nil
+}
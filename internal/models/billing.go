@@ -0,0 +1,227 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Category of a fee schedule entry
+const (
+	FeeCategoryConsultation = "consultation"
+	FeeCategoryProcedure    = "procedure"
+	FeeCategoryLabTest      = "lab_test"
+)
+
+// Status of an invoice, payments move it from issued to paid
+const (
+	InvoiceStatusIssued        = "issued"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusVoid          = "void"
+)
+
+// Methods of payment
+const (
+	PaymentMethodCash = "cash"
+	PaymentMethodCard = "card"
+	PaymentMethodUPI  = "upi"
+)
+
+// Methods in the order they are shown in the cash closing report
+var PaymentMethods = []string{PaymentMethodCash, PaymentMethodCard, PaymentMethodUPI}
+
+var feeCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{0,31}$`)
+
+// Entry of the fee schedule, amounts are in rupees and tax rate in percent
+type Fee struct {
+	Code           string  `json:"code"`
+	Name           string  `json:"name"`
+	Category       string  `json:"category"`
+	Specialization string  `json:"specialization"` // consultations only, empty for the default consultation fee
+	Amount         float64 `json:"amount"`
+	TaxRate        float64 `json:"tax_rate"`
+	Active         *bool   `json:"active"`
+}
+
+// Item requested on an invoice, either a fee code or a description with a unit price
+type InvoiceItem struct {
+	FeeCode         string   `json:"fee_code"`
+	Description     string   `json:"description"`
+	UnitPrice       *float64 `json:"unit_price"`
+	TaxRate         float64  `json:"tax_rate"` // items without fee code only
+	Quantity        int      `json:"quantity"`
+	DiscountPercent float64  `json:"discount_percent"`
+}
+
+// Invoice requested for a visit, the consultation fee of the visit's doctor is added unless include_consultation is false
+type InvoiceRequest struct {
+	Items               []InvoiceItem `json:"items"`
+	IncludeConsultation *bool         `json:"include_consultation"`
+	Notes               string        `json:"notes"`
+}
+
+// Priced line of an invoice
+type InvoiceLine struct {
+	LineNo          int     `json:"line_no"`
+	FeeID           *string `json:"fee_id"`
+	Code            string  `json:"code"`
+	Description     string  `json:"description"`
+	Quantity        int     `json:"quantity"`
+	UnitPrice       float64 `json:"unit_price"`
+	DiscountPercent float64 `json:"discount_percent"`
+	DiscountAmount  float64 `json:"discount_amount"`
+	TaxRate         float64 `json:"tax_rate"`
+	TaxAmount       float64 `json:"tax_amount"`
+	LineTotal       float64 `json:"line_total"`
+}
+
+// Totals of an invoice, total is subtotal less discount plus tax
+type InvoiceTotals struct {
+	Subtotal      float64 `json:"subtotal"`
+	DiscountTotal float64 `json:"discount_total"`
+	TaxTotal      float64 `json:"tax_total"`
+	Total         float64 `json:"total"`
+}
+
+type Payment struct {
+	Amount    float64 `json:"amount"`
+	Method    string  `json:"method"`
+	Reference string  `json:"reference"`
+}
+
+type InvoiceVoid struct {
+	Reason string `json:"reason"`
+}
+
+// Converts rupees to paise, amounts are added up in paise so totals do not pick up float errors
+func ToPaise(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func FromPaise(paise int64) float64 {
+	return float64(paise) / 100
+}
+
+// Checks amount has no fraction of a paisa
+func wholePaise(amount float64) bool {
+	return math.Abs(amount*100-math.Round(amount*100)) < 1e-6
+}
+
+// Percent of an amount in paise, rounded half away from zero
+func percentOf(paise int64, percent float64) int64 {
+	return int64(math.Round(float64(paise) * percent / 100))
+}
+
+// Sets discount, tax and total of a line from its quantity, unit price, discount percent and tax rate.
+// Tax is charged on the discounted amount.
+func PriceInvoiceLine(line *InvoiceLine) {
+	gross := ToPaise(line.UnitPrice) * int64(line.Quantity)
+	discount := percentOf(gross, line.DiscountPercent)
+	tax := percentOf(gross-discount, line.TaxRate)
+
+	line.DiscountAmount = FromPaise(discount)
+	line.TaxAmount = FromPaise(tax)
+	line.LineTotal = FromPaise(gross - discount + tax)
+}
+
+// Adds up priced lines
+func TotalInvoiceLines(lines []InvoiceLine) InvoiceTotals {
+	var subtotal, discount, tax int64
+	for _, line := range lines {
+		subtotal += ToPaise(line.UnitPrice) * int64(line.Quantity)
+		discount += ToPaise(line.DiscountAmount)
+		tax += ToPaise(line.TaxAmount)
+	}
+	return InvoiceTotals{
+		Subtotal:      FromPaise(subtotal),
+		DiscountTotal: FromPaise(discount),
+		TaxTotal:      FromPaise(tax),
+		Total:         FromPaise(subtotal - discount + tax),
+	}
+}
+
+func ValidateFeeReq(feeRequest Fee) error {
+
+	if !feeCodePattern.MatchString(feeRequest.Code) {
+		return errors.New("code must be 1 to 32 upper case letters, digits, '-' or '_'")
+	}
+
+	if strings.TrimSpace(feeRequest.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+
+	switch feeRequest.Category {
+	case FeeCategoryConsultation:
+	case FeeCategoryProcedure, FeeCategoryLabTest:
+		if feeRequest.Specialization != "" {
+			return errors.New("specialization can only be set for consultation fees")
+		}
+	default:
+		return errors.New("category must be one of following - ['consultation', 'procedure', 'lab_test']")
+	}
+
+	if feeRequest.Amount < 0 || !wholePaise(feeRequest.Amount) {
+		return errors.New("amount must not be negative and must have at most 2 decimal places")
+	}
+
+	if feeRequest.TaxRate < 0 || feeRequest.TaxRate > 100 {
+		return errors.New("tax_rate must be between 0 and 100")
+	}
+
+	return nil
+}
+
+func ValidateInvoiceReq(invoiceRequest InvoiceRequest) error {
+
+	includeConsultation := invoiceRequest.IncludeConsultation == nil || *invoiceRequest.IncludeConsultation
+	if len(invoiceRequest.Items) == 0 && !includeConsultation {
+		return errors.New("at least one item is required")
+	}
+
+	for i, item := range invoiceRequest.Items {
+		line := i + 1
+		if item.FeeCode == "" {
+			if strings.TrimSpace(item.Description) == "" || item.UnitPrice == nil {
+				return fmt.Errorf("item %d: fee_code, or description and unit_price, must be provided", line)
+			}
+			if *item.UnitPrice < 0 || !wholePaise(*item.UnitPrice) {
+				return fmt.Errorf("item %d: unit_price must not be negative and must have at most 2 decimal places", line)
+			}
+			if item.TaxRate < 0 || item.TaxRate > 100 {
+				return fmt.Errorf("item %d: tax_rate must be between 0 and 100", line)
+			}
+		} else if item.UnitPrice != nil || item.TaxRate != 0 {
+			return fmt.Errorf("item %d: unit_price and tax_rate are taken from the fee schedule for fee_code", line)
+		}
+		if item.Quantity < 1 || item.Quantity > 1000 {
+			return fmt.Errorf("item %d: quantity must be between 1 and 1000", line)
+		}
+		if item.DiscountPercent < 0 || item.DiscountPercent > 100 {
+			return fmt.Errorf("item %d: discount_percent must be between 0 and 100", line)
+		}
+	}
+
+	return nil
+}
+
+func ValidatePaymentReq(paymentRequest Payment) error {
+
+	if paymentRequest.Amount <= 0 || !wholePaise(paymentRequest.Amount) {
+		return errors.New("amount must be greater than 0 and must have at most 2 decimal places")
+	}
+
+	switch paymentRequest.Method {
+	case PaymentMethodCash:
+	case PaymentMethodCard, PaymentMethodUPI:
+		if paymentRequest.Reference == "" {
+			return errors.New("reference is required for card and UPI payments")
+		}
+	default:
+		return errors.New("method must be one of following - ['cash', 'card', 'upi']")
+	}
+
+	return nil
+}
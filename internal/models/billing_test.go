@@ -0,0 +1,81 @@
+package models
+
+import "testing"
+
+func TestPriceInvoiceLine(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		line     InvoiceLine
+		discount float64
+		tax      float64
+		total    float64
+	}{
+		{"no discount or tax", InvoiceLine{Quantity: 1, UnitPrice: 500}, 0, 0, 500},
+		{"tax on discounted amount", InvoiceLine{Quantity: 2, UnitPrice: 250, DiscountPercent: 10, TaxRate: 18}, 50, 81, 531},
+		{"discount rounds half up", InvoiceLine{Quantity: 1, UnitPrice: 0.25, DiscountPercent: 10}, 0.03, 0, 0.22},
+		{"tax rounds half up", InvoiceLine{Quantity: 1, UnitPrice: 0.5, TaxRate: 5}, 0, 0.03, 0.53},
+		{"tax rounds down", InvoiceLine{Quantity: 3, UnitPrice: 33.33, TaxRate: 12}, 0, 12, 111.99},
+		{"fractional discount and tax", InvoiceLine{Quantity: 3, UnitPrice: 99.99, DiscountPercent: 12.5, TaxRate: 18}, 37.5, 47.24, 309.71},
+		{"full discount", InvoiceLine{Quantity: 1, UnitPrice: 300, DiscountPercent: 100, TaxRate: 18}, 300, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := test.line
+			PriceInvoiceLine(&line)
+			if line.DiscountAmount != test.discount || line.TaxAmount != test.tax || line.LineTotal != test.total {
+				t.Errorf("PriceInvoiceLine() discount, tax, total = %v, %v, %v, want %v, %v, %v",
+					line.DiscountAmount, line.TaxAmount, line.LineTotal, test.discount, test.tax, test.total)
+			}
+		})
+	}
+}
+
+func TestTotalInvoiceLines(t *testing.T) {
+
+	// each line rounds its own discount and tax, the invoice adds the rounded paise
+	lines := []InvoiceLine{
+		{Quantity: 1, UnitPrice: 0.5, TaxRate: 5},
+		{Quantity: 1, UnitPrice: 0.5, TaxRate: 5},
+		{Quantity: 1, UnitPrice: 0.5, TaxRate: 5},
+		{Quantity: 3, UnitPrice: 99.99, DiscountPercent: 12.5, TaxRate: 18},
+		{Quantity: 7, UnitPrice: 0.1},
+	}
+	var lineTotals int64
+	for i := range lines {
+		PriceInvoiceLine(&lines[i])
+		lineTotals += ToPaise(lines[i].LineTotal)
+	}
+
+	totals := TotalInvoiceLines(lines)
+	want := InvoiceTotals{Subtotal: 302.17, DiscountTotal: 37.5, TaxTotal: 47.33, Total: 312}
+	if totals != want {
+		t.Errorf("TotalInvoiceLines() = %+v, want %+v", totals, want)
+	}
+	if ToPaise(totals.Total) != lineTotals {
+		t.Errorf("invoice total %v differs from the sum of line totals %v", totals.Total, FromPaise(lineTotals))
+	}
+}
+
+func TestWholePaise(t *testing.T) {
+
+	tests := []struct {
+		amount float64
+		want   bool
+	}{
+		{0, true},
+		{500, true},
+		{0.1, true},
+		{19.99, true},
+		{1.005, false},
+		{0.001, false},
+		{99.999, false},
+	}
+
+	for _, test := range tests {
+		if got := wholePaise(test.amount); got != test.want {
+			t.Errorf("wholePaise(%v) = %v, want %v", test.amount, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severity of a drug interaction
+const (
+	InteractionMinor           = "minor"
+	InteractionModerate        = "moderate"
+	InteractionMajor           = "major"
+	InteractionContraindicated = "contraindicated"
+)
+
+// Kind of a medication warning
+const (
+	WarningInteraction = "interaction"
+	WarningAllergy     = "allergy"
+)
+
+var interactionSeverities = []string{InteractionMinor, InteractionModerate, InteractionMajor, InteractionContraindicated}
+
+// Columns expected in catalogue CSV files, in order
+var (
+	drugCSVHeader        = []string{"name", "generic_name", "drug_class", "form", "strength"}
+	interactionCSVHeader = []string{"subject_a", "subject_b", "severity", "description"}
+)
+
+type Drug struct {
+	Name        string `json:"name"`
+	GenericName string `json:"generic_name"`
+	DrugClass   string `json:"drug_class"`
+	Form        string `json:"form"`
+	Strength    string `json:"strength"`
+}
+
+// Rule between two generic names or drug classes
+type DrugInteraction struct {
+	SubjectA    string `json:"subject_a"`
+	SubjectB    string `json:"subject_b"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Interaction or allergy found for a drug being prescribed
+type MedicationWarning struct {
+	Type        string `json:"type"`
+	Severity    string `json:"severity"`
+	Drug        string `json:"drug"`
+	With        string `json:"with"`
+	Description string `json:"description"`
+}
+
+// Reason a doctor gives to save despite warnings
+type MedicationOverride struct {
+	OverrideReason string `json:"override_reason"`
+}
+
+// Reads CSV records after checking the header row
+func readCatalogueCSV(r io.Reader, header []string) ([][]string, error) {
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, errors.New("csv must have a header row and at least one record")
+	}
+
+	for i, column := range header {
+		if i >= len(records[0]) || strings.ToLower(strings.TrimSpace(records[0][i])) != column {
+			return nil, fmt.Errorf("csv header must be - %s", strings.Join(header, ","))
+		}
+	}
+
+	return records[1:], nil
+}
+
+// Parses drugs from CSV with columns name, generic_name, drug_class, form, strength
+func ParseDrugCSV(r io.Reader) ([]Drug, error) {
+
+	records, err := readCatalogueCSV(r, drugCSVHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	drugs := make([]Drug, 0, len(records))
+	for i, record := range records {
+		drug := Drug{Name: strings.TrimSpace(record[0]), GenericName: strings.TrimSpace(record[1]), DrugClass: strings.TrimSpace(record[2]),
+			Form: strings.TrimSpace(record[3]), Strength: strings.TrimSpace(record[4])}
+		if drug.Name == "" || drug.GenericName == "" {
+			return nil, fmt.Errorf("line %d: name and generic_name must not be empty", i+2)
+		}
+		drugs = append(drugs, drug)
+	}
+	return drugs, nil
+}
+
+// Parses interaction rules from CSV with columns subject_a, subject_b, severity, description.
+// Subjects are lowercased and ordered so a rule is stored once for both directions.
+func ParseInteractionCSV(r io.Reader) ([]DrugInteraction, error) {
+
+	records, err := readCatalogueCSV(r, interactionCSVHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	interactions := make([]DrugInteraction, 0, len(records))
+	for i, record := range records {
+		interaction := DrugInteraction{SubjectA: strings.ToLower(strings.TrimSpace(record[0])), SubjectB: strings.ToLower(strings.TrimSpace(record[1])),
+			Severity: strings.ToLower(strings.TrimSpace(record[2])), Description: strings.TrimSpace(record[3])}
+		if interaction.SubjectA == "" || interaction.SubjectB == "" || interaction.Description == "" {
+			return nil, fmt.Errorf("line %d: subject_a, subject_b and description must not be empty", i+2)
+		}
+
+		validSeverity := false
+		for _, severity := range interactionSeverities {
+			if interaction.Severity == severity {
+				validSeverity = true
+				break
+			}
+		}
+		if !validSeverity {
+			return nil, fmt.Errorf("line %d: severity must be among following - ['%s']", i+2, strings.Join(interactionSeverities, "', '"))
+		}
+
+		if interaction.SubjectB < interaction.SubjectA {
+			interaction.SubjectA, interaction.SubjectB = interaction.SubjectB, interaction.SubjectA
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Status of a visit
+const (
+	EncounterStatusOpen   = "open"
+	EncounterStatusClosed = "closed"
+)
+
+type Encounter struct {
+	DoctorID       uuid.UUID `json:"doctor_id"`
+	ChiefComplaint string    `json:"chief_complaint"`
+	Findings       string    `json:"findings"`
+	Treatment      string    `json:"treatment"`
+}
+
+func ValidateEncounterReq(encounterRequest Encounter) error {
+
+	if strings.TrimSpace(encounterRequest.ChiefComplaint) == "" {
+		return errors.New("chief_complaint must not be empty")
+	}
+
+	return nil
+}
+
+// Validates fields a doctor can change while the visit is open
+func ValidateEncounterPatchReq(request []byte) error {
+	var data map[string]interface{}
+
+	if err := json.Unmarshal(request, &data); err != nil {
+		return errors.New("invalid request body for visit")
+	}
+	// override_reason only accompanies a treatment with medication warnings
+	if _, ok := data["override_reason"]; ok {
+		if _, ok := data["override_reason"].(string); !ok {
+			return errors.New("override_reason must be a string")
+		}
+		delete(data, "override_reason")
+	}
+	if len(data) == 0 {
+		return errors.New("at least one of following is required - ['chief_complaint', 'findings', 'treatment']")
+	}
+
+	for key, value := range data {
+		switch key {
+		case "chief_complaint", "findings", "treatment":
+			text, ok := value.(string)
+			if !ok {
+				return errors.New(key + " must be a string")
+			}
+			if key == "chief_complaint" && strings.TrimSpace(text) == "" {
+				return errors.New("chief_complaint must not be empty")
+			}
+		default:
+			return errors.New("field '" + key + "' cannot be changed, allowed fields are - ['chief_complaint', 'findings', 'treatment']")
+		}
+	}
+
+	return nil
+}
+
+func ValidateEncounterStatus(status string) error {
+	if status == "" || status == EncounterStatusOpen || status == EncounterStatusClosed {
+		return nil
+	}
+	return errors.New("status must be one of following - ['open', 'closed']")
+}
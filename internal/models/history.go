@@ -0,0 +1,118 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Kinds of medical history records, as used in request paths
+const (
+	HistoryAllergies     = "allergies"
+	HistoryConditions    = "conditions"
+	HistorySurgeries     = "surgeries"
+	HistoryFamilyHistory = "family-history"
+)
+
+// Status of a chronic or past condition
+const (
+	ConditionStatusActive     = "active"
+	ConditionStatusControlled = "controlled"
+	ConditionStatusResolved   = "resolved"
+)
+
+// Dates in history records are YYYY-MM-DD and empty when unknown
+type Condition struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	DiagnosedOn string `json:"diagnosed_on"`
+	ResolvedOn  string `json:"resolved_on"`
+	Notes       string `json:"notes"`
+}
+
+type Surgery struct {
+	Procedure   string `json:"procedure"`
+	PerformedOn string `json:"performed_on"`
+	Hospital    string `json:"hospital"`
+	Notes       string `json:"notes"`
+}
+
+type FamilyHistory struct {
+	Relation   string `json:"relation"`
+	Condition  string `json:"condition"`
+	AgeAtOnset *int   `json:"age_at_onset"`
+	Notes      string `json:"notes"`
+}
+
+// Checks an optional history date, it must not be in the future
+func validateHistoryDate(field string, date string) error {
+	if date == "" {
+		return nil
+	}
+	parsed, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return errors.New(field + " must be in YYYY-MM-DD format")
+	}
+	// a day of slack for clinics ahead of UTC
+	if parsed.After(time.Now().AddDate(0, 0, 1)) {
+		return errors.New(field + " must not be in the future")
+	}
+	return nil
+}
+
+// ['active', 'controlled', 'resolved']
+func ValidateConditionReq(conditionRequest Condition) error {
+
+	if strings.TrimSpace(conditionRequest.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+
+	switch conditionRequest.Status {
+	case ConditionStatusActive, ConditionStatusControlled, ConditionStatusResolved:
+	default:
+		return errors.New("status must be one of following - ['active', 'controlled', 'resolved']")
+	}
+
+	if err := validateHistoryDate("diagnosed_on", conditionRequest.DiagnosedOn); err != nil {
+		return err
+	}
+	if err := validateHistoryDate("resolved_on", conditionRequest.ResolvedOn); err != nil {
+		return err
+	}
+	if conditionRequest.ResolvedOn != "" && conditionRequest.Status != ConditionStatusResolved {
+		return errors.New("resolved_on is only allowed for resolved conditions")
+	}
+	// dates are in the same layout, so they compare as strings
+	if conditionRequest.DiagnosedOn != "" && conditionRequest.ResolvedOn != "" && conditionRequest.ResolvedOn < conditionRequest.DiagnosedOn {
+		return errors.New("resolved_on must not be before diagnosed_on")
+	}
+
+	return nil
+}
+
+func ValidateSurgeryReq(surgeryRequest Surgery) error {
+
+	if strings.TrimSpace(surgeryRequest.Procedure) == "" {
+		return errors.New("procedure must not be empty")
+	}
+
+	return validateHistoryDate("performed_on", surgeryRequest.PerformedOn)
+}
+
+func ValidateFamilyHistoryReq(familyRequest FamilyHistory) error {
+
+	if strings.TrimSpace(familyRequest.Relation) == "" {
+		return errors.New("relation must not be empty")
+	}
+	if len(familyRequest.Relation) > 32 {
+		return errors.New("relation must be at most 32 characters")
+	}
+	if strings.TrimSpace(familyRequest.Condition) == "" {
+		return errors.New("condition must not be empty")
+	}
+	if familyRequest.AgeAtOnset != nil && (*familyRequest.AgeAtOnset < 0 || *familyRequest.AgeAtOnset > 125) {
+		return errors.New("age_at_onset must be between 0 and 125")
+	}
+
+	return nil
+}
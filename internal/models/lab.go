@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Priority of a lab order
+const (
+	LabPriorityRoutine = "routine"
+	LabPriorityUrgent  = "urgent"
+	LabPriorityStat    = "stat"
+)
+
+// Status of a lab order, orders move through them in this order
+const (
+	LabStatusOrdered   = "ordered"
+	LabStatusCollected = "collected"
+	LabStatusResulted  = "resulted"
+	LabStatusReviewed  = "reviewed"
+)
+
+// Flag of a lab result against its reference range
+const (
+	LabFlagNormal = "normal"
+	LabFlagLow    = "low"
+	LabFlagHigh   = "high"
+)
+
+type LabOrder struct {
+	Panel    string `json:"panel"` // e.g. CBC, lipid profile
+	Priority string `json:"priority"`
+	Notes    string `json:"notes"`
+}
+
+type LabResult struct {
+	Analyte string   `json:"analyte"`
+	Value   float64  `json:"value"`
+	Unit    string   `json:"unit"`
+	RefLow  *float64 `json:"ref_low"`
+	RefHigh *float64 `json:"ref_high"`
+	Flag    string   `json:"flag"` // set from the reference range, ignored in requests
+}
+
+type LabResults struct {
+	Results []LabResult `json:"results"`
+}
+
+// Review of resulted order by the ordering doctor, abnormal results must be acknowledged
+type LabReview struct {
+	AcknowledgeAbnormal bool   `json:"acknowledge_abnormal"`
+	Note                string `json:"note"`
+}
+
+func ValidateLabOrderReq(labOrderRequest LabOrder) error {
+
+	if strings.TrimSpace(labOrderRequest.Panel) == "" {
+		return errors.New("panel must not be empty")
+	}
+
+	switch labOrderRequest.Priority {
+	case LabPriorityRoutine, LabPriorityUrgent, LabPriorityStat:
+	default:
+		return errors.New("priority must be one of following - ['routine', 'urgent', 'stat']")
+	}
+
+	return nil
+}
+
+func ValidateLabResultsReq(resultsRequest LabResults) error {
+
+	if len(resultsRequest.Results) == 0 {
+		return errors.New("at least one result is required")
+	}
+
+	for i, result := range resultsRequest.Results {
+		line := i + 1
+		if strings.TrimSpace(result.Analyte) == "" {
+			return fmt.Errorf("result %d: analyte must not be empty", line)
+		}
+		if strings.TrimSpace(result.Unit) == "" || len(result.Unit) > 32 {
+			return fmt.Errorf("result %d: unit must be 1 to 32 characters", line)
+		}
+		if result.RefLow == nil && result.RefHigh == nil {
+			return fmt.Errorf("result %d: at least one of ref_low and ref_high is required", line)
+		}
+		if result.RefLow != nil && result.RefHigh != nil && *result.RefLow > *result.RefHigh {
+			return fmt.Errorf("result %d: ref_low must not be above ref_high", line)
+		}
+	}
+
+	return nil
+}
+
+// Flags a result outside its reference range, a missing bound is open ended
+func FlagLabResult(result LabResult) string {
+	switch {
+	case result.RefLow != nil && result.Value < *result.RefLow:
+		return LabFlagLow
+	case result.RefHigh != nil && result.Value > *result.RefHigh:
+		return LabFlagHigh
+	}
+	return LabFlagNormal
+}
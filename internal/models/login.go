@@ -1,5 +1,12 @@
 package models
 
+// Roles a user can authenticate with
+const (
+	RoleDoctor       = "doctor"
+	RoleReceptionist = "receptionist"
+	RoleAdmin        = "admin"
+)
+
 type Credentials struct {
 	Role     string `json:"role"`
 	Email    string `json:"email"`
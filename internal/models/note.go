@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Status of a clinical note, signed notes only change through amendments
+const (
+	NoteStatusDraft  = "draft"
+	NoteStatusSigned = "signed"
+)
+
+// Subjective, Objective, Assessment and Plan sections of a clinical note
+type SOAPNote struct {
+	Subjective string `json:"subjective"`
+	Objective  string `json:"objective"`
+	Assessment string `json:"assessment"`
+	Plan       string `json:"plan"`
+}
+
+// Full content of a signed note as amended, with the reason for the change
+type NoteAmendment struct {
+	SOAPNote
+	Reason string `json:"reason"`
+}
+
+// Trims surrounding spaces of all sections
+func (note *SOAPNote) Trim() {
+	note.Subjective, note.Objective = strings.TrimSpace(note.Subjective), strings.TrimSpace(note.Objective)
+	note.Assessment, note.Plan = strings.TrimSpace(note.Assessment), strings.TrimSpace(note.Plan)
+}
+
+func ValidateSOAPNoteReq(noteRequest SOAPNote) error {
+
+	if noteRequest.Subjective == "" && noteRequest.Objective == "" && noteRequest.Assessment == "" && noteRequest.Plan == "" {
+		return errors.New("at least one of following is required - ['subjective', 'objective', 'assessment', 'plan']")
+	}
+
+	for _, section := range []string{noteRequest.Subjective, noteRequest.Objective, noteRequest.Assessment, noteRequest.Plan} {
+		if len(section) > 10000 {
+			return errors.New("each section must be at most 10000 characters")
+		}
+	}
+
+	return nil
+}
+
+func ValidateNoteAmendmentReq(amendmentRequest NoteAmendment) error {
+
+	if strings.TrimSpace(amendmentRequest.Reason) == "" {
+		return errors.New("reason for amendment is required")
+	}
+
+	return ValidateSOAPNoteReq(amendmentRequest.SOAPNote)
+}
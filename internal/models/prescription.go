@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Status of a prescription
+const (
+	PrescriptionStatusActive = "active"
+	PrescriptionStatusVoided = "voided"
+)
+
+// Routes of administration accepted on prescription items
+var drugRoutes = []string{"oral", "sublingual", "topical", "inhalation", "nasal", "ophthalmic", "otic", "rectal", "vaginal", "transdermal", "iv", "im", "sc"}
+
+type PrescriptionItem struct {
+	Drug         string `json:"drug"`
+	Strength     string `json:"strength"`
+	Route        string `json:"route"`
+	Dose         string `json:"dose"`
+	Frequency    string `json:"frequency"`
+	Duration     string `json:"duration"`
+	Quantity     int    `json:"quantity"`
+	Instructions string `json:"instructions"`
+}
+
+type Prescription struct {
+	Items          []PrescriptionItem `json:"items"`
+	Notes          string             `json:"notes"`
+	OverrideReason string             `json:"override_reason"` // required when drug safety checks return warnings
+}
+
+type PrescriptionVoid struct {
+	Reason string `json:"reason"`
+}
+
+func ValidatePrescriptionReq(prescriptionRequest Prescription) error {
+
+	if len(prescriptionRequest.Items) == 0 {
+		return errors.New("at least one item is required")
+	}
+
+	for i, item := range prescriptionRequest.Items {
+		line := i + 1
+		required := map[string]string{"drug": item.Drug, "strength": item.Strength, "dose": item.Dose, "frequency": item.Frequency, "duration": item.Duration}
+		for _, field := range []string{"drug", "strength", "dose", "frequency", "duration"} {
+			if strings.TrimSpace(required[field]) == "" {
+				return fmt.Errorf("item %d: %s must not be empty", line, field)
+			}
+		}
+
+		validRoute := false
+		for _, route := range drugRoutes {
+			if item.Route == route {
+				validRoute = true
+				break
+			}
+		}
+		if !validRoute {
+			return fmt.Errorf("item %d: route must be among following - ['%s']", line, strings.Join(drugRoutes, "', '"))
+		}
+
+		if item.Quantity <= 0 {
+			return fmt.Errorf("item %d: quantity must be greater than 0", line)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Status of a place in the OPD queue
+const (
+	QueueStatusWaiting        = "waiting"
+	QueueStatusCalled         = "called"
+	QueueStatusInConsultation = "in_consultation"
+	QueueStatusDone           = "done"
+	QueueStatusNoShow         = "no_show"
+)
+
+// Statuses a queue entry may move to from its current status
+var queueTransitions = map[string][]string{
+	QueueStatusWaiting:        {QueueStatusCalled, QueueStatusNoShow},
+	QueueStatusCalled:         {QueueStatusInConsultation, QueueStatusWaiting, QueueStatusNoShow},
+	QueueStatusInConsultation: {QueueStatusDone},
+}
+
+var queuePrefixPattern = regexp.MustCompile(`^[A-Z]{1,6}$`)
+
+type QueueEntry struct {
+	TokenID  string    `json:"token_id"`
+	DoctorID uuid.UUID `json:"doctor_id"` // defaults to the patient's assigned doctor
+}
+
+type QueueStatusUpdate struct {
+	Status string `json:"status"`
+}
+
+type QueuePrefix struct {
+	Prefix string `json:"prefix"`
+}
+
+func ValidateQueueReq(queueRequest QueueEntry) error {
+
+	if len(strings.TrimSpace(queueRequest.TokenID)) != 6 {
+		return errors.New("token_id of a registered patient is required")
+	}
+
+	return nil
+}
+
+func ValidateQueueStatus(status string) error {
+	switch status {
+	case "", QueueStatusWaiting, QueueStatusCalled, QueueStatusInConsultation, QueueStatusDone, QueueStatusNoShow:
+		return nil
+	}
+	return errors.New("status must be one of waiting, called, in_consultation, done or no_show")
+}
+
+// Reports whether a queue entry can move from one status to another
+func CanTransitionQueue(from string, to string) bool {
+	for _, allowed := range queueTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateQueuePrefix(prefixRequest QueuePrefix) error {
+
+	if !queuePrefixPattern.MatchString(prefixRequest.Prefix) {
+		return errors.New("prefix must be 1 to 6 uppercase letters")
+	}
+
+	return nil
+}
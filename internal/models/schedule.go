@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Layouts of schedule times, all times are clinic wall clock times
+const (
+	ClockLayout    = "15:04"
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = "2006-01-02T15:04"
+)
+
+// Status of an appointment
+const (
+	AppointmentStatusBooked    = "booked"
+	AppointmentStatusCancelled = "cancelled"
+	AppointmentStatusCompleted = "completed"
+	AppointmentStatusNoShow    = "no_show"
+)
+
+type WorkingHours struct {
+	Weekday     int    `json:"weekday"` // 0 is Sunday
+	StartTime   string `json:"start_time"`
+	EndTime     string `json:"end_time"`
+	SlotMinutes int    `json:"slot_minutes"`
+}
+
+type Leave struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason"`
+}
+
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type Appointment struct {
+	DoctorID uuid.UUID `json:"doctor_id"`
+	TokenID  string    `json:"token_id"`
+	StartAt  string    `json:"start_at"`
+	Reason   string    `json:"reason"`
+}
+
+// Validates a doctor's complete weekly schedule, windows of a weekday must not overlap
+func ValidateWorkingHoursReq(hours []WorkingHours) error {
+
+	for i, window := range hours {
+		if window.Weekday < 0 || window.Weekday > 6 {
+			return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, err := time.Parse(ClockLayout, window.StartTime)
+		if err != nil {
+			return errors.New("start_time must be in HH:MM format")
+		}
+		end, err := time.Parse(ClockLayout, window.EndTime)
+		if err != nil {
+			return errors.New("end_time must be in HH:MM format")
+		}
+		if !start.Before(end) {
+			return fmt.Errorf("start_time must be before end_time on weekday %d", window.Weekday)
+		}
+		if window.SlotMinutes < 5 || window.SlotMinutes > 240 {
+			return errors.New("slot_minutes must be between 5 and 240")
+		}
+
+		for _, other := range hours[:i] {
+			if other.Weekday != window.Weekday {
+				continue
+			}
+			otherStart, _ := time.Parse(ClockLayout, other.StartTime)
+			otherEnd, _ := time.Parse(ClockLayout, other.EndTime)
+			if start.Before(otherEnd) && otherStart.Before(end) {
+				return fmt.Errorf("working hours overlap on weekday %d", window.Weekday)
+			}
+		}
+	}
+
+	return nil
+}
+
+func ValidateLeaveReq(leaveRequest Leave) error {
+
+	start, err := time.Parse(DateLayout, leaveRequest.StartDate)
+	if err != nil {
+		return errors.New("start_date must be in YYYY-MM-DD format")
+	}
+	end, err := time.Parse(DateLayout, leaveRequest.EndDate)
+	if err != nil {
+		return errors.New("end_date must be in YYYY-MM-DD format")
+	}
+	if end.Before(start) {
+		return errors.New("end_date must not be before start_date")
+	}
+
+	return nil
+}
+
+func ValidateHolidayReq(holidayRequest Holiday) error {
+
+	if _, err := time.Parse(DateLayout, holidayRequest.Date); err != nil {
+		return errors.New("date must be in YYYY-MM-DD format")
+	}
+	if strings.TrimSpace(holidayRequest.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+
+	return nil
+}
+
+func ValidateAppointmentReq(appointmentRequest Appointment) error {
+
+	if appointmentRequest.DoctorID == uuid.Nil {
+		return errors.New("doctor_id is required")
+	}
+	if len(strings.TrimSpace(appointmentRequest.TokenID)) != 6 {
+		return errors.New("token_id of a registered patient is required")
+	}
+	if _, err := time.Parse(DateTimeLayout, appointmentRequest.StartAt); err != nil {
+		return errors.New("start_at must be in YYYY-MM-DDTHH:MM format")
+	}
+
+	return nil
+}
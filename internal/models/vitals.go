@@ -0,0 +1,265 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Vitals that have normal ranges, readings are stored in the units noted
+const (
+	VitalSystolic    = "systolic"    // mmHg
+	VitalDiastolic   = "diastolic"   // mmHg
+	VitalTemperature = "temperature" // °C
+	VitalPulse       = "pulse"       // beats per minute
+	VitalSpO2        = "spo2"        // %
+	VitalBMI         = "bmi"         // kg/m², from weight and height
+)
+
+// Flag of a vital outside its normal range
+const (
+	VitalFlagLow  = "low"
+	VitalFlagHigh = "high"
+)
+
+// Order vitals are flagged in
+var vitalNames = []string{VitalSystolic, VitalDiastolic, VitalTemperature, VitalPulse, VitalSpO2, VitalBMI}
+
+// Units of the stored readings
+var vitalUnits = map[string]string{
+	VitalSystolic:    "mmHg",
+	VitalDiastolic:   "mmHg",
+	VitalTemperature: "C",
+	VitalPulse:       "bpm",
+	VitalSpO2:        "%",
+	VitalBMI:         "kg/m2",
+}
+
+// A value with its unit as entered at reception
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type BloodPressure struct {
+	Systolic  float64 `json:"systolic"`
+	Diastolic float64 `json:"diastolic"`
+	Unit      string  `json:"unit"`
+}
+
+// Vitals taken together, any of them may be left out
+type Vitals struct {
+	BloodPressure *BloodPressure `json:"blood_pressure"`
+	Temperature   *Measurement   `json:"temperature"`
+	Pulse         *Measurement   `json:"pulse"`
+	SpO2          *Measurement   `json:"spo2"`
+	Weight        *Measurement   `json:"weight"`
+	Height        *Measurement   `json:"height"`
+}
+
+// Vitals converted to stored units: mmHg, °C, bpm, %, kg and cm
+type VitalReading struct {
+	Systolic    *float64 `json:"systolic"`
+	Diastolic   *float64 `json:"diastolic"`
+	Temperature *float64 `json:"temperature"`
+	Pulse       *float64 `json:"pulse"`
+	SpO2        *float64 `json:"spo2"`
+	Weight      *float64 `json:"weight"`
+	Height      *float64 `json:"height"`
+}
+
+// Normal range of a vital for an age band, gender is empty when the range applies to all
+type VitalRange struct {
+	Vital  string  `json:"vital"`
+	Gender string  `json:"gender"`
+	MinAge int     `json:"min_age"`
+	MaxAge int     `json:"max_age"`
+	Low    float64 `json:"low"`
+	High   float64 `json:"high"`
+}
+
+type VitalFlag struct {
+	Vital  string  `json:"vital"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit"`
+	Low    float64 `json:"low"`
+	High   float64 `json:"high"`
+	Status string  `json:"status"`
+}
+
+// Converts a measurement to the stored unit, values outside [min, max] in that unit are rejected as entry mistakes
+func convertMeasurement(field string, m *Measurement, conversions map[string]func(float64) float64, min float64, max float64) (*float64, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	units := make([]string, 0, len(conversions))
+	for unit := range conversions {
+		units = append(units, unit)
+	}
+
+	convert, ok := conversions[strings.ToLower(strings.TrimSpace(m.Unit))]
+	if !ok {
+		sort.Strings(units)
+		return nil, fmt.Errorf("%s unit must be one of following - ['%s']", field, strings.Join(units, "', '"))
+	}
+	value := math.Round(convert(m.Value)*10) / 10
+	if value < min || value > max {
+		return nil, fmt.Errorf("%s of %g %s is not a plausible reading", field, m.Value, m.Unit)
+	}
+	return &value, nil
+}
+
+func same(value float64) float64 { return value }
+
+// Validates units and plausibility of vitals and converts them to stored units
+func ParseVitalsReq(vitalsRequest Vitals) (VitalReading, error) {
+
+	var reading VitalReading
+	var err error
+
+	if vitalsRequest.BloodPressure == nil && vitalsRequest.Temperature == nil && vitalsRequest.Pulse == nil &&
+		vitalsRequest.SpO2 == nil && vitalsRequest.Weight == nil && vitalsRequest.Height == nil {
+		return reading, errors.New("at least one of following is required - ['blood_pressure', 'temperature', 'pulse', 'spo2', 'weight', 'height']")
+	}
+
+	if bp := vitalsRequest.BloodPressure; bp != nil {
+		if unit := strings.ToLower(strings.TrimSpace(bp.Unit)); unit != "mmhg" && unit != "" {
+			return reading, errors.New("blood_pressure unit must be mmHg")
+		}
+		if bp.Systolic < 40 || bp.Systolic > 300 || bp.Diastolic < 20 || bp.Diastolic > 200 {
+			return reading, fmt.Errorf("blood pressure of %g/%g mmHg is not a plausible reading", bp.Systolic, bp.Diastolic)
+		}
+		if bp.Diastolic >= bp.Systolic {
+			return reading, errors.New("diastolic pressure must be lower than systolic pressure")
+		}
+		systolic, diastolic := math.Round(bp.Systolic), math.Round(bp.Diastolic)
+		reading.Systolic, reading.Diastolic = &systolic, &diastolic
+	}
+
+	if reading.Temperature, err = convertMeasurement("temperature", vitalsRequest.Temperature, map[string]func(float64) float64{
+		"c": same, "f": func(f float64) float64 { return (f - 32) * 5 / 9 },
+	}, 25, 45); err != nil {
+		return reading, err
+	}
+	if reading.Pulse, err = convertMeasurement("pulse", vitalsRequest.Pulse, map[string]func(float64) float64{"bpm": same}, 20, 250); err != nil {
+		return reading, err
+	}
+	if reading.SpO2, err = convertMeasurement("spo2", vitalsRequest.SpO2, map[string]func(float64) float64{"%": same}, 50, 100); err != nil {
+		return reading, err
+	}
+	if reading.Weight, err = convertMeasurement("weight", vitalsRequest.Weight, map[string]func(float64) float64{
+		"kg": same, "lb": func(lb float64) float64 { return lb * 0.45359237 },
+	}, 0.3, 350); err != nil {
+		return reading, err
+	}
+	if reading.Height, err = convertMeasurement("height", vitalsRequest.Height, map[string]func(float64) float64{
+		"cm": same, "in": func(in float64) float64 { return in * 2.54 },
+	}, 25, 250); err != nil {
+		return reading, err
+	}
+
+	return reading, nil
+}
+
+// Body mass index of a reading, nil without both weight and height
+func (reading VitalReading) BMI() *float64 {
+	if reading.Weight == nil || reading.Height == nil {
+		return nil
+	}
+	metres := *reading.Height / 100
+	bmi := math.Round(*reading.Weight/(metres*metres)*10) / 10
+	return &bmi
+}
+
+func ValidateVitalRangesReq(ranges []VitalRange) error {
+
+	if len(ranges) == 0 {
+		return errors.New("at least one range is required")
+	}
+
+	for i, vitalRange := range ranges {
+		line := i + 1
+		if _, ok := vitalUnits[vitalRange.Vital]; !ok {
+			return fmt.Errorf("range %d: vital must be among following - ['%s']", line, strings.Join(vitalNames, "', '"))
+		}
+		switch vitalRange.Gender {
+		case "", "male", "female", "other":
+		default:
+			return fmt.Errorf("range %d: gender must be empty or one of following - ['male', 'female', 'other']", line)
+		}
+		if vitalRange.MinAge < 0 || vitalRange.MaxAge > 125 || vitalRange.MinAge > vitalRange.MaxAge {
+			return fmt.Errorf("range %d: ages must satisfy 0 <= min_age <= max_age <= 125", line)
+		}
+		if vitalRange.Low >= vitalRange.High {
+			return fmt.Errorf("range %d: low must be below high", line)
+		}
+	}
+
+	return nil
+}
+
+// Picks the range for a patient, a range for their gender wins over one for all, then the narrowest age band
+func matchVitalRange(ranges []VitalRange, vital string, age int, gender string) (VitalRange, bool) {
+
+	var best VitalRange
+	found := false
+	for _, candidate := range ranges {
+		if candidate.Vital != vital || age < candidate.MinAge || age > candidate.MaxAge || (candidate.Gender != "" && candidate.Gender != gender) {
+			continue
+		}
+		if !found {
+			best, found = candidate, true
+			continue
+		}
+		if (candidate.Gender != "") != (best.Gender != "") {
+			if candidate.Gender != "" {
+				best = candidate
+			}
+			continue
+		}
+		if candidate.MaxAge-candidate.MinAge < best.MaxAge-best.MinAge {
+			best = candidate
+		}
+	}
+	return best, found
+}
+
+// Lists the vitals of a reading outside the normal ranges for the patient's age and gender
+func FlagVitals(reading VitalReading, ranges []VitalRange, age int, gender string) []VitalFlag {
+
+	values := map[string]*float64{
+		VitalSystolic:    reading.Systolic,
+		VitalDiastolic:   reading.Diastolic,
+		VitalTemperature: reading.Temperature,
+		VitalPulse:       reading.Pulse,
+		VitalSpO2:        reading.SpO2,
+		VitalBMI:         reading.BMI(),
+	}
+
+	flags := make([]VitalFlag, 0)
+	for _, vital := range vitalNames {
+		value := values[vital]
+		if value == nil {
+			continue
+		}
+		normal, ok := matchVitalRange(ranges, vital, age, gender)
+		if !ok {
+			continue
+		}
+
+		flag := VitalFlag{Vital: vital, Value: *value, Unit: vitalUnits[vital], Low: normal.Low, High: normal.High}
+		switch {
+		case *value < normal.Low:
+			flag.Status = VitalFlagLow
+		case *value > normal.High:
+			flag.Status = VitalFlagHigh
+		default:
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testVitalRanges = []VitalRange{
+	{Vital: VitalPulse, MinAge: 0, MaxAge: 120, Low: 60, High: 100},
+	{Vital: VitalPulse, MinAge: 0, MaxAge: 12, Low: 70, High: 120},
+	{Vital: VitalPulse, Gender: "female", MinAge: 0, MaxAge: 120, Low: 65, High: 105},
+	{Vital: VitalPulse, Gender: "female", MinAge: 13, MaxAge: 19, Low: 68, High: 110},
+	{Vital: VitalTemperature, MinAge: 0, MaxAge: 120, Low: 36.1, High: 37.8},
+	{Vital: VitalBMI, MinAge: 18, MaxAge: 120, Low: 18.5, High: 24.9},
+}
+
+func TestMatchVitalRange(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		vital  string
+		age    int
+		gender string
+		low    float64
+		found  bool
+	}{
+		{"adult range for all", VitalPulse, 30, "male", 60, true},
+		{"narrower child band", VitalPulse, 8, "male", 70, true},
+		{"upper age bound is inclusive", VitalPulse, 12, "male", 70, true},
+		{"just past the child band", VitalPulse, 13, "male", 60, true},
+		{"gender range wins over narrower band for all", VitalPulse, 8, "female", 65, true},
+		{"narrowest gender band", VitalPulse, 16, "female", 68, true},
+		{"lower age bound is inclusive", VitalPulse, 13, "female", 68, true},
+		{"just past the gender band", VitalPulse, 20, "female", 65, true},
+		{"other gender uses ranges for all", VitalPulse, 8, "other", 70, true},
+		{"age outside every band", VitalPulse, 121, "male", 0, false},
+		{"vital without range", VitalSpO2, 30, "male", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normal, found := matchVitalRange(testVitalRanges, test.vital, test.age, test.gender)
+			if found != test.found || normal.Low != test.low {
+				t.Errorf("matchVitalRange(%s, %d, %s) = low %v, %v, want low %v, %v", test.vital, test.age, test.gender, normal.Low, found, test.low, test.found)
+			}
+		})
+	}
+}
+
+func TestFlagVitals(t *testing.T) {
+
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		reading VitalReading
+		age     int
+		gender  string
+		want    []VitalFlag
+	}{
+		{"at low bound", VitalReading{Pulse: value(60)}, 30, "male", []VitalFlag{}},
+		{"at high bound", VitalReading{Pulse: value(100)}, 30, "male", []VitalFlag{}},
+		{"below low bound", VitalReading{Pulse: value(59.9)}, 30, "male",
+			[]VitalFlag{{Vital: VitalPulse, Value: 59.9, Unit: "bpm", Low: 60, High: 100, Status: VitalFlagLow}}},
+		{"above high bound", VitalReading{Pulse: value(100.1)}, 30, "male",
+			[]VitalFlag{{Vital: VitalPulse, Value: 100.1, Unit: "bpm", Low: 60, High: 100, Status: VitalFlagHigh}}},
+		{"normal for a child", VitalReading{Pulse: value(115)}, 8, "male", []VitalFlag{}},
+		{"flagged against the gender band", VitalReading{Pulse: value(66)}, 16, "female",
+			[]VitalFlag{{Vital: VitalPulse, Value: 66, Unit: "bpm", Low: 68, High: 110, Status: VitalFlagLow}}},
+		{"vital without range is not flagged", VitalReading{SpO2: value(80)}, 30, "male", []VitalFlag{}},
+		{"several vitals in order", VitalReading{Temperature: value(37.9), Pulse: value(101), Weight: value(90), Height: value(175)}, 30, "male",
+			[]VitalFlag{
+				{Vital: VitalTemperature, Value: 37.9, Unit: "C", Low: 36.1, High: 37.8, Status: VitalFlagHigh},
+				{Vital: VitalPulse, Value: 101, Unit: "bpm", Low: 60, High: 100, Status: VitalFlagHigh},
+				{Vital: VitalBMI, Value: 29.4, Unit: "kg/m2", Low: 18.5, High: 24.9, Status: VitalFlagHigh},
+			}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FlagVitals(test.reading, testVitalRanges, test.age, test.gender); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("FlagVitals() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// Package pdf writes simple PDF documents: text in the standard Helvetica fonts, lines and filled rectangles.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// RGB colour with components from 0 to 1
+type Color struct {
+	R, G, B float64
+}
+
+var Black = Color{0, 0, 0}
+
+// Document of A4 pages, positions are in points from the top left corner of the page
+type Document struct {
+	title   string
+	pages   []*bytes.Buffer
+	current int
+}
+
+// Constructor method for a document, title is shown by PDF viewers
+func New(title string) *Document {
+	return &Document{title: title, current: -1}
+}
+
+// Adds a page and makes it the current page
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.current = len(d.pages) - 1
+}
+
+// Number of pages added so far
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Makes an earlier page the current page, e.g. to add footers once the page count is known
+func (d *Document) SetPage(index int) {
+	if index >= 0 && index < len(d.pages) {
+		d.current = index
+	}
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if d.current < 0 {
+		d.AddPage()
+	}
+	return d.pages[d.current]
+}
+
+func num(value float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
+
+func colorOps(c Color, op string) string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B) + " " + op
+}
+
+// Writes text with its baseline at y
+func (d *Document) Text(x float64, y float64, size float64, bold bool, c Color, text string) {
+	font := "/F1"
+	if bold {
+		font = "/F2"
+	}
+	fmt.Fprintf(d.page(), "BT %s %s Tf %s %s %s Td (%s) Tj ET\n", font, num(size), colorOps(c, "rg"), num(x), num(PageHeight-y), escape(encode(text)))
+}
+
+// Writes text ending at x
+func (d *Document) TextRight(x float64, y float64, size float64, bold bool, c Color, text string) {
+	d.Text(x-TextWidth(text, size, bold), y, size, bold, c, text)
+}
+
+// Draws a straight line
+func (d *Document) Line(x1 float64, y1 float64, x2 float64, y2 float64, width float64, c Color) {
+	fmt.Fprintf(d.page(), "%s w %s %s %s m %s %s l S\n", num(width), colorOps(c, "RG"), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// Draws a filled rectangle with its top left corner at x, y
+func (d *Document) Rect(x float64, y float64, width float64, height float64, fill Color) {
+	fmt.Fprintf(d.page(), "%s %s %s %s %s re f\n", colorOps(fill, "rg"), num(x), num(PageHeight-y-height), num(width), num(height))
+}
+
+// Writes the document
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// objects 1 to 5 are fixed, pages and their contents follow in pairs
+	pageRefs := make([]string, len(d.pages))
+	for i := range d.pages {
+		pageRefs[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageRefs, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (MediGo) /CreationDate (D:%s) >>", escape(encode(d.title)), time.Now().UTC().Format("20060102150405Z")))
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(PageWidth), num(PageHeight), 7+2*i))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(content.Bytes())
+		zw.Close()
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// Escapes characters with meaning in PDF strings
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", "", "\n", " ").Replace(text)
+}
+
+// Characters of Windows-1252 outside Latin-1
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// Converts text to the fonts' WinAnsi encoding, characters it lacks become '?'
+func encode(text string) string {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\t':
+			encoded = append(encoded, ' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			encoded = append(encoded, byte(r))
+		case winAnsi[r] != 0:
+			encoded = append(encoded, winAnsi[r])
+		case r < 0x20:
+			// control characters are dropped
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+	return string(encoded)
+}
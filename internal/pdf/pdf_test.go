@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteToMultiPage(t *testing.T) {
+
+	doc := New(`Report (draft) \ copy`)
+	for i := 0; i < 3; i++ {
+		doc.AddPage()
+		doc.Text(40, 60, 12, i == 1, Black, fmt.Sprintf(`Page %d (of 3) C:\notes\`, i+1))
+		doc.Line(40, 70, 200, 70, 0.5, Black)
+		doc.Rect(40, 80, 100, 20, Color{R: 0.5, G: 0.5, B: 0.5})
+	}
+
+	var out bytes.Buffer
+	if _, err := doc.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	data := out.Bytes()
+
+	// startxref points at the xref table
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if match == nil {
+		t.Fatal("missing startxref trailer")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	// catalog, pages, two fonts and info, then a page and its content per page
+	lines := strings.Split(string(data[xref:]), "\n")
+	var first, count int
+	fmt.Sscanf(lines[1], "%d %d", &first, &count)
+	if wantCount := 5 + 2*3 + 1; first != 0 || count != wantCount {
+		t.Fatalf("xref subsection = %d %d, want 0 %d", first, count, wantCount)
+	}
+	if lines[2] != "0000000000 65535 f " {
+		t.Errorf("xref free entry = %q", lines[2])
+	}
+	for object := 1; object < count; object++ {
+		entry := lines[2+object]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry %d = %q, want 20 byte entries", object, entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", object); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("xref offset %d of object %d points at %q", offset, object, data[offset:offset+10])
+		}
+	}
+	if want := fmt.Sprintf("/Size %d ", count); !bytes.Contains(data, []byte(want)) {
+		t.Errorf("trailer does not declare %s", want)
+	}
+	if !bytes.Contains(data, []byte("/Count 3 >>")) {
+		t.Error("pages object does not count 3 pages")
+	}
+	if !bytes.Contains(data, []byte(`/Title (Report \(draft\) \\ copy)`)) {
+		t.Error("title is not escaped")
+	}
+
+	// content streams are compressed and escape parentheses and backslashes
+	streams := regexp.MustCompile(`(?s)<< /Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindAllSubmatchIndex(data, -1)
+	if len(streams) != 3 {
+		t.Fatalf("found %d content streams, want 3", len(streams))
+	}
+	for i, loc := range streams {
+		length, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		start := loc[1]
+		if !bytes.HasPrefix(data[start+length:], []byte("\nendstream")) {
+			t.Fatalf("stream %d length %d does not end at endstream", i+1, length)
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(data[start : start+length]))
+		if err != nil {
+			t.Fatalf("stream %d: %v", i+1, err)
+		}
+		content, _ := io.ReadAll(reader)
+		if want := fmt.Sprintf(`(Page %d \(of 3\) C:\\notes\\) Tj`, i+1); !bytes.Contains(content, []byte(want)) {
+			t.Errorf("stream %d = %q, want it to contain %q", i+1, content, want)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`plain`, `plain`},
+		{`(note)`, `\(note\)`},
+		{`a\b`, `a\\b`},
+		{`\(`, `\\\(`},
+		{"two\nlines\r", `two lines`},
+	}
+
+	for _, test := range tests {
+		if got := escape(test.text); got != test.want {
+			t.Errorf("escape(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/config"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Reads and normalizes an account request body
+func decodeAccountRequest(body []byte) (models.Account, error) {
+	var accountReq models.Account
+	if err := json.Unmarshal(body, &accountReq); err != nil {
+		return accountReq, err
+	}
+	accountReq.Fullname = strings.TrimSpace(accountReq.Fullname)
+	accountReq.Email = strings.ToLower(strings.TrimSpace(accountReq.Email))
+	accountReq.Role = strings.TrimSpace(accountReq.Role)
+	accountReq.Specialization = strings.TrimSpace(accountReq.Specialization)
+	return accountReq, nil
+}
+
+// Writes response for errors returned while creating or updating accounts
+func accountErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrEmailTaken), errors.Is(err, store.ErrAdminExists):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrInvalidAccountType):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving account"})
+		panic(err)
+	}
+}
+
+// POST: Create new doctor or staff account
+func (a *APIRoutes) CreateAccount(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	accountType := mux.Vars(r)["account_type"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	defer r.Body.Close()
+
+	accountReq, err := decodeAccountRequest(body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for account"})
+		log.Println(err)
+		return
+	}
+
+	// validate request body
+	if err := models.ValidateAccountReq(accountType, accountReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(accountReq.Password)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving account"})
+		panic(err)
+	}
+
+	accountID, err := a.service.CreateAccount(accountType, &accountReq, passwordHash)
+	if err != nil {
+		accountErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Account created successfully!", Data: map[string]uuid.UUID{"account_id": accountID}})
+	log.Printf("Account %s created in %s by %s", accountID, accountType, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return list of doctor or staff accounts based on pagination
+func (a *APIRoutes) GetAllAccounts(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	accountType := mux.Vars(r)["account_type"]
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := a.service.GetAllAccounts(accountType, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("All accounts data populated successfully")
+}
+
+// PATCH: Update fields of existing doctor or staff account
+func (a *APIRoutes) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	params := mux.Vars(r)
+	accountType := params["account_type"]
+	accountID, err := uuid.Parse(strings.TrimSpace(params["account_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid account ID"})
+		log.Println("Invalid account ID")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	defer r.Body.Close()
+
+	accountReq, err := decodeAccountRequest(body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for account"})
+		log.Println(err)
+		return
+	}
+
+	// validate request body for partial update
+	if err := models.ValidateAccountPatchReq(accountType, body); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	// admins cannot demote themselves and lock everyone out
+	if accountID == middleware.UserIDFromContext(r.Context()) && accountReq.Role != "" && accountReq.Role != models.RoleAdmin {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "You cannot change your own role"})
+		log.Println("Admin attempted to change own role")
+		return
+	}
+
+	var passwordHash string
+	if accountReq.Password != "" {
+		passwordHash, err = auth.HashPassword(accountReq.Password)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving account"})
+			panic(err)
+		}
+	}
+
+	updatedAccount, err := a.service.UpdateAccount(accountType, accountID.String(), &accountReq, passwordHash)
+	if err != nil {
+		accountErrorResponse(w, err)
+		return
+	}
+
+	if updatedAccount > 0 {
+		message := "Account updated successfully!"
+		if accountReq.Email != "" || passwordHash != "" || accountReq.Role != "" {
+			message = "Account updated successfully! Existing sessions of the account have been ended"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: message})
+		log.Printf("Account %s updated by %s", accountID, middleware.EmailFromContext(r.Context()))
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "No account present for provided ID or nothing to update"})
+		log.Println("value of updatedAccount is ", updatedAccount)
+	}
+}
+
+// POST: Deactivate doctor or staff account
+func (a *APIRoutes) DeactivateAccount(w http.ResponseWriter, r *http.Request) {
+	a.setAccountActive(w, r, false)
+}
+
+// POST: Reactivate doctor or staff account
+func (a *APIRoutes) ReactivateAccount(w http.ResponseWriter, r *http.Request) {
+	a.setAccountActive(w, r, true)
+}
+
+func (a *APIRoutes) setAccountActive(w http.ResponseWriter, r *http.Request, active bool) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	params := mux.Vars(r)
+	accountType := params["account_type"]
+	accountID, err := uuid.Parse(strings.TrimSpace(params["account_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid account ID"})
+		log.Println("Invalid account ID")
+		return
+	}
+
+	if !active && accountID == middleware.UserIDFromContext(r.Context()) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "You cannot deactivate your own account"})
+		log.Println("Admin attempted to deactivate own account")
+		return
+	}
+
+	updatedAccount, err := a.service.SetAccountActive(accountType, accountID.String(), active)
+	if err != nil {
+		accountErrorResponse(w, err)
+		return
+	}
+
+	status := "deactivated"
+	if active {
+		status = "reactivated"
+	}
+
+	if updatedAccount > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Account " + status + " successfully!"})
+		log.Printf("Account %s %s by %s", accountID, status, middleware.EmailFromContext(r.Context()))
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: "No account present for provided ID"})
+		log.Println("value of updatedAccount is ", updatedAccount)
+	}
+}
+
+// POST: Create the very first admin, authorized by the BOOTSTRAP_TOKEN environment variable
+func (a *APIRoutes) BootstrapAdmin(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	bootstrapConfig, err := config.BootstrapConfig()
+	if err != nil || bootstrapConfig.BootstrapToken == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "Bootstrap is disabled"})
+		log.Println("Bootstrap attempted without BOOTSTRAP_TOKEN configured")
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Bootstrap-Token")), []byte(bootstrapConfig.BootstrapToken)) != 1 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "Invalid bootstrap token"})
+		log.Println("Invalid bootstrap token")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	defer r.Body.Close()
+
+	accountReq, err := decodeAccountRequest(body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for account"})
+		log.Println(err)
+		return
+	}
+	accountReq.Role = models.RoleAdmin
+
+	if err := models.ValidateAccountReq(models.AccountTypeStaff, accountReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(accountReq.Password)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving account"})
+		panic(err)
+	}
+
+	accountID, err := a.service.CreateFirstAdmin(&accountReq, passwordHash)
+	if err != nil {
+		accountErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Admin account created successfully!", Data: map[string]uuid.UUID{"account_id": accountID}})
+	log.Println("First admin account created")
+}
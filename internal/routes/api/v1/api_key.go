@@ -0,0 +1,185 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+type apiKeyCreated struct {
+	KeyID     uuid.UUID `json:"key_id"`
+	APIKey    string    `json:"api_key"`
+	KeyPrefix string    `json:"key_prefix"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// POST: Issue a new API key, the key is returned only once
+func (a *APIRoutes) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var apiKeyReq models.APIKey
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	defer r.Body.Close()
+
+	err = json.Unmarshal(body, &apiKeyReq)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for api key"})
+		log.Println(err)
+		return
+	}
+	apiKeyReq.Name = strings.TrimSpace(apiKeyReq.Name)
+
+	// validate request body
+	if err := models.ValidateAPIKeyReq(apiKeyReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	apiKey, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving api key"})
+		panic(err)
+	}
+	keyPrefix := apiKey[:12]
+
+	keyID, err := a.service.CreateAPIKey(&apiKeyReq, keyHash, keyPrefix, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, store.ErrAPIKeyOwnerNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving api key"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "API key created successfully! Store it now, it will not be shown again", Data: apiKeyCreated{
+		KeyID: keyID, APIKey: apiKey, KeyPrefix: keyPrefix, Scopes: apiKeyReq.Scopes, ExpiresAt: *apiKeyReq.ExpiresAt,
+	}})
+	log.Printf("API key %s for owner %s created by %s", keyID, apiKeyReq.OwnerID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return list of API keys, optionally filtered by owner
+func (a *APIRoutes) GetAllAPIKeys(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	var ownerID uuid.NullUUID
+	if owner := strings.TrimSpace(query.Get("owner_id")); owner != "" {
+		parsedOwner, err := uuid.Parse(owner)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid owner ID"})
+			log.Println("Invalid owner ID")
+			return
+		}
+		ownerID = uuid.NullUUID{UUID: parsedOwner, Valid: true}
+	}
+
+	resp, err := a.service.GetAllAPIKeys(ownerID, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("All api keys populated successfully")
+}
+
+// DELETE: Revoke an API key
+func (a *APIRoutes) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	keyID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["key_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid api key ID"})
+		log.Println("Invalid api key ID")
+		return
+	}
+
+	revokedKey, err := a.service.RevokeAPIKey(keyID.String())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while revoking api key"})
+		panic(err)
+	}
+
+	if revokedKey > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "API key revoked successfully!"})
+		log.Printf("API key %s revoked by %s", keyID, middleware.EmailFromContext(r.Context()))
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: "No active api key present for provided ID"})
+		log.Println("value of revokedKey is ", revokedKey)
+	}
+}
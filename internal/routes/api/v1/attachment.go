@@ -0,0 +1,322 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/config"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Largest file accepted when MAX_UPLOAD_SIZE is not usable, 10MB
+const defaultMaxUploadSize = 10 << 20
+
+// Returns largest accepted attachment size in bytes
+func maxUploadSize() int64 {
+	storageConfig, err := config.StorageConfig()
+	if err != nil || storageConfig.MaxUploadSize <= 0 {
+		return defaultMaxUploadSize
+	}
+	return storageConfig.MaxUploadSize
+}
+
+// Writes response for errors returned while reading or changing attachments
+func attachmentErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrAttachmentNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrStorageUnavailable):
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusServiceUnavailable, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving attachment"})
+		panic(err)
+	}
+}
+
+// Writes response for a patient outside the caller's scope, returns false when access is denied
+func (a *APIRoutes) attachmentPatientAllowed(w http.ResponseWriter, r *http.Request, tokenID string) bool {
+
+	// doctors can only access their own patients
+	allowed, err := a.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access patients of another doctor"})
+		log.Println("Doctor denied access to attachments of patient assigned to another doctor")
+		return false
+	}
+	return true
+}
+
+// Reads attachment of the path and checks its patient is within the caller's scope
+func (a *APIRoutes) attachmentFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	attachmentID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["attachment_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid attachment ID"})
+		log.Println("Invalid attachment ID")
+		return "", false
+	}
+
+	attachment, err := a.service.GetAttachment(attachmentID.String())
+	if err != nil {
+		attachmentErrorResponse(w, err)
+		return "", false
+	}
+
+	if !a.attachmentPatientAllowed(w, r, attachment.TokenID) {
+		return "", false
+	}
+	return attachment.AttachmentID, true
+}
+
+// POST: Upload a document or image for a patient as multipart form with fields file, category and description
+func (a *APIRoutes) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	if !a.attachmentPatientAllowed(w, r, tokenID) {
+		return
+	}
+
+	maxSize := maxUploadSize()
+	limit := strconv.FormatInt(maxSize>>20, 10) + "MB"
+	if maxSize < 1<<20 {
+		limit = strconv.FormatInt(maxSize>>10, 10) + "KB"
+	}
+	tooLarge := Response{Code: http.StatusRequestEntityTooLarge, Message: "File must not be larger than " + limit}
+
+	// leave room for the other form fields and multipart boundaries
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	defer r.Body.Close()
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		w.Header().Set("Content-Type", "application/json")
+		if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(tooLarge)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Request must be multipart/form-data"})
+		}
+		log.Println(err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "file is required"})
+		log.Println(err)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Could not read uploaded file"})
+		log.Println(err)
+		return
+	}
+	if int64(len(content)) > maxSize {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(tooLarge)
+		log.Println("Uploaded file is larger than allowed")
+		return
+	}
+	if len(content) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "file must not be empty"})
+		log.Println("Uploaded file is empty")
+		return
+	}
+
+	checksum := sha256.Sum256(content)
+	attachmentReq := models.Attachment{
+		Category:    strings.ToLower(strings.TrimSpace(r.FormValue("category"))),
+		Description: strings.TrimSpace(r.FormValue("description")),
+		Filename:    models.CleanAttachmentFilename(header.Filename),
+		ContentType: http.DetectContentType(content), // the declared type is not trusted
+		Size:        int64(len(content)),
+		Checksum:    hex.EncodeToString(checksum[:]),
+	}
+	if attachmentReq.Category == "" {
+		attachmentReq.Category = "other"
+	}
+
+	if err := models.ValidateAttachmentReq(attachmentReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if models.AttachmentContentTypes[attachmentReq.ContentType] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		} else {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusUnsupportedMediaType, Message: err.Error()})
+		}
+		log.Println(err)
+		return
+	}
+
+	attachment, err := a.service.CreateAttachment(tokenID, &attachmentReq, content, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		attachmentErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Attachment uploaded successfully!", Data: attachment})
+	log.Printf("Attachment %s uploaded for patient %s by %s", attachment.AttachmentID, tokenID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return details of a patient's attachments, newest first
+func (a *APIRoutes) GetPatientAttachments(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	if !a.attachmentPatientAllowed(w, r, tokenID) {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := a.service.GetAttachments(tokenID, int32(limit), int32(offset))
+	if err != nil {
+		attachmentErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Attachments data populated successfully for token ID- ", tokenID)
+}
+
+// GET: Download the file of an attachment
+func (a *APIRoutes) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	attachmentID, ok := a.attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	file, attachment, err := a.service.OpenAttachment(r.Context(), attachmentID)
+	if err != nil {
+		attachmentErrorResponse(w, err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Checksum-SHA256", attachment.Checksum)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Download of attachment %s interrupted: %v", attachmentID, err)
+		return
+	}
+	log.Printf("Attachment %s downloaded by %s", attachmentID, middleware.EmailFromContext(r.Context()))
+}
+
+// DELETE: Remove an attachment and its file
+func (a *APIRoutes) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	attachmentID, ok := a.attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.service.DeleteAttachment(attachmentID); err != nil {
+		attachmentErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Attachment deleted successfully!"})
+	log.Printf("Attachment %s deleted by %s", attachmentID, middleware.EmailFromContext(r.Context()))
+}
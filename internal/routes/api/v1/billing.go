@@ -0,0 +1,499 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Writes response for errors returned while reading or changing fees, invoices and payments
+func billingErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrEncounterNotFound), errors.Is(err, store.ErrFeeNotFound),
+		errors.Is(err, store.ErrInvoiceNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrUnknownFeeCode), errors.Is(err, store.ErrConsultationFeeMissing):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusUnprocessableEntity, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrFeeExists), errors.Is(err, store.ErrInvoiceExists), errors.Is(err, store.ErrInvoiceStatus),
+		errors.Is(err, store.ErrInvoiceHasPayments), errors.Is(err, store.ErrOverpayment):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving billing data"})
+		panic(err)
+	}
+}
+
+// Reads invoice ID of the path, writes response when it is not valid
+func invoiceIDFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	invoiceID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["invoice_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid invoice ID"})
+		log.Println("Invalid invoice ID")
+		return "", false
+	}
+	return invoiceID.String(), true
+}
+
+// Reads and validates fee of the request body, new fees are active unless stated otherwise
+func decodeFeeReq(w http.ResponseWriter, r *http.Request) (models.Fee, bool) {
+
+	var feeReq models.Fee
+
+	if err := json.NewDecoder(r.Body).Decode(&feeReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for fee"})
+		log.Println(err)
+		return models.Fee{}, false
+	}
+	defer r.Body.Close()
+
+	feeReq.Code, feeReq.Name = strings.ToUpper(strings.TrimSpace(feeReq.Code)), strings.TrimSpace(feeReq.Name)
+	feeReq.Category, feeReq.Specialization = strings.ToLower(strings.TrimSpace(feeReq.Category)), strings.TrimSpace(feeReq.Specialization)
+	if feeReq.Active == nil {
+		active := true
+		feeReq.Active = &active
+	}
+
+	if err := models.ValidateFeeReq(feeReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return models.Fee{}, false
+	}
+	return feeReq, true
+}
+
+// GET: Return the fee schedule, optionally of one category, with inactive fees when include_inactive=true
+func (b *APIRoutes) GetFees(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+	category := strings.ToLower(strings.TrimSpace(query.Get("category")))
+
+	switch category {
+	case "", models.FeeCategoryConsultation, models.FeeCategoryProcedure, models.FeeCategoryLabTest:
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "category must be one of following - ['consultation', 'procedure', 'lab_test']"})
+		log.Println("Invalid fee category")
+		return
+	}
+
+	fees, err := b.service.GetFees(category, query.Get("include_inactive") == "true")
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: map[string]interface{}{"fees_data": fees}})
+	log.Println("Fee schedule populated successfully")
+}
+
+// POST: Add a fee to the schedule
+func (b *APIRoutes) CreateFee(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	feeReq, ok := decodeFeeReq(w, r)
+	if !ok {
+		return
+	}
+
+	fee, err := b.service.CreateFee(&feeReq)
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Fee added successfully!", Data: fee})
+	log.Printf("Fee %s added by %s", fee.Code, middleware.EmailFromContext(r.Context()))
+}
+
+// PUT: Replace a fee of the schedule, set active to false to withdraw it
+func (b *APIRoutes) UpdateFee(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	feeID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["fee_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid fee ID"})
+		log.Println("Invalid fee ID")
+		return
+	}
+
+	feeReq, ok := decodeFeeReq(w, r)
+	if !ok {
+		return
+	}
+
+	fee, err := b.service.UpdateFee(feeID.String(), &feeReq)
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Fee updated successfully!", Data: fee})
+	log.Printf("Fee %s updated by %s", fee.Code, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Invoice a visit from fee schedule codes and other items, with discounts per item
+func (b *APIRoutes) CreateInvoice(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var invoiceReq models.InvoiceRequest
+
+	encounterID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["encounter_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid visit ID"})
+		log.Println("Invalid visit ID")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&invoiceReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for invoice"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	invoiceReq.Notes = strings.TrimSpace(invoiceReq.Notes)
+	for i := range invoiceReq.Items {
+		item := &invoiceReq.Items[i]
+		item.FeeCode, item.Description = strings.ToUpper(strings.TrimSpace(item.FeeCode)), strings.TrimSpace(item.Description)
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+	}
+
+	if err := models.ValidateInvoiceReq(invoiceReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	invoiceID, err := b.service.CreateInvoice(encounterID.String(), &invoiceReq, clinicNow(), middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	invoice, err := b.service.GetInvoice(invoiceID.String())
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Invoice created successfully!", Data: invoice})
+	log.Printf("Invoice %s created for visit %s by %s", invoice.InvoiceNo, encounterID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return invoices of a patient, newest first
+func (b *APIRoutes) GetPatientInvoices(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := b.service.GetInvoices(store.InvoiceFilter{TokenID: tokenID}, int32(limit), int32(offset))
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Invoices data populated successfully for token ID- ", tokenID)
+}
+
+// GET: Return invoices filtered by status or invoice date
+func (b *APIRoutes) GetAllInvoices(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	filter := store.InvoiceFilter{
+		Status:      strings.ToLower(strings.TrimSpace(query.Get("status"))),
+		InvoiceDate: strings.TrimSpace(query.Get("date")),
+	}
+
+	switch filter.Status {
+	case "", models.InvoiceStatusIssued, models.InvoiceStatusPartiallyPaid, models.InvoiceStatusPaid, models.InvoiceStatusVoid:
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "status must be one of following - ['issued', 'partially_paid', 'paid', 'void']"})
+		log.Println("Invalid invoice status")
+		return
+	}
+
+	if filter.InvoiceDate != "" {
+		if _, err := time.Parse(models.DateLayout, filter.InvoiceDate); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "date must be in YYYY-MM-DD format"})
+			log.Println(err)
+			return
+		}
+	}
+
+	resp, err := b.service.GetInvoices(filter, int32(limit), int32(offset))
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("All invoices data populated successfully")
+}
+
+// GET: Return a single invoice with its lines and payments
+func (b *APIRoutes) GetInvoice(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	invoiceID, ok := invoiceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	invoice, err := b.service.GetInvoice(invoiceID)
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: invoice})
+	log.Println("Invoice data populated successfully for invoice ID- ", invoiceID)
+}
+
+// POST: Record a full or part payment of an invoice, a receipt number is issued
+func (b *APIRoutes) RecordPayment(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var paymentReq models.Payment
+
+	invoiceID, ok := invoiceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&paymentReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for payment"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	paymentReq.Method, paymentReq.Reference = strings.ToLower(strings.TrimSpace(paymentReq.Method)), strings.TrimSpace(paymentReq.Reference)
+
+	if err := models.ValidatePaymentReq(paymentReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	payment, err := b.service.RecordPayment(invoiceID, &paymentReq, clinicNow(), middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Payment recorded successfully!", Data: payment})
+	log.Printf("Payment %s of %.2f recorded against invoice %s by %s", payment.ReceiptNo, payment.Amount, payment.InvoiceNo, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Void an invoice that has no payments, the visit can then be invoiced again
+func (b *APIRoutes) VoidInvoice(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var voidReq models.InvoiceVoid
+
+	invoiceID, ok := invoiceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&voidReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for void"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	voidReq.Reason = strings.TrimSpace(voidReq.Reason)
+	if voidReq.Reason == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "reason must not be empty"})
+		log.Println("Invoice void reason is empty")
+		return
+	}
+
+	if err := b.service.VoidInvoice(invoiceID, voidReq.Reason, middleware.UserIDFromContext(r.Context())); err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Invoice voided successfully!"})
+	log.Printf("Invoice %s voided by %s", invoiceID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return the cash closing report of a clinic date, today when date is not set
+func (b *APIRoutes) GetCashClosing(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	date, err := scheduleDate(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	report, err := b.service.GetCashClosing(date.Format(models.DateLayout))
+	if err != nil {
+		billingErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: report})
+	log.Printf("Cash closing report of %s populated for %s", report.Date, middleware.EmailFromContext(r.Context()))
+}
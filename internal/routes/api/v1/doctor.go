@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// GET: Return directory of doctors filtered by specialization and name
+func (d *APIRoutes) GetAllDoctors(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		var r interface{}
+		if r = recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	if limiter.Allow() {
+
+		query := r.URL.Query()
+
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		specialization := strings.TrimSpace(query.Get("specialization"))
+		name := strings.TrimSpace(query.Get("name"))
+
+		// Get data from store
+		resp, err := d.service.GetAllDoctors(specialization, name, int32(limit), int32(offset))
+		if err != nil {
+			// send error response
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+			panic(err)
+		}
+
+		// Send response
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+		log.Println("All doctors data populated successfully")
+
+	} else {
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	}
+}
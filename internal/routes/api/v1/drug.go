@@ -0,0 +1,243 @@
+package routes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+)
+
+// Largest catalogue CSV accepted over HTTP, bigger files can be loaded with the catalogue command
+const maxCatalogueUpload = 5 << 20
+
+// Checks drugs about to be saved for a patient. Warnings without an override reason are
+// returned with 409 and the caller must not save, otherwise the warnings to record are returned.
+func (rx *APIRoutes) checkMedicationSafety(w http.ResponseWriter, tokenID string, drugs []string, overrideReason string) ([]models.MedicationWarning, bool) {
+
+	warnings, err := rx.service.CheckMedications(tokenID, drugs)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while checking medications"})
+		panic(err)
+	}
+
+	if len(warnings) > 0 && strings.TrimSpace(overrideReason) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: "Medication warnings found, resend with override_reason to save anyway", Data: map[string][]models.MedicationWarning{"warnings": warnings}})
+		log.Printf("Medication warnings returned for patient %s", tokenID)
+		return nil, false
+	}
+	return warnings, true
+}
+
+// Same as checkMedicationSafety for drugs named in a free text treatment
+func (rx *APIRoutes) checkTreatmentSafety(w http.ResponseWriter, tokenID string, treatment string, overrideReason string) ([]models.MedicationWarning, bool) {
+
+	drugs, err := rx.service.FindDrugsInText(treatment)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while checking medications"})
+		panic(err)
+	}
+	return rx.checkMedicationSafety(w, tokenID, drugs, overrideReason)
+}
+
+// Logs warnings overridden while saving a treatment, they are recorded by the store with the treatment
+func logTreatmentOverride(r *http.Request, tokenID string, warnings []models.MedicationWarning) {
+	if len(warnings) > 0 {
+		log.Printf("Medication warnings for patient %s overridden by %s", tokenID, middleware.EmailFromContext(r.Context()))
+	}
+}
+
+// Response data listing overridden warnings, nil when there were none
+func warningsData(warnings []models.MedicationWarning) interface{} {
+	if len(warnings) == 0 {
+		return nil
+	}
+	return map[string][]models.MedicationWarning{"warnings": warnings}
+}
+
+// GET: Search the drug catalogue by brand or generic name
+func (rx *APIRoutes) GetDrugs(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := rx.service.GetDrugs(strings.TrimSpace(query.Get("search")), int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Drug data populated successfully")
+}
+
+// POST: Load drugs into the catalogue from a CSV body, existing drugs are updated
+func (rx *APIRoutes) ImportDrugs(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	defer r.Body.Close()
+	drugs, err := models.ParseDrugCSV(http.MaxBytesReader(w, r.Body, maxCatalogueUpload))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	result, err := rx.service.ImportDrugs(drugs)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while importing drugs"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Drugs imported successfully!", Data: result})
+	log.Printf("%d drugs imported and %d updated by %s", result.Inserted, result.Updated, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Load interaction rules from a CSV body, existing rules are updated
+func (rx *APIRoutes) ImportDrugInteractions(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	defer r.Body.Close()
+	interactions, err := models.ParseInteractionCSV(http.MaxBytesReader(w, r.Body, maxCatalogueUpload))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	result, err := rx.service.ImportDrugInteractions(interactions)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while importing interactions"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Interactions imported successfully!", Data: result})
+	log.Printf("%d interactions imported and %d updated by %s", result.Inserted, result.Updated, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Check drugs against each other, a patient's active medications and allergies without saving anything
+func (rx *APIRoutes) CheckPatientMedications(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var checkReq struct {
+		Drugs []string `json:"drugs"`
+	}
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&checkReq); err != nil || len(checkReq.Drugs) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "drugs must be a non empty list of drug names"})
+		log.Println("Invalid Request body for medication check")
+		return
+	}
+	defer r.Body.Close()
+
+	// doctors can only check their own patients
+	allowed, err := rx.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view patients of another doctor"})
+		log.Println("Doctor denied medication check of patient assigned to another doctor")
+		return
+	}
+
+	drugs := make([]string, 0, len(checkReq.Drugs))
+	for _, drug := range checkReq.Drugs {
+		if drug = strings.TrimSpace(drug); drug != "" {
+			drugs = append(drugs, drug)
+		}
+	}
+
+	warnings, err := rx.service.CheckMedications(tokenID, drugs)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while checking medications"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: map[string][]models.MedicationWarning{"warnings": warnings}})
+	log.Printf("Medication check for patient %s returned %d warnings", tokenID, len(warnings))
+}
+
+// Reads the override reason sent along with a patient or visit update
+func overrideReasonFromBody(body []byte) string {
+	var override models.MedicationOverride
+	_ = json.Unmarshal(body, &override)
+	return strings.TrimSpace(override.OverrideReason)
+}
@@ -0,0 +1,442 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Writes response for errors returned while opening or changing visits
+func encounterErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrEncounterNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrEncounterAlreadyOpen), errors.Is(err, store.ErrEncounterClosed):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrDoctorNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving visit"})
+		panic(err)
+	}
+}
+
+// POST: Open a new visit for a registered patient
+func (e *APIRoutes) OpenEncounter(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var encounterReq models.Encounter
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	defer r.Body.Close()
+
+	if err = json.Unmarshal(body, &encounterReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for visit"})
+		log.Println(err)
+		return
+	}
+	encounterReq.ChiefComplaint = strings.TrimSpace(encounterReq.ChiefComplaint)
+
+	// validate request body
+	if err := models.ValidateEncounterReq(encounterReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	// doctors open visits with themselves for their own patients
+	scope := patientScope(r)
+	if scope.Valid {
+		encounterReq.DoctorID = scope.UUID
+	}
+
+	encounterID, err := e.service.OpenEncounter(tokenID, &encounterReq, middleware.UserIDFromContext(r.Context()), scope)
+	if err != nil {
+		encounterErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Visit opened successfully!", Data: map[string]uuid.UUID{"encounter_id": encounterID}})
+	log.Printf("Visit %s opened for patient %s by %s", encounterID, tokenID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return visit history of a patient, newest first
+func (e *APIRoutes) GetPatientEncounters(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	// doctors can only view their own patients
+	allowed, err := e.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view patients of another doctor"})
+		log.Println("Doctor denied access to visits of patient assigned to another doctor")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := e.service.GetEncounters(store.EncounterFilter{TokenID: tokenID}, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Patient visit history populated successfully")
+}
+
+// GET: Return visits filtered by status, doctor and date, doctors only see their own visits
+func (e *APIRoutes) GetAllEncounters(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	filter := store.EncounterFilter{
+		Status:    strings.TrimSpace(query.Get("status")),
+		VisitDate: strings.TrimSpace(query.Get("date")),
+	}
+
+	if err := models.ValidateEncounterStatus(filter.Status); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	if filter.VisitDate != "" {
+		if _, err := time.Parse(time.DateOnly, filter.VisitDate); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "date must be in YYYY-MM-DD format"})
+			log.Println(err)
+			return
+		}
+	}
+
+	if doctorID := strings.TrimSpace(query.Get("doctor_id")); doctorID != "" {
+		parsedDoctorID, err := uuid.Parse(doctorID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+			log.Println("Invalid doctor ID")
+			return
+		}
+		filter.DoctorID = uuid.NullUUID{UUID: parsedDoctorID, Valid: true}
+	}
+
+	// doctors can only list their own visits
+	if scope := patientScope(r); scope.Valid {
+		if filter.DoctorID.Valid && filter.DoctorID.UUID != scope.UUID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view visits of another doctor"})
+			log.Println("Doctor denied access to visits of another doctor")
+			return
+		}
+		filter.DoctorID = scope
+	}
+
+	resp, err := e.service.GetEncounters(filter, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("All visits data populated successfully")
+}
+
+// GET: Return a single visit
+func (e *APIRoutes) GetEncounter(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	encounterID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["encounter_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid visit ID"})
+		log.Println("Invalid visit ID")
+		return
+	}
+
+	resp, err := e.service.GetEncounter(encounterID.String())
+	if err != nil {
+		if errors.Is(err, store.ErrEncounterNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	// doctors can only view their own visits
+	if scope := patientScope(r); scope.Valid && resp.DoctorID != scope.UUID.String() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view visits of another doctor"})
+		log.Println("Doctor denied access to visit of another doctor")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Visit data populated successfully")
+}
+
+// Reads and validates the fields of a visit update, an empty body is allowed when optional
+func readEncounterFields(w http.ResponseWriter, r *http.Request, optional bool) (map[string]string, bool) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	defer r.Body.Close()
+
+	fields := make(map[string]string)
+	if optional && len(strings.TrimSpace(string(body))) == 0 {
+		return fields, true
+	}
+
+	if err := models.ValidateEncounterPatchReq(body); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return nil, false
+	}
+
+	_ = json.Unmarshal(body, &fields)
+	for key, value := range fields {
+		fields[key] = strings.TrimSpace(value)
+	}
+	return fields, true
+}
+
+// Checks drugs named in a visit's treatment, the override reason is taken out of fields.
+// Returns the warnings overridden and the patient's token ID to record them against.
+func (e *APIRoutes) checkEncounterTreatment(w http.ResponseWriter, r *http.Request, encounterID string, fields map[string]string) ([]models.MedicationWarning, string, string, bool) {
+
+	overrideReason := fields["override_reason"]
+	delete(fields, "override_reason")
+	if fields["treatment"] == "" {
+		return nil, "", "", true
+	}
+
+	encounter, err := e.service.GetEncounter(encounterID)
+	if err != nil {
+		encounterErrorResponse(w, err)
+		return nil, "", "", false
+	}
+	// warnings name the patient's allergies and medications
+	if scope := patientScope(r); scope.Valid && encounter.DoctorID != scope.UUID.String() {
+		encounterErrorResponse(w, store.ErrEncounterNotFound)
+		return nil, "", "", false
+	}
+
+	warnings, ok := e.checkTreatmentSafety(w, encounter.TokenID, fields["treatment"], overrideReason)
+	return warnings, encounter.TokenID, overrideReason, ok
+}
+
+// PATCH: Record findings and treatment on an open visit
+func (e *APIRoutes) UpdateEncounter(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	encounterID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["encounter_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid visit ID"})
+		log.Println("Invalid visit ID")
+		return
+	}
+
+	fields, ok := readEncounterFields(w, r, false)
+	if !ok {
+		return
+	}
+
+	warnings, tokenID, overrideReason, ok := e.checkEncounterTreatment(w, r, encounterID.String(), fields)
+	if !ok {
+		return
+	}
+
+	err = e.service.UpdateEncounter(encounterID.String(), fields, uuid.NullUUID{}, patientScope(r), middleware.UserIDFromContext(r.Context()), warnings, strings.TrimSpace(overrideReason))
+	if err != nil {
+		encounterErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Visit updated successfully!", Data: warningsData(warnings)})
+	log.Printf("Visit %s updated by %s", encounterID, middleware.EmailFromContext(r.Context()))
+	logTreatmentOverride(r, tokenID, warnings)
+}
+
+// POST: Close an open visit, doctors may record final findings and treatment with it
+func (e *APIRoutes) CloseEncounter(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	encounterID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["encounter_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid visit ID"})
+		log.Println("Invalid visit ID")
+		return
+	}
+
+	fields, ok := readEncounterFields(w, r, true)
+	if !ok {
+		return
+	}
+
+	// only doctors can write clinical details
+	if len(fields) > 0 && middleware.RoleFromContext(r.Context()) != models.RoleDoctor {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "Only doctors can record findings and treatment"})
+		log.Println("Non doctor attempted to record visit details")
+		return
+	}
+
+	warnings, tokenID, overrideReason, ok := e.checkEncounterTreatment(w, r, encounterID.String(), fields)
+	if !ok {
+		return
+	}
+
+	closedBy := uuid.NullUUID{UUID: middleware.UserIDFromContext(r.Context()), Valid: true}
+	err = e.service.UpdateEncounter(encounterID.String(), fields, closedBy, patientScope(r), middleware.UserIDFromContext(r.Context()), warnings, strings.TrimSpace(overrideReason))
+	if err != nil {
+		encounterErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Visit closed successfully!", Data: warningsData(warnings)})
+	log.Printf("Visit %s closed by %s", encounterID, middleware.EmailFromContext(r.Context()))
+	logTreatmentOverride(r, tokenID, warnings)
+}
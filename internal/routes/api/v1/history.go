@@ -0,0 +1,309 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Invalid request body for a history record, reported with 400
+type historyRequestError struct {
+	error
+}
+
+// Writes response for errors returned while reading or changing medical history
+func historyErrorResponse(w http.ResponseWriter, err error) {
+	var requestErr historyRequestError
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.As(err, &requestErr):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrHistoryRecordNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrAllergyExists):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving medical history"})
+		panic(err)
+	}
+}
+
+// Validates token ID of the path and that the patient is within the caller's scope
+func (h *APIRoutes) historyTokenID(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return "", false
+	}
+
+	// doctors can only access their own patients
+	allowed, err := h.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access patients of another doctor"})
+		log.Println("Doctor denied access to medical history of patient assigned to another doctor")
+		return "", false
+	}
+	return tokenID, true
+}
+
+// Decodes and validates a record of the path's record type, then adds it or updates recordID when set
+func (h *APIRoutes) saveHistoryRecord(r *http.Request, recordType string, tokenID string, recordID string) (uuid.UUID, error) {
+
+	recordedBy := middleware.UserIDFromContext(r.Context())
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+
+	switch recordType {
+	case models.HistoryAllergies:
+		var allergyReq models.Allergy
+		if err := decoder.Decode(&allergyReq); err != nil {
+			return uuid.Nil, historyRequestError{errors.New("Invalid Request body for allergy")}
+		}
+		allergyReq.Substance, allergyReq.Reaction, allergyReq.NotedOn = strings.TrimSpace(allergyReq.Substance), strings.TrimSpace(allergyReq.Reaction), strings.TrimSpace(allergyReq.NotedOn)
+		if allergyReq.Severity = strings.ToLower(strings.TrimSpace(allergyReq.Severity)); allergyReq.Severity == "" {
+			allergyReq.Severity = "moderate"
+		}
+		if err := models.ValidateAllergyReq(allergyReq); err != nil {
+			return uuid.Nil, historyRequestError{err}
+		}
+		if recordID == "" {
+			return h.service.AddAllergy(tokenID, &allergyReq, recordedBy)
+		}
+		return uuid.Nil, h.service.UpdateAllergy(tokenID, recordID, &allergyReq)
+
+	case models.HistoryConditions:
+		var conditionReq models.Condition
+		if err := decoder.Decode(&conditionReq); err != nil {
+			return uuid.Nil, historyRequestError{errors.New("Invalid Request body for condition")}
+		}
+		conditionReq.Name, conditionReq.Notes = strings.TrimSpace(conditionReq.Name), strings.TrimSpace(conditionReq.Notes)
+		conditionReq.DiagnosedOn, conditionReq.ResolvedOn = strings.TrimSpace(conditionReq.DiagnosedOn), strings.TrimSpace(conditionReq.ResolvedOn)
+		if conditionReq.Status = strings.ToLower(strings.TrimSpace(conditionReq.Status)); conditionReq.Status == "" {
+			conditionReq.Status = models.ConditionStatusActive
+		}
+		if err := models.ValidateConditionReq(conditionReq); err != nil {
+			return uuid.Nil, historyRequestError{err}
+		}
+		if recordID == "" {
+			return h.service.AddCondition(tokenID, &conditionReq, recordedBy)
+		}
+		return uuid.Nil, h.service.UpdateCondition(tokenID, recordID, &conditionReq)
+
+	case models.HistorySurgeries:
+		var surgeryReq models.Surgery
+		if err := decoder.Decode(&surgeryReq); err != nil {
+			return uuid.Nil, historyRequestError{errors.New("Invalid Request body for surgery")}
+		}
+		surgeryReq.Procedure, surgeryReq.PerformedOn = strings.TrimSpace(surgeryReq.Procedure), strings.TrimSpace(surgeryReq.PerformedOn)
+		surgeryReq.Hospital, surgeryReq.Notes = strings.TrimSpace(surgeryReq.Hospital), strings.TrimSpace(surgeryReq.Notes)
+		if err := models.ValidateSurgeryReq(surgeryReq); err != nil {
+			return uuid.Nil, historyRequestError{err}
+		}
+		if recordID == "" {
+			return h.service.AddSurgery(tokenID, &surgeryReq, recordedBy)
+		}
+		return uuid.Nil, h.service.UpdateSurgery(tokenID, recordID, &surgeryReq)
+
+	default: // models.HistoryFamilyHistory, the route only matches known record types
+		var familyReq models.FamilyHistory
+		if err := decoder.Decode(&familyReq); err != nil {
+			return uuid.Nil, historyRequestError{errors.New("Invalid Request body for family history")}
+		}
+		familyReq.Relation, familyReq.Condition, familyReq.Notes = strings.ToLower(strings.TrimSpace(familyReq.Relation)), strings.TrimSpace(familyReq.Condition), strings.TrimSpace(familyReq.Notes)
+		if err := models.ValidateFamilyHistoryReq(familyReq); err != nil {
+			return uuid.Nil, historyRequestError{err}
+		}
+		if recordID == "" {
+			return h.service.AddFamilyHistory(tokenID, &familyReq, recordedBy)
+		}
+		return uuid.Nil, h.service.UpdateFamilyHistory(tokenID, recordID, &familyReq)
+	}
+}
+
+// GET: Return allergies, conditions, surgeries and family history of a patient
+func (h *APIRoutes) GetMedicalHistory(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID, ok := h.historyTokenID(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.service.GetMedicalHistory(tokenID)
+	if err != nil {
+		historyErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: history})
+	log.Println("Medical history populated successfully for token ID- ", tokenID)
+}
+
+// GET: Return one kind of history records of a patient
+func (h *APIRoutes) GetHistoryRecords(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID, ok := h.historyTokenID(w, r)
+	if !ok {
+		return
+	}
+
+	recordType := mux.Vars(r)["record_type"]
+	records, err := h.service.GetHistoryRecords(recordType, tokenID)
+	if err != nil {
+		historyErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: records})
+	log.Printf("Patient %s %s populated successfully", tokenID, recordType)
+}
+
+// POST: Record an allergy, condition, surgery or family history of a patient
+func (h *APIRoutes) AddHistoryRecord(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID, ok := h.historyTokenID(w, r)
+	if !ok {
+		return
+	}
+
+	recordType := mux.Vars(r)["record_type"]
+	recordID, err := h.saveHistoryRecord(r, recordType, tokenID, "")
+	if err != nil {
+		historyErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Medical history recorded successfully!", Data: map[string]uuid.UUID{"record_id": recordID}})
+	log.Printf("Patient %s %s record %s added by %s", tokenID, recordType, recordID, middleware.EmailFromContext(r.Context()))
+}
+
+// PUT: Replace a history record of a patient
+func (h *APIRoutes) UpdateHistoryRecord(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID, ok := h.historyTokenID(w, r)
+	if !ok {
+		return
+	}
+
+	params := mux.Vars(r)
+	recordID, err := uuid.Parse(strings.TrimSpace(params["record_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid record ID"})
+		log.Println("Invalid record ID")
+		return
+	}
+
+	if _, err = h.saveHistoryRecord(r, params["record_type"], tokenID, recordID.String()); err != nil {
+		historyErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Medical history updated successfully!"})
+	log.Printf("Patient %s %s record %s updated by %s", tokenID, params["record_type"], recordID, middleware.EmailFromContext(r.Context()))
+}
+
+// DELETE: Remove a history record entered in error
+func (h *APIRoutes) DeleteHistoryRecord(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID, ok := h.historyTokenID(w, r)
+	if !ok {
+		return
+	}
+
+	params := mux.Vars(r)
+	recordID, err := uuid.Parse(strings.TrimSpace(params["record_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid record ID"})
+		log.Println("Invalid record ID")
+		return
+	}
+
+	if err = h.service.DeleteHistoryRecord(params["record_type"], tokenID, recordID.String()); err != nil {
+		historyErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Medical history record removed successfully!"})
+	log.Printf("Patient %s %s record %s removed by %s", tokenID, params["record_type"], recordID, middleware.EmailFromContext(r.Context()))
+}
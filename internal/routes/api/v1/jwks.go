@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+)
+
+// GET: Return public keys that verify MediGo tokens as JSON Web Key Set
+func (a *APIRoutes) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auth.JWKS())
+}
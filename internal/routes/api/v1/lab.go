@@ -0,0 +1,412 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Writes response for errors returned while reading or changing lab orders
+func labErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrLabOrderNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrLabOrderStatus), errors.Is(err, store.ErrAbnormalNotAcknowledged):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving lab order"})
+		panic(err)
+	}
+}
+
+// Reads lab order of the path and checks it is within the caller's scope, doctors see orders of their patients and those they placed
+func (l *APIRoutes) labOrderFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	labOrderID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["lab_order_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid lab order ID"})
+		log.Println("Invalid lab order ID")
+		return "", false
+	}
+
+	order, err := l.service.GetLabOrder(labOrderID.String())
+	if err != nil {
+		labErrorResponse(w, err)
+		return "", false
+	}
+
+	allowed, err := l.canAccessPatient(r, order.TokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed && order.DoctorID != middleware.UserIDFromContext(r.Context()).String() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access lab orders of another doctor's patient"})
+		log.Println("Doctor denied access to lab order of patient assigned to another doctor")
+		return "", false
+	}
+	return order.LabOrderID, true
+}
+
+// POST: Order a test panel for one of the doctor's patients
+func (l *APIRoutes) CreateLabOrder(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var labOrderReq models.LabOrder
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&labOrderReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for lab order"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	labOrderReq.Panel, labOrderReq.Notes = strings.TrimSpace(labOrderReq.Panel), strings.TrimSpace(labOrderReq.Notes)
+	labOrderReq.Priority = strings.ToLower(strings.TrimSpace(labOrderReq.Priority))
+	if labOrderReq.Priority == "" {
+		labOrderReq.Priority = models.LabPriorityRoutine
+	}
+
+	if err := models.ValidateLabOrderReq(labOrderReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	labOrderID, err := l.service.CreateLabOrder(tokenID, &labOrderReq, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		labErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Lab order placed successfully!", Data: map[string]interface{}{"lab_order_id": labOrderID}})
+	log.Printf("Lab order %s placed for patient %s by %s", labOrderID, tokenID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return lab orders of a patient, most urgent and newest first
+func (l *APIRoutes) GetPatientLabOrders(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	// doctors can only access their own patients
+	allowed, err := l.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access patients of another doctor"})
+		log.Println("Doctor denied access to lab orders of patient assigned to another doctor")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := l.service.GetLabOrders(store.LabOrderFilter{TokenID: tokenID}, int32(limit), int32(offset))
+	if err != nil {
+		labErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Lab orders data populated successfully for token ID- ", tokenID)
+}
+
+// GET: Return lab orders filtered by status, doctor or abnormal results, doctors see only orders they placed
+func (l *APIRoutes) GetAllLabOrders(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	filter := store.LabOrderFilter{
+		Status:   strings.ToLower(strings.TrimSpace(query.Get("status"))),
+		Abnormal: query.Get("abnormal") == "true",
+	}
+
+	switch filter.Status {
+	case "", models.LabStatusOrdered, models.LabStatusCollected, models.LabStatusResulted, models.LabStatusReviewed:
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "status must be one of following - ['ordered', 'collected', 'resulted', 'reviewed']"})
+		log.Println("Invalid lab order status")
+		return
+	}
+
+	if doctorID := strings.TrimSpace(query.Get("doctor_id")); doctorID != "" {
+		parsedDoctorID, err := uuid.Parse(doctorID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+			log.Println("Invalid doctor ID")
+			return
+		}
+		filter.DoctorID = uuid.NullUUID{UUID: parsedDoctorID, Valid: true}
+	}
+
+	// doctors can only list their own orders
+	if scope := patientScope(r); scope.Valid {
+		if filter.DoctorID.Valid && filter.DoctorID.UUID != scope.UUID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view lab orders of another doctor"})
+			log.Println("Doctor denied access to lab orders of another doctor")
+			return
+		}
+		filter.DoctorID = scope
+	}
+
+	resp, err := l.service.GetLabOrders(filter, int32(limit), int32(offset))
+	if err != nil {
+		labErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("All lab orders data populated successfully")
+}
+
+// GET: Return a single lab order with its results
+func (l *APIRoutes) GetLabOrder(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	labOrderID, ok := l.labOrderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := l.service.GetLabOrder(labOrderID)
+	if err != nil {
+		labErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Lab order data populated successfully for ID- ", labOrderID)
+}
+
+// POST: Mark the sample of an ordered test as collected
+func (l *APIRoutes) CollectLabOrder(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	labOrderID, ok := l.labOrderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := l.service.CollectLabOrder(labOrderID, middleware.UserIDFromContext(r.Context())); err != nil {
+		labErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Sample marked as collected!"})
+	log.Printf("Sample of lab order %s collected by %s", labOrderID, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Record results of a collected sample, values outside reference ranges are flagged in the response
+func (l *APIRoutes) RecordLabResults(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var resultsReq models.LabResults
+
+	labOrderID, ok := l.labOrderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&resultsReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for lab results"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	for i := range resultsReq.Results {
+		result := &resultsReq.Results[i]
+		result.Analyte, result.Unit = strings.TrimSpace(result.Analyte), strings.TrimSpace(result.Unit)
+	}
+
+	if err := models.ValidateLabResultsReq(resultsReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	results, err := l.service.RecordLabResults(labOrderID, resultsReq.Results, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		labErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Lab results recorded successfully!", Data: map[string]interface{}{"results": results}})
+	log.Printf("Results of lab order %s recorded by %s", labOrderID, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Review results of an order, the ordering doctor must acknowledge abnormal results
+func (l *APIRoutes) ReviewLabOrder(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var reviewReq models.LabReview
+
+	labOrderID, ok := l.labOrderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&reviewReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for lab review"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+	reviewReq.Note = strings.TrimSpace(reviewReq.Note)
+
+	err := l.service.ReviewLabOrder(labOrderID, &reviewReq, middleware.UserIDFromContext(r.Context()))
+	if errors.Is(err, store.ErrAbnormalNotAcknowledged) {
+		// show the doctor what has to be acknowledged
+		order, readErr := l.service.GetLabOrder(labOrderID)
+		if readErr != nil {
+			labErrorResponse(w, readErr)
+			return
+		}
+		abnormal := make([]models.LabResult, 0)
+		for _, result := range order.Results {
+			if result.Flag != models.LabFlagNormal {
+				abnormal = append(abnormal, result)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error(), Data: map[string]interface{}{"abnormal_results": abnormal}})
+		log.Println(err)
+		return
+	}
+	if err != nil {
+		labErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Lab order reviewed successfully!"})
+	log.Printf("Lab order %s reviewed by %s", labOrderID, middleware.EmailFromContext(r.Context()))
+}
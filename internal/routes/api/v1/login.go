@@ -2,13 +2,20 @@ package routes
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
 	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
 )
 
 // POST: Return auth token based on credentials
@@ -43,26 +50,89 @@ func (l *APIRoutes) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	loginResponse, err := l.service.GetLoginInfo(&credentials)
+	clientIP := middleware.ClientIP(r)
+
+	// Reject attempts while email or client IP is delayed or locked
+	throttle, err := l.service.CheckLoginThrottle(credentials.Email, clientIP)
 	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during authentication"})
+		panic(err)
+	}
+	if throttle.RetryAfter > 0 {
+		retryAfter := int(math.Ceil(throttle.RetryAfter.Seconds()))
+		message := fmt.Sprintf("Too many failed attempts, try again in %d seconds", retryAfter)
+		if throttle.Locked {
+			message = fmt.Sprintf("Login temporarily locked after too many failed attempts, try again in %d minutes or contact an admin", int(math.Ceil(throttle.RetryAfter.Minutes())))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusTooManyRequests, Message: message})
+		log.Printf("Login throttled for %s from %s", credentials.Email, clientIP)
+		return
+	}
+
+	loginResponse, err := l.service.GetLoginInfo(&credentials)
+	if err != nil && !errors.Is(err, store.ErrAccountNotFound) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during authentication"})
 		panic(err)
 	}
 
-	// Verifying a password
-	err = auth.CheckPassowrd(loginResponse.HashedPassword, credentials.Password)
+	// Verifying a password, unknown accounts take as long as a wrong password
+	failureReason := "wrong_password"
+	if errors.Is(err, store.ErrAccountNotFound) {
+		auth.CheckDummyPassword(credentials.Password)
+		failureReason = "unknown_account"
+	} else {
+		err = auth.CheckPassowrd(loginResponse.HashedPassword, credentials.Password)
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		if recErr := l.service.RecordLoginFailure(credentials.Email, credentials.Role, clientIP, failureReason); recErr != nil {
+			log.Println("Failed to record login failure: ", recErr)
+		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Incorrect email or password for authentication"})
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusUnauthorized, Message: "Incorrect email or password for authentication"})
 		log.Println("Incorrect username or password for authentication")
 		return
 	}
 
-	// Generate JWT token for authentication
-	tokenString, err := auth.GenerateToken(credentials.Email, loginResponse.UserID)
+	// Password step passed, tokens are only issued after the OTP step when two-factor applies
+	if loginResponse.MFAEnabled || loginResponse.MFARequired {
+		mfaToken, err := auth.GenerateMFAToken(credentials.Email, loginResponse.UserID, loginResponse.Role)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Failed to generate token for authentication"})
+			panic(err)
+		}
+
+		message := "Two-factor code required, submit it to /api/v1/login/mfa"
+		if !loginResponse.MFAEnabled {
+			message = "Two-factor authentication is required, enroll via /api/v1/login/mfa/enroll"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusAccepted, Message: message, Data: map[string]interface{}{
+			"mfa_required": true,
+			"mfa_enrolled": loginResponse.MFAEnabled,
+			"mfa_token":    mfaToken,
+		}})
+		log.Println("Password verified, awaiting two-factor code")
+		return
+	}
+
+	if err := l.service.RecordLoginSuccess(credentials.Email, loginResponse.Role, clientIP); err != nil {
+		log.Println("Failed to record login success: ", err)
+	}
+
+	// Generate access and refresh token for authentication
+	owner := store.RefreshTokenOwner{UserID: loginResponse.UserID, Email: credentials.Email, Role: loginResponse.Role, FamilyID: uuid.New()}
+	tokens, err := l.startTokenFamily(owner)
 
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -71,10 +141,9 @@ func (l *APIRoutes) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		log.Println("Failed to generate token for authentication")
 		panic(err)
 	}
-	resp := map[string]string{"token": tokenString}
 	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Authentication token generated successfully. Valid for next 30mins", Data: resp})
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Authentication token generated successfully", Data: tokens})
 
 	log.Println("Authentication token generated successfully")
 }
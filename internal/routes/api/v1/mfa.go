@@ -0,0 +1,564 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/config"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+const recoveryCodeCount = 10
+
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaRequirementRequest struct {
+	Required *bool `json:"required"`
+}
+
+// Returns issuer shown in authenticator apps
+func mfaIssuer() string {
+	mfaConfig, err := config.MFAConfig()
+	if err != nil || mfaConfig.Issuer == "" {
+		return "MediGo"
+	}
+	return mfaConfig.Issuer
+}
+
+// Generates and stores a pending TOTP secret, returning what the authenticator app needs
+func (m *APIRoutes) startMFAEnrollment(userID uuid.UUID, email string) (map[string]string, error) {
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.service.StartMFAEnrollment(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(mfaIssuer(), email, secret), // render as QR code for authenticator apps
+	}, nil
+}
+
+// Confirms pending TOTP secret with a code and returns fresh recovery codes
+func (m *APIRoutes) confirmMFAEnrollment(userID uuid.UUID, secret string, code string) ([]string, bool, error) {
+
+	step, valid := auth.ValidateTOTP(secret, code, time.Now())
+	if !valid {
+		return nil, false, nil
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err = m.service.ConfirmMFAEnrollment(userID, step, recoveryCodeHashes); err != nil {
+		return nil, false, err
+	}
+	return recoveryCodes, true, nil
+}
+
+// Verifies TOTP code or unused recovery code of a user with enabled two-factor authentication
+func (m *APIRoutes) verifySecondFactor(userID uuid.UUID, secret store.MFASecret, code string) (bool, error) {
+
+	if step, valid := auth.ValidateTOTP(secret.Secret, code, time.Now()); valid {
+		err := m.service.MarkTOTPStepUsed(userID, step)
+		if errors.Is(err, store.ErrTOTPReplayed) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	return m.service.UseRecoveryCode(userID, auth.HashRecoveryCode(code))
+}
+
+// Rejects the request while email or client IP is throttled, returns true if a response was written
+func (m *APIRoutes) writeThrottled(w http.ResponseWriter, email string, clientIP string) bool {
+
+	throttle, err := m.service.CheckLoginThrottle(email, clientIP)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during authentication"})
+		panic(err)
+	}
+	if throttle.RetryAfter <= 0 {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttle.RetryAfter.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusTooManyRequests, Message: "Too many failed attempts, try again later"})
+	log.Printf("Two-factor verification throttled for %s from %s", email, clientIP)
+	return true
+}
+
+// POST: Second login step, exchanges challenge token and TOTP or recovery code for auth tokens
+func (m *APIRoutes) LoginMFAHandler(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var mfaReq mfaLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&mfaReq); err != nil || mfaReq.MFAToken == "" || strings.TrimSpace(mfaReq.Code) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - mfa_token, code are mandatory fields"})
+		log.Println("Invalid Request body for two-factor login")
+		return
+	}
+
+	claims, err := auth.VerifyMFAToken(mfaReq.MFAToken)
+	if err == nil {
+		var revoked bool
+		revoked, err = m.service.IsTokenRevoked(claims.Id)
+		if err == nil && revoked {
+			err = errors.New("mfa token already used")
+		}
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusUnauthorized, Message: "Invalid or expired mfa token, login again"})
+		log.Println("Invalid mfa token: ", err)
+		return
+	}
+
+	clientIP := middleware.ClientIP(r)
+	if m.writeThrottled(w, claims.Email, clientIP) {
+		return
+	}
+
+	secret, err := m.service.GetMFASecret(claims.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrMFANotEnrolled) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Two-factor authentication is not enrolled, call /api/v1/login/mfa/enroll first"})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during authentication"})
+		panic(err)
+	}
+
+	// a pending secret is confirmed by the first valid code, completing enrollment required by an admin
+	var recoveryCodes []string
+	var verified bool
+	if secret.Enabled {
+		verified, err = m.verifySecondFactor(claims.UserID, secret, mfaReq.Code)
+	} else {
+		recoveryCodes, verified, err = m.confirmMFAEnrollment(claims.UserID, secret.Secret, mfaReq.Code)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during authentication"})
+		panic(err)
+	}
+	if !verified {
+		if recErr := m.service.RecordLoginFailure(claims.Email, claims.Role, clientIP, "wrong_otp"); recErr != nil {
+			log.Println("Failed to record login failure: ", recErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusUnauthorized, Message: "Incorrect two-factor code"})
+		log.Println("Incorrect two-factor code for authentication")
+		return
+	}
+
+	// challenge token is single use
+	if err := m.service.RevokeAccessToken(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		log.Println("Failed to revoke mfa token: ", err)
+	}
+	if err := m.service.RecordLoginSuccess(claims.Email, claims.Role, clientIP); err != nil {
+		log.Println("Failed to record login success: ", err)
+	}
+
+	owner := store.RefreshTokenOwner{UserID: claims.UserID, Email: claims.Email, Role: claims.Role, FamilyID: uuid.New()}
+	tokens, err := m.startTokenFamily(owner)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Failed to generate token for authentication"})
+		panic(err)
+	}
+	if recoveryCodes != nil {
+		tokens["recovery_codes"] = recoveryCodes
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Authentication token generated successfully", Data: tokens})
+	log.Println("Authentication token generated successfully after two-factor verification")
+}
+
+// POST: Start enrollment during login when an admin requires two-factor authentication
+func (m *APIRoutes) LoginMFAEnrollHandler(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var mfaReq mfaLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&mfaReq); err != nil || mfaReq.MFAToken == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - mfa_token is a mandatory field"})
+		log.Println("Invalid Request body for two-factor enrollment")
+		return
+	}
+
+	claims, err := auth.VerifyMFAToken(mfaReq.MFAToken)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusUnauthorized, Message: "Invalid or expired mfa token, login again"})
+		log.Println("Invalid mfa token: ", err)
+		return
+	}
+
+	m.writeMFAEnrollment(w, claims.UserID, claims.Email)
+}
+
+// POST: Start two-factor enrollment for the logged in user
+func (m *APIRoutes) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	m.writeMFAEnrollment(w, middleware.UserIDFromContext(r.Context()), middleware.EmailFromContext(r.Context()))
+}
+
+func (m *APIRoutes) writeMFAEnrollment(w http.ResponseWriter, userID uuid.UUID, email string) {
+
+	enrollment, err := m.startMFAEnrollment(userID, email)
+	if err != nil {
+		if errors.Is(err, store.ErrMFAAlreadyEnabled) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during two-factor enrollment"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Scan the provisioning URI with an authenticator app and confirm with a code", Data: enrollment})
+	log.Println("Two-factor enrollment started")
+}
+
+// POST: Confirm two-factor enrollment of the logged in user with a code
+func (m *APIRoutes) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var codeReq mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&codeReq); err != nil || strings.TrimSpace(codeReq.Code) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - code is a mandatory field"})
+		log.Println("Invalid Request body for two-factor confirmation")
+		return
+	}
+
+	secret, err := m.service.GetMFASecret(userID)
+	if err == nil && secret.Enabled {
+		err = store.ErrMFAAlreadyEnabled
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrMFANotEnrolled) || errors.Is(err, store.ErrMFAAlreadyEnabled) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during two-factor enrollment"})
+		panic(err)
+	}
+
+	recoveryCodes, verified, err := m.confirmMFAEnrollment(userID, secret.Secret, codeReq.Code)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during two-factor enrollment"})
+		panic(err)
+	}
+	if !verified {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Incorrect two-factor code"})
+		log.Println("Incorrect two-factor code for enrollment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Two-factor authentication enabled. Store the recovery codes safely, they are shown only once", Data: map[string][]string{"recovery_codes": recoveryCodes}})
+	log.Println("Two-factor authentication enabled")
+}
+
+// POST: Disable two-factor authentication of the logged in user with a code
+func (m *APIRoutes) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	m.withSecondFactor(w, r, func(userID uuid.UUID) {
+
+		required, err := m.service.IsMFARequired(userID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while disabling two-factor authentication"})
+			panic(err)
+		}
+		if required {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: store.ErrMFARequiredByAdmin.Error()})
+			log.Println(store.ErrMFARequiredByAdmin)
+			return
+		}
+
+		if _, err := m.service.DisableMFA(userID); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while disabling two-factor authentication"})
+			panic(err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Two-factor authentication disabled"})
+		log.Println("Two-factor authentication disabled")
+	})
+}
+
+// POST: Replace recovery codes of the logged in user with a code
+func (m *APIRoutes) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	m.withSecondFactor(w, r, func(userID uuid.UUID) {
+
+		recoveryCodes, recoveryCodeHashes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+		if err == nil {
+			err = m.service.ReplaceRecoveryCodes(userID, recoveryCodeHashes)
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while generating recovery codes"})
+			panic(err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Recovery codes replaced. Store them safely, they are shown only once", Data: map[string][]string{"recovery_codes": recoveryCodes}})
+		log.Println("Recovery codes replaced")
+	})
+}
+
+// Runs next only after the logged in user proved possession of the second factor
+func (m *APIRoutes) withSecondFactor(w http.ResponseWriter, r *http.Request, next func(userID uuid.UUID)) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	userID := middleware.UserIDFromContext(r.Context())
+	email := middleware.EmailFromContext(r.Context())
+	clientIP := middleware.ClientIP(r)
+
+	var codeReq mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&codeReq); err != nil || strings.TrimSpace(codeReq.Code) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - code is a mandatory field"})
+		log.Println("Invalid Request body for two-factor verification")
+		return
+	}
+
+	if m.writeThrottled(w, email, clientIP) {
+		return
+	}
+
+	secret, err := m.service.GetMFASecret(userID)
+	if err == nil && !secret.Enabled {
+		err = store.ErrMFANotEnrolled
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrMFANotEnrolled) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during two-factor verification"})
+		panic(err)
+	}
+
+	verified, err := m.verifySecondFactor(userID, secret, codeReq.Code)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during two-factor verification"})
+		panic(err)
+	}
+	if !verified {
+		if recErr := m.service.RecordLoginFailure(email, middleware.RoleFromContext(r.Context()), clientIP, "wrong_otp"); recErr != nil {
+			log.Println("Failed to record login failure: ", recErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusUnauthorized, Message: "Incorrect two-factor code"})
+		log.Println("Incorrect two-factor code")
+		return
+	}
+
+	next(userID)
+}
+
+// PUT: Require or stop requiring two-factor authentication for an account
+func (m *APIRoutes) SetAccountMFARequirement(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	params := mux.Vars(r)
+	accountID, err := uuid.Parse(strings.TrimSpace(params["account_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid account ID"})
+		log.Println("Invalid account ID")
+		return
+	}
+
+	var requirementReq mfaRequirementRequest
+	if err := json.NewDecoder(r.Body).Decode(&requirementReq); err != nil || requirementReq.Required == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - required is a mandatory field"})
+		log.Println("Invalid Request body for two-factor requirement")
+		return
+	}
+
+	updatedAccount, err := m.service.SetMFARequired(params["account_type"], accountID.String(), *requirementReq.Required)
+	if err != nil {
+		accountErrorResponse(w, err)
+		return
+	}
+	if updatedAccount == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: "No account present for provided ID"})
+		log.Println("value of updatedAccount is ", updatedAccount)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: fmt.Sprintf("Two-factor requirement set to %t", *requirementReq.Required)})
+	log.Printf("Two-factor requirement of %s set to %t by %s", accountID, *requirementReq.Required, middleware.EmailFromContext(r.Context()))
+}
+
+// DELETE: Reset two-factor authentication of an account that lost its authenticator
+func (m *APIRoutes) ResetAccountMFA(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	params := mux.Vars(r)
+	accountID, err := uuid.Parse(strings.TrimSpace(params["account_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid account ID"})
+		log.Println("Invalid account ID")
+		return
+	}
+
+	resetAccount, err := m.service.ResetAccountMFA(params["account_type"], accountID.String())
+	if err != nil {
+		accountErrorResponse(w, err)
+		return
+	}
+	if resetAccount == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: "No account present for provided ID"})
+		log.Println("value of resetAccount is ", resetAccount)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Two-factor authentication of %s reset by %s", accountID, middleware.EmailFromContext(r.Context()))
+}
@@ -0,0 +1,391 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Writes response for errors returned while reading or changing clinical notes
+func noteErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrNoteNotFound), errors.Is(err, store.ErrNoteVersionNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrNoteSigned), errors.Is(err, store.ErrNoteNotSigned):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving clinical note"})
+		panic(err)
+	}
+}
+
+// Reads note of the path and checks it is within the caller's scope, doctors see notes of their patients and those they wrote
+func (n *APIRoutes) noteFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	noteID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["note_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid note ID"})
+		log.Println("Invalid note ID")
+		return "", false
+	}
+
+	note, err := n.service.GetNote(noteID.String())
+	if err != nil {
+		noteErrorResponse(w, err)
+		return "", false
+	}
+
+	allowed, err := n.canAccessPatient(r, note.TokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed && note.DoctorID != middleware.UserIDFromContext(r.Context()).String() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access notes of another doctor's patient"})
+		log.Println("Doctor denied access to clinical note of patient assigned to another doctor")
+		return "", false
+	}
+	return note.NoteID, true
+}
+
+// POST: Start a draft clinical note for one of the doctor's patients
+func (n *APIRoutes) CreateNote(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var noteReq models.SOAPNote
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&noteReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for clinical note"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	noteReq.Trim()
+	if err := models.ValidateSOAPNoteReq(noteReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	noteID, err := n.service.CreateNote(tokenID, &noteReq, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Clinical note saved as draft!", Data: map[string]interface{}{"note_id": noteID}})
+	log.Printf("Clinical note %s started for patient %s by %s", noteID, tokenID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return clinical notes of a patient with their latest version, newest first
+func (n *APIRoutes) GetPatientNotes(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	// doctors can only access their own patients
+	allowed, err := n.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access patients of another doctor"})
+		log.Println("Doctor denied access to clinical notes of patient assigned to another doctor")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := n.service.GetNotes(tokenID, int32(limit), int32(offset))
+	if err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Clinical notes data populated successfully for token ID- ", tokenID)
+}
+
+// GET: Return a clinical note with its latest version
+func (n *APIRoutes) GetNote(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	noteID, ok := n.noteFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := n.service.GetNote(noteID)
+	if err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Clinical note data populated successfully for ID- ", noteID)
+}
+
+// PUT: Replace the content of a draft note, signed notes are amended instead
+func (n *APIRoutes) UpdateNote(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var noteReq models.SOAPNote
+
+	noteID, ok := n.noteFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&noteReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for clinical note"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	noteReq.Trim()
+	if err := models.ValidateSOAPNoteReq(noteReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	if err := n.service.UpdateNoteDraft(noteID, &noteReq, middleware.UserIDFromContext(r.Context())); err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Draft note updated successfully!"})
+	log.Printf("Draft note %s updated by %s", noteID, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Sign a draft note, its content cannot be changed afterwards
+func (n *APIRoutes) SignNote(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	noteID, ok := n.noteFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := n.service.SignNote(noteID, middleware.UserIDFromContext(r.Context())); err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Clinical note signed successfully!"})
+	log.Printf("Clinical note %s signed by %s", noteID, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Amend a signed note with its full corrected content and a reason, earlier versions are kept
+func (n *APIRoutes) AmendNote(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var amendmentReq models.NoteAmendment
+
+	noteID, ok := n.noteFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&amendmentReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for amendment"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	amendmentReq.Trim()
+	amendmentReq.Reason = strings.TrimSpace(amendmentReq.Reason)
+	if err := models.ValidateNoteAmendmentReq(amendmentReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	version, err := n.service.AmendNote(noteID, &amendmentReq, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Amendment saved successfully!", Data: map[string]interface{}{"note_id": noteID, "version": version}})
+	log.Printf("Clinical note %s amended to version %d by %s", noteID, version, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return every version of a note, the original first
+func (n *APIRoutes) GetNoteVersions(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	noteID, ok := n.noteFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := n.service.GetNoteVersions(noteID)
+	if err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: map[string]interface{}{"versions_data": versions}})
+	log.Println("Clinical note versions populated successfully for ID- ", noteID)
+}
+
+// GET: Return one version of a note
+func (n *APIRoutes) GetNoteVersion(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	noteID, ok := n.noteFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil || version < 1 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid version number"})
+		log.Println("Invalid version number")
+		return
+	}
+
+	resp, err := n.service.GetNoteVersion(noteID, version)
+	if err != nil {
+		noteErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Printf("Version %d of clinical note %s populated successfully", version, noteID)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -12,7 +13,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
 	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
 	"golang.org/x/time/rate"
 )
 
@@ -22,6 +25,31 @@ var (
 	mu      sync.Mutex
 )
 
+// Restricts doctors to their own patients, receptionists and admins can access all patients
+func patientScope(r *http.Request) uuid.NullUUID {
+	if middleware.RoleFromContext(r.Context()) == models.RoleDoctor {
+		return uuid.NullUUID{UUID: middleware.UserIDFromContext(r.Context()), Valid: true}
+	}
+	return uuid.NullUUID{}
+}
+
+// Checks that patient with token ID is within the caller's scope
+func (p *APIRoutes) canAccessPatient(r *http.Request, tokenID string) (bool, error) {
+	scope := patientScope(r)
+	if !scope.Valid {
+		return true, nil
+	}
+
+	assignedTo, err := p.service.GetPatientAssignee(tokenID)
+	if err != nil {
+		if errors.Is(err, store.ErrPatientNotFound) {
+			return true, nil // let the caller report missing patient as before
+		}
+		return false, err
+	}
+	return assignedTo == scope.UUID, nil
+}
+
 // GET: Return list of patients based on pagination
 func (p *APIRoutes) GetAllPatients(w http.ResponseWriter, r *http.Request) {
 
@@ -42,7 +70,7 @@ func (p *APIRoutes) GetAllPatients(w http.ResponseWriter, r *http.Request) {
 		offset, _ := strconv.Atoi(query.Get("offset"))
 
 		// Get data from store
-		resp, err := p.service.GetAllPatients(int32(limit), int32(offset))
+		resp, err := p.service.GetAllPatients(int32(limit), int32(offset), patientScope(r))
 
 		if err != nil {
 			// send error response
@@ -63,7 +91,7 @@ func (p *APIRoutes) GetAllPatients(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// GET: Return patient details from token ID
+// GET: Return patient details from token ID for staff
 func (p *APIRoutes) GetPatientByTokenID(w http.ResponseWriter, r *http.Request) {
 
 	// panic recovery
@@ -89,9 +117,32 @@ func (p *APIRoutes) GetPatientByTokenID(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// doctors can only view their own patients
+		allowed, err := p.canAccessPatient(r, id)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+			panic(err)
+		}
+		if !allowed {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view patients of another doctor"})
+			log.Println("Doctor denied access to patient assigned to another doctor")
+			return
+		}
+
 		// Get data from store layer
 		resp, err := p.service.GetPatientByTokenID(id)
 		if err != nil {
+			if errors.Is(err, store.ErrPatientNotFound) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+				log.Println(err)
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
@@ -139,6 +190,15 @@ func (p *APIRoutes) GetAllPatientsByDocID(w http.ResponseWriter, r *http.Request
 			return
 		}
 
+		// doctors can only list their own patients
+		if scope := patientScope(r); scope.Valid && scope.UUID != doctorID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view patients of another doctor"})
+			log.Println("Doctor denied access to patients of another doctor")
+			return
+		}
+
 		query := r.URL.Query()
 
 		limit, _ := strconv.Atoi(query.Get("limit"))
@@ -293,6 +353,15 @@ func (p *APIRoutes) UpdatePatient(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// only doctors are allowed to write treatment
+		if patientReq.Treatment != "" && middleware.RoleFromContext(r.Context()) != models.RoleDoctor {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "Only doctors are allowed to update treatment"})
+			log.Println("Treatment update denied for non-doctor role")
+			return
+		}
+
 		// validate request body
 		if err := models.ValidatePatientReq(patientReq); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
@@ -302,8 +371,34 @@ func (p *APIRoutes) UpdatePatient(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// doctors can only update their own patients
+		allowed, err := p.canAccessPatient(r, id)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+			panic(err)
+		}
+		if !allowed {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to update patients of another doctor"})
+			log.Println("Doctor denied update of patient assigned to another doctor")
+			return
+		}
+
+		// drugs named in the treatment are checked for interactions and allergies
+		var warnings []models.MedicationWarning
+		overrideReason := overrideReasonFromBody(body)
+		if patientReq.Treatment != "" {
+			var ok bool
+			if warnings, ok = p.checkTreatmentSafety(w, id, patientReq.Treatment, overrideReason); !ok {
+				return
+			}
+		}
+
 		// Pass data to store to update patient
-		updatedPatient, err := p.service.UpdatePatient(id, &patientReq)
+		updatedPatient, err := p.service.UpdatePatient(id, &patientReq, patientScope(r), middleware.UserIDFromContext(r.Context()), warnings, strings.TrimSpace(overrideReason))
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Header().Set("Content-Type", "application/json")
@@ -315,9 +410,9 @@ func (p *APIRoutes) UpdatePatient(w http.ResponseWriter, r *http.Request) {
 			// data is updated successfully
 			w.WriteHeader(http.StatusOK)
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Patient data updated successfully!"})
-			log.Println("Patient data updated successfully!")
+			json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Patient data updated successfully!", Data: warningsData(warnings)})
 			log.Println("Patient data updated successfully!")
+			logTreatmentOverride(r, id, warnings)
 			// Get the updated result
 			// p.GetPatientByTokenID(w, r)
 		} else {
@@ -382,6 +477,15 @@ func (p *APIRoutes) UpdatePatientPartial(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// only doctors are allowed to write treatment
+		if patientReq.Treatment != "" && middleware.RoleFromContext(r.Context()) != models.RoleDoctor {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "Only doctors are allowed to update treatment"})
+			log.Println("Treatment update denied for non-doctor role")
+			return
+		}
+
 		// validate request body  for partial update
 		if err := models.ValidatePatientPatchReq(body); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
@@ -391,8 +495,34 @@ func (p *APIRoutes) UpdatePatientPartial(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// doctors can only update their own patients
+		allowed, err := p.canAccessPatient(r, id)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+			panic(err)
+		}
+		if !allowed {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to update patients of another doctor"})
+			log.Println("Doctor denied update of patient assigned to another doctor")
+			return
+		}
+
+		// drugs named in the treatment are checked for interactions and allergies
+		var warnings []models.MedicationWarning
+		overrideReason := overrideReasonFromBody(body)
+		if patientReq.Treatment != "" {
+			var ok bool
+			if warnings, ok = p.checkTreatmentSafety(w, id, patientReq.Treatment, overrideReason); !ok {
+				return
+			}
+		}
+
 		// Pass data to store to update patient
-		updatedPatient, err := p.service.UpdatePatient(id, &patientReq)
+		updatedPatient, err := p.service.UpdatePatient(id, &patientReq, patientScope(r), middleware.UserIDFromContext(r.Context()), warnings, strings.TrimSpace(overrideReason))
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Header().Set("Content-Type", "application/json")
@@ -404,8 +534,9 @@ func (p *APIRoutes) UpdatePatientPartial(w http.ResponseWriter, r *http.Request)
 			// data is updated successfully
 			w.WriteHeader(http.StatusOK)
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Patient data updated successfully!"})
+			json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Patient data updated successfully!", Data: warningsData(warnings)})
 			log.Println("Patient data updated successfully!")
+			logTreatmentOverride(r, id, warnings)
 			// Get the updated result
 			// p.GetPatientByTokenID(w, r)
 		} else {
@@ -454,6 +585,13 @@ func (p *APIRoutes) DeletePatient(w http.ResponseWriter, r *http.Request) {
 
 		// Pass data to service layer to delete patient
 		deletedPatient, err := p.service.DeletePatient(id)
+		if errors.Is(err, store.ErrPatientHasInvoices) || errors.Is(err, store.ErrPatientHasSignedNotes) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+			log.Println(err)
+			return
+		}
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"golang.org/x/time/rate"
+)
+
+// limiter that allows 5 verification attempts per token ID, refilling one every 3 minutes
+var patientAccessLimiter = middleware.NewRateLimiter(rate.Every(3*time.Minute), 5)
+
+type patientAccessRequest struct {
+	Contact string `json:"contact"`
+}
+
+// POST: Return reduced patient details once token ID and contact number match
+func (p *APIRoutes) VerifyPatientAccess(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	params := mux.Vars(r)
+	// Get token id
+	id := strings.TrimSpace(params["token_id"])
+
+	// 100000 (inclusive) → 999999 (inclusive)
+	if len(id) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	var accessReq patientAccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&accessReq); err != nil || strings.TrimSpace(accessReq.Contact) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - contact is a mandatory field"})
+		log.Println("Invalid Request body for patient access")
+		return
+	}
+
+	// guessing contact numbers for one token is throttled regardless of caller
+	if !patientAccessLimiter.Allow(id) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusTooManyRequests, Message: "Too many attempts for this token ID, try again later"})
+		log.Println("Too many patient access attempts for token ID- ", id)
+		return
+	}
+
+	verified, err := p.service.VerifyPatientContact(id, strings.TrimSpace(accessReq.Contact))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	// unknown token IDs and wrong contact numbers get the same answer
+	if !verified {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: "No patient found for provided token ID and contact"})
+		log.Println("Patient access verification failed for token ID- ", id)
+		return
+	}
+
+	resp, err := p.service.GetPublicPatientView(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	// Send response
+	var respData []interface{}
+	respData = append(respData, resp) // enclose data in an array
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: respData})
+	log.Println("Public patient data populated successfully for token ID- ", id)
+}
@@ -0,0 +1,347 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Printable prescription handed to pharmacists
+var prescriptionTemplate = template.Must(template.New("prescription").Funcs(template.FuncMap{"inc": func(i int) int { return i + 1 }}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Prescription {{.PrescriptionID}}</title>
+<style>
+body { font-family: Arial, sans-serif; margin: 2em; color: #222; }
+header { border-bottom: 2px solid #222; margin-bottom: 1em; }
+table { width: 100%; border-collapse: collapse; margin-top: 1em; }
+th, td { border: 1px solid #999; padding: 6px; text-align: left; vertical-align: top; }
+.void { color: #b00; font-size: 1.4em; font-weight: bold; }
+.signature { margin-top: 4em; text-align: right; }
+@media print { button { display: none; } }
+</style>
+</head>
+<body>
+<header>
+<h2>Dr. {{.DoctorName}}</h2>
+{{if .Specialization}}<p>{{.Specialization}}</p>{{end}}
+</header>
+{{if eq .Status "voided"}}<p class="void">VOID - {{.VoidReason}}</p>{{end}}
+<p><strong>Patient:</strong> {{.PatientName}} &nbsp; <strong>Age:</strong> {{.PatientAge}} &nbsp; <strong>Gender:</strong> {{.PatientGender}} &nbsp; <strong>Token ID:</strong> {{.TokenID}}</p>
+<p><strong>Date:</strong> {{.CreatedAt}} &nbsp; <strong>Prescription:</strong> {{.PrescriptionID}}</p>
+<table>
+<tr><th>#</th><th>Drug</th><th>Strength</th><th>Route</th><th>Dose</th><th>Frequency</th><th>Duration</th><th>Qty</th><th>Instructions</th></tr>
+{{range $i, $item := .Items}}<tr><td>{{inc $i}}</td><td>{{$item.Drug}}</td><td>{{$item.Strength}}</td><td>{{$item.Route}}</td><td>{{$item.Dose}}</td><td>{{$item.Frequency}}</td><td>{{$item.Duration}}</td><td>{{$item.Quantity}}</td><td>{{$item.Instructions}}</td></tr>
+{{end}}</table>
+{{if .Notes}}<p><strong>Notes:</strong> {{.Notes}}</p>{{end}}
+<p class="signature">Dr. {{.DoctorName}}</p>
+<button onclick="window.print()">Print</button>
+</body>
+</html>
+`))
+
+// Writes response for errors returned while reading or changing prescriptions
+func prescriptionErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrPrescriptionNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrPrescriptionVoided):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving prescription"})
+		panic(err)
+	}
+}
+
+// Parses prescription ID of the path
+func prescriptionIDFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+
+	prescriptionID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["prescription_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid prescription ID"})
+		log.Println("Invalid prescription ID")
+		return uuid.Nil, false
+	}
+	return prescriptionID, true
+}
+
+// Checks that a prescription is within the caller's scope, doctors see prescriptions of their patients and those they wrote
+func (rx *APIRoutes) canAccessPrescription(w http.ResponseWriter, r *http.Request, tokenID string, doctorID string) bool {
+
+	allowed, err := rx.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed && doctorID != middleware.UserIDFromContext(r.Context()).String() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view prescriptions of another doctor's patient"})
+		log.Println("Doctor denied access to prescription of patient assigned to another doctor")
+		return false
+	}
+	return true
+}
+
+// POST: Prescribe drugs for one of the doctor's patients
+func (rx *APIRoutes) CreatePrescription(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var prescriptionReq models.Prescription
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&prescriptionReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for prescription"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	prescriptionReq.Notes = strings.TrimSpace(prescriptionReq.Notes)
+	for i := range prescriptionReq.Items {
+		item := &prescriptionReq.Items[i]
+		item.Drug, item.Strength, item.Dose = strings.TrimSpace(item.Drug), strings.TrimSpace(item.Strength), strings.TrimSpace(item.Dose)
+		item.Frequency, item.Duration, item.Instructions = strings.TrimSpace(item.Frequency), strings.TrimSpace(item.Duration), strings.TrimSpace(item.Instructions)
+		item.Route = strings.ToLower(strings.TrimSpace(item.Route))
+	}
+
+	if err := models.ValidatePrescriptionReq(prescriptionReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	// warnings name the patient's allergies and medications, so check the patient is the doctor's first
+	allowed, err := rx.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		prescriptionErrorResponse(w, store.ErrPatientNotFound)
+		return
+	}
+
+	// interactions and allergies must be overridden with a reason
+	prescriptionReq.OverrideReason = strings.TrimSpace(prescriptionReq.OverrideReason)
+	drugs := make([]string, 0, len(prescriptionReq.Items))
+	for _, item := range prescriptionReq.Items {
+		drugs = append(drugs, item.Drug)
+	}
+	warnings, ok := rx.checkMedicationSafety(w, tokenID, drugs, prescriptionReq.OverrideReason)
+	if !ok {
+		return
+	}
+
+	prescriptionID, err := rx.service.CreatePrescription(tokenID, &prescriptionReq, middleware.UserIDFromContext(r.Context()), warnings)
+	if err != nil {
+		prescriptionErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Prescription saved successfully!", Data: map[string]interface{}{"prescription_id": prescriptionID, "warnings": warnings}})
+	log.Printf("Prescription %s written for patient %s by %s", prescriptionID, tokenID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return prescriptions of a patient, newest first
+func (rx *APIRoutes) GetPatientPrescriptions(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	// doctors can only view their own patients
+	allowed, err := rx.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view patients of another doctor"})
+		log.Println("Doctor denied access to prescriptions of patient assigned to another doctor")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	resp, err := rx.service.GetPrescriptions(tokenID, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Patient prescriptions populated successfully")
+}
+
+// GET: Return a single prescription
+func (rx *APIRoutes) GetPrescription(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	prescriptionID, ok := prescriptionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := rx.service.GetPrescription(prescriptionID.String())
+	if err != nil {
+		prescriptionErrorResponse(w, err)
+		return
+	}
+	if !rx.canAccessPrescription(w, r, resp.TokenID, resp.DoctorID) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Prescription data populated successfully")
+}
+
+// GET: Render a prescription as a printable page for pharmacists
+func (rx *APIRoutes) PrintPrescription(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	prescriptionID, ok := prescriptionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := rx.service.GetPrescription(prescriptionID.String())
+	if err != nil {
+		prescriptionErrorResponse(w, err)
+		return
+	}
+	if !rx.canAccessPrescription(w, r, resp.TokenID, resp.DoctorID) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := prescriptionTemplate.Execute(w, resp); err != nil {
+		panic(err)
+	}
+	log.Printf("Prescription %s printed by %s", resp.PrescriptionID, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Void a prescription written by the doctor, it stays on record marked void
+func (rx *APIRoutes) VoidPrescription(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var voidReq models.PrescriptionVoid
+
+	prescriptionID, ok := prescriptionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&voidReq); err != nil || strings.TrimSpace(voidReq.Reason) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - reason is a mandatory field"})
+		log.Println("Invalid Request body for voiding prescription")
+		return
+	}
+	defer r.Body.Close()
+
+	err := rx.service.VoidPrescription(prescriptionID.String(), strings.TrimSpace(voidReq.Reason), middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		prescriptionErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Prescription voided successfully!"})
+	log.Printf("Prescription %s voided by %s", prescriptionID, middleware.EmailFromContext(r.Context()))
+}
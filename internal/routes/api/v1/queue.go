@@ -0,0 +1,304 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Writes response for errors returned while changing the queue
+func queueErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrQueueEntryNotFound), errors.Is(err, store.ErrQueueEmpty):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrAlreadyQueued), errors.Is(err, store.ErrConsultationInProgress), errors.Is(err, store.ErrQueueTransition):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrDoctorNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while updating queue"})
+		panic(err)
+	}
+}
+
+// POST: Issue today's queue token of a doctor to a registered patient
+func (q *APIRoutes) IssueQueueToken(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var queueReq models.QueueEntry
+
+	if err := json.NewDecoder(r.Body).Decode(&queueReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for queue"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+	queueReq.TokenID = strings.TrimSpace(queueReq.TokenID)
+
+	if err := models.ValidateQueueReq(queueReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	resp, err := q.service.IssueQueueToken(&queueReq, clinicNow().Format(models.DateLayout), middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		queueErrorResponse(w, err)
+		return
+	}
+
+	// refresh waiting room displays
+	q.service.PublishQueueChange()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Queue token issued successfully!", Data: resp})
+	log.Printf("Queue token %s issued to patient %s by %s", resp.Token, queueReq.TokenID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return a day's queue filtered by doctor and status, doctors only see their own queue
+func (q *APIRoutes) GetQueue(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	date, err := scheduleDate(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	filter := store.QueueFilter{
+		Date:   date.Format(models.DateLayout),
+		Status: strings.TrimSpace(query.Get("status")),
+	}
+
+	if err := models.ValidateQueueStatus(filter.Status); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	if doctorID := strings.TrimSpace(query.Get("doctor_id")); doctorID != "" {
+		parsedDoctorID, err := uuid.Parse(doctorID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+			log.Println("Invalid doctor ID")
+			return
+		}
+		filter.DoctorID = uuid.NullUUID{UUID: parsedDoctorID, Valid: true}
+	}
+
+	// doctors can only list their own queue
+	if scope := patientScope(r); scope.Valid {
+		if filter.DoctorID.Valid && filter.DoctorID.UUID != scope.UUID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view queue of another doctor"})
+			log.Println("Doctor denied access to queue of another doctor")
+			return
+		}
+		filter.DoctorID = scope
+	}
+
+	resp, err := q.service.GetQueue(filter, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Queue data populated successfully")
+}
+
+// POST: Call the next waiting patient of the doctor's queue
+func (q *APIRoutes) CallNextPatient(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	doctorID := middleware.UserIDFromContext(r.Context())
+
+	resp, err := q.service.CallNextPatient(doctorID, clinicNow().Format(models.DateLayout))
+	if err != nil {
+		queueErrorResponse(w, err)
+		return
+	}
+
+	// refresh waiting room displays
+	q.service.PublishQueueChange()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Next patient called", Data: resp})
+	log.Printf("Queue token %s called by %s", resp.Token, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Move a queue entry to another status, doctors can only change their own queue
+func (q *APIRoutes) UpdateQueueStatus(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var statusReq models.QueueStatusUpdate
+
+	entryID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["queue_entry_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid queue entry ID"})
+		log.Println("Invalid queue entry ID")
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&statusReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - status is a mandatory field"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+	statusReq.Status = strings.TrimSpace(statusReq.Status)
+
+	if err = models.ValidateQueueStatus(statusReq.Status); err != nil || statusReq.Status == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "status must be one of waiting, called, in_consultation, done or no_show"})
+		log.Println("Invalid queue status")
+		return
+	}
+
+	resp, err := q.service.UpdateQueueStatus(entryID.String(), statusReq.Status, patientScope(r))
+	if err != nil {
+		queueErrorResponse(w, err)
+		return
+	}
+
+	// refresh waiting room displays
+	q.service.PublishQueueChange()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Queue status updated successfully!", Data: resp})
+	log.Printf("Queue token %s moved to %s by %s", resp.Token, resp.Status, middleware.EmailFromContext(r.Context()))
+}
+
+// PUT: Set letters shown in front of a doctor's queue numbers
+func (q *APIRoutes) SetQueuePrefix(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var prefixReq models.QueuePrefix
+
+	doctorID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["doctor_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+		log.Println("Invalid doctor ID")
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&prefixReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - prefix is a mandatory field"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+	prefixReq.Prefix = strings.ToUpper(strings.TrimSpace(prefixReq.Prefix))
+
+	if err = models.ValidateQueuePrefix(prefixReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	if err = q.service.SetQueuePrefix(doctorID, prefixReq.Prefix); err != nil {
+		if errors.Is(err, store.ErrDoctorNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Queue prefix saved successfully!"})
+	log.Printf("Queue prefix of doctor %s set to %s by %s", doctorID, prefixReq.Prefix, middleware.EmailFromContext(r.Context()))
+}
@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+)
+
+// Interval of keep-alive comments, also re-checks the board for a new day
+const queueStreamHeartbeat = 15 * time.Second
+
+type queueStreamEvent struct {
+	Date    string      `json:"date"`
+	Doctors interface{} `json:"doctors"`
+}
+
+// GET: Stream tokens now being served and waiting positions as Server-Sent Events.
+// Events carry only queue tokens and doctor names so they can be shown on a waiting room display.
+func (q *APIRoutes) QueueStream(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var doctorID uuid.NullUUID
+	if id := strings.TrimSpace(r.URL.Query().Get("doctor_id")); id != "" {
+		parsedDoctorID, err := uuid.Parse(id)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+			log.Println("Invalid doctor ID")
+			return
+		}
+		doctorID = uuid.NullUUID{UUID: parsedDoctorID, Valid: true}
+	}
+
+	controller := http.NewResponseController(w)
+	changes, unsubscribe := q.service.SubscribeQueueChanges()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	var lastSent []byte
+	var eventID int
+
+	// writes the board when it differs from what the client has
+	send := func(heartbeat bool) error {
+		// the server write timeout would otherwise end the stream
+		controller.SetWriteDeadline(time.Now().Add(2 * queueStreamHeartbeat))
+
+		date := clinicNow().Format(models.DateLayout)
+		board, err := q.service.GetQueueBoard(date, doctorID)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(queueStreamEvent{Date: date, Doctors: board})
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(payload, lastSent) {
+			eventID++
+			if _, err = fmt.Fprintf(w, "id: %d\nevent: queue\ndata: %s\n\n", eventID, payload); err != nil {
+				return err
+			}
+			lastSent = payload
+		} else if heartbeat {
+			if _, err = fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return err
+			}
+		}
+		return controller.Flush()
+	}
+
+	if err := send(false); err != nil {
+		log.Println("Queue stream closed: ", err)
+		return
+	}
+
+	ticker := time.NewTicker(queueStreamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changes:
+			if err := send(false); err != nil {
+				log.Println("Queue stream closed: ", err)
+				return
+			}
+		case <-ticker.C:
+			if err := send(true); err != nil {
+				log.Println("Queue stream closed: ", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/config"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/pdf"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Layout of the patient report, in points
+const (
+	reportMargin      = 40.0
+	reportHeaderSize  = 78.0
+	reportFooterSpace = 72.0 // kept free at the bottom of every page
+)
+
+var (
+	reportBrand = pdf.Color{R: 0.11, G: 0.37, B: 0.45}
+	reportMuted = pdf.Color{R: 0.42, G: 0.45, B: 0.48}
+	reportPanel = pdf.Color{R: 0.96, G: 0.97, B: 0.97}
+	reportWhite = pdf.Color{R: 1, G: 1, B: 1}
+)
+
+// Lays out report content top to bottom, starting new pages as they fill up
+type reportWriter struct {
+	doc    *pdf.Document
+	clinic string
+	token  string
+	y      float64
+}
+
+func (rw *reportWriter) newPage() {
+	rw.doc.AddPage()
+	rw.doc.Rect(0, 0, pdf.PageWidth, reportHeaderSize, reportBrand)
+	rw.doc.Text(reportMargin, 38, 20, true, reportWhite, rw.clinic)
+	rw.doc.Text(reportMargin, 58, 11, false, reportWhite, "Patient Medical Report")
+	rw.doc.TextRight(pdf.PageWidth-reportMargin, 38, 12, true, reportWhite, "Token ID: "+rw.token)
+	rw.y = reportHeaderSize + 28
+}
+
+// Starts a new page unless height fits above the footer
+func (rw *reportWriter) ensure(height float64) {
+	if rw.y+height > pdf.PageHeight-reportFooterSpace {
+		rw.newPage()
+	}
+}
+
+func (rw *reportWriter) heading(title string) {
+	rw.ensure(40)
+	rw.doc.Text(reportMargin, rw.y, 13, true, reportBrand, title)
+	rw.doc.Line(reportMargin, rw.y+6, pdf.PageWidth-reportMargin, rw.y+6, 0.8, reportBrand)
+	rw.y += 24
+}
+
+// Writes label and value pairs in two columns on shaded panels
+func (rw *reportWriter) fields(pairs [][2]string) {
+	columnWidth := (pdf.PageWidth - 2*reportMargin - 12) / 2
+	for i := 0; i < len(pairs); i += 2 {
+		row := pairs[i:min(i+2, len(pairs))]
+
+		// the taller value decides the height of the row
+		wrapped := make([][]string, len(row))
+		height := 0.0
+		for j, pair := range row {
+			wrapped[j] = pdf.Wrap(pair[1], 10, false, columnWidth-20)
+			height = max(height, 30+14*float64(len(wrapped[j])))
+		}
+
+		rw.ensure(height)
+		for j, pair := range row {
+			x := reportMargin + float64(j)*(columnWidth+12)
+			rw.doc.Rect(x, rw.y, columnWidth, height, reportPanel)
+			rw.doc.Text(x+10, rw.y+16, 9, true, reportBrand, pair[0])
+			for k, line := range wrapped[j] {
+				rw.doc.Text(x+10, rw.y+31+14*float64(k), 10, false, pdf.Black, line)
+			}
+		}
+		rw.y += height + 10
+	}
+	rw.y += 6
+}
+
+func (rw *reportWriter) paragraph(text string) {
+	for _, line := range pdf.Wrap(text, 10, false, pdf.PageWidth-2*reportMargin) {
+		rw.ensure(15)
+		rw.doc.Text(reportMargin, rw.y, 10, false, pdf.Black, line)
+		rw.y += 15
+	}
+	rw.y += 12
+}
+
+func (rw *reportWriter) subheading(title string) {
+	rw.ensure(34)
+	rw.doc.Text(reportMargin, rw.y, 10.5, true, pdf.Black, title)
+	rw.y += 16
+}
+
+func (rw *reportWriter) bullet(text string) {
+	lines := pdf.Wrap(text, 10, false, pdf.PageWidth-2*reportMargin-14)
+	for i, line := range lines {
+		rw.ensure(15)
+		if i == 0 {
+			rw.doc.Text(reportMargin+2, rw.y, 10, false, reportBrand, "•")
+		}
+		rw.doc.Text(reportMargin+14, rw.y, 10, false, pdf.Black, line)
+		rw.y += 15
+	}
+}
+
+// Formats a stored timestamp for the report, unknown formats are shown as stored
+func reportTime(timestamp string) string {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999", "2006-01-02T15:04:05.999999"} {
+		if parsed, err := time.Parse(layout, timestamp); err == nil {
+			return parsed.Format("02 Jan 2006, 03:04 PM")
+		}
+	}
+	return timestamp
+}
+
+// Joins the non-empty parts of a history entry
+func reportJoin(parts ...string) string {
+	kept := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if strings.TrimSpace(part) != "" {
+			kept = append(kept, strings.TrimSpace(part))
+		}
+	}
+	return strings.Join(kept, " - ")
+}
+
+// Code of the reported record keyed with the report secret, the same record version always gives the same code
+// and it cannot be computed for altered content without the secret
+func reportVerificationCode(patient store.PatientDetails) (string, error) {
+	fields := []string{patient.TokenID, patient.Fullname, patient.Gender, strconv.Itoa(patient.Age), patient.Contact,
+		patient.Symptoms, patient.Treatment, patient.AssignedTo, patient.CreatedAt, patient.UpdatedAt}
+	mac, err := auth.SignReport([]byte(strings.Join(fields, "\x1f")))
+	if err != nil {
+		return "", err
+	}
+	return formatVerificationCode(hex.EncodeToString(mac[:10])), nil
+}
+
+// Groups a code in blocks of four upper case characters, spaces and dashes in the input are ignored
+func formatVerificationCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	blocks := make([]string, 0, len(code)/4+1)
+	for len(code) > 4 {
+		blocks = append(blocks, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(blocks, code), "-")
+}
+
+// Renders patient details into a PDF report carrying the verification code
+func renderPatientReport(patient store.PatientDetails, code string, clinic string, generatedBy string, generatedAt string) []byte {
+
+	rw := &reportWriter{doc: pdf.New("Patient Medical Report " + patient.TokenID), clinic: clinic, token: patient.TokenID}
+	rw.newPage()
+
+	assignedTo := patient.AssignedTo
+	if assignedTo != "" && assignedTo != "NA" {
+		assignedTo = "Dr. " + assignedTo
+	}
+
+	rw.fields([][2]string{
+		{"Full Name", patient.Fullname},
+		{"Gender", patient.Gender},
+		{"Age", strconv.Itoa(patient.Age) + " years"},
+		{"Contact", patient.Contact},
+		{"Assigned Doctor", assignedTo},
+		{"Token ID", patient.TokenID},
+		{"Registered On", reportTime(patient.CreatedAt)},
+		{"Last Updated", reportTime(patient.UpdatedAt)},
+	})
+
+	rw.heading("Patient Symptoms")
+	rw.paragraph(patient.Symptoms)
+
+	rw.heading("Diagnosis and Treatment")
+	if strings.TrimSpace(patient.Treatment) == "" {
+		rw.paragraph("No diagnosis or treatment recorded yet.")
+	} else {
+		rw.paragraph(patient.Treatment)
+	}
+
+	rw.heading("Medical History")
+	history := patient.MedicalHistory
+	if history == nil || len(history.Allergies)+len(history.Conditions)+len(history.Surgeries)+len(history.FamilyHistory) == 0 {
+		rw.paragraph("No allergies, conditions, surgeries or family history recorded.")
+	} else {
+		if len(history.Allergies) > 0 {
+			rw.subheading("Allergies")
+			for _, allergy := range history.Allergies {
+				rw.bullet(reportJoin(allergy.Substance, allergy.Severity, allergy.Reaction, allergy.NotedOn))
+			}
+			rw.y += 8
+		}
+		if len(history.Conditions) > 0 {
+			rw.subheading("Conditions")
+			for _, condition := range history.Conditions {
+				rw.bullet(reportJoin(condition.Name, condition.Status, condition.DiagnosedOn, condition.Notes))
+			}
+			rw.y += 8
+		}
+		if len(history.Surgeries) > 0 {
+			rw.subheading("Surgeries")
+			for _, surgery := range history.Surgeries {
+				rw.bullet(reportJoin(surgery.Procedure, surgery.PerformedOn, surgery.Hospital, surgery.Notes))
+			}
+			rw.y += 8
+		}
+		if len(history.FamilyHistory) > 0 {
+			rw.subheading("Family History")
+			for _, family := range history.FamilyHistory {
+				onset := ""
+				if family.AgeAtOnset != nil {
+					onset = "onset at " + strconv.Itoa(*family.AgeAtOnset)
+				}
+				rw.bullet(reportJoin(family.Relation, family.Condition, onset, family.Notes))
+			}
+		}
+	}
+
+	// footers go on last, once the page count is known
+	for i := 0; i < rw.doc.PageCount(); i++ {
+		rw.doc.SetPage(i)
+		top := pdf.PageHeight - reportFooterSpace + 14
+		rw.doc.Line(reportMargin, top, pdf.PageWidth-reportMargin, top, 0.5, reportMuted)
+		rw.doc.Text(reportMargin, top+14, 8, true, reportMuted, "Verification code "+code)
+		rw.doc.TextRight(pdf.PageWidth-reportMargin, top+14, 8, false, reportMuted, fmt.Sprintf("Page %d of %d", i+1, rw.doc.PageCount()))
+		rw.doc.Text(reportMargin, top+26, 8, false, reportMuted, "Generated by "+generatedBy+" on "+generatedAt+" from the record last updated "+reportTime(patient.UpdatedAt)+".")
+		rw.doc.Text(reportMargin, top+38, 8, false, reportMuted, "Staff of "+clinic+" can look up this code to confirm the copy was issued by the clinic and covers the record version above.")
+	}
+
+	var out bytes.Buffer
+	rw.doc.WriteTo(&out)
+	return out.Bytes()
+}
+
+// GET: Return report of a patient as a PDF, the same on every client
+func (p *APIRoutes) GetPatientReportPDF(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	if !limiter.Allow() {
+		http.Error(w, "Too Many Requests - Limit: 1request/second", http.StatusTooManyRequests)
+		return
+	}
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return
+	}
+
+	// doctors can only view their own patients
+	allowed, err := p.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view patients of another doctor"})
+		log.Println("Doctor denied access to report of patient assigned to another doctor")
+		return
+	}
+
+	resp, err := p.service.GetPatientByTokenID(tokenID)
+	if err != nil {
+		if errors.Is(err, store.ErrPatientNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	patient := resp.(store.PatientDetails)
+
+	clinic := "MediGo"
+	if clinicConfig, err := config.ClinicConfig(); err == nil && clinicConfig.Name != "" {
+		clinic = clinicConfig.Name
+	}
+
+	// codes are recorded before the report is handed out so every printed code can be looked up
+	generatedBy := middleware.EmailFromContext(r.Context())
+	code, err := reportVerificationCode(patient)
+	if err == nil {
+		err = p.service.RecordReportVerification(code, tokenID, patient.UpdatedAt, generatedBy)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while generating report"})
+		panic(err)
+	}
+	report := renderPatientReport(patient, code, clinic, generatedBy, clinicNow().Format("02 Jan 2006, 03:04 PM"))
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.Itoa(len(report)))
+	w.Header().Set("Content-Disposition", `inline; filename="patient-report-`+tokenID+`.pdf"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(report)
+	log.Printf("Report generated for patient %s by %s", tokenID, generatedBy)
+}
+
+// GET: Look up the report a verification code was issued for and the record version it covers
+func (p *APIRoutes) VerifyReport(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	code := formatVerificationCode(strings.TrimSpace(mux.Vars(r)["code"]))
+	if _, err := hex.DecodeString(strings.ReplaceAll(code, "-", "")); err != nil || len(code) != 24 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid verification code"})
+		log.Println("Invalid verification code")
+		return
+	}
+
+	verification, err := p.service.GetReportVerification(code)
+	if err != nil {
+		if errors.Is(err, store.ErrReportNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	message := "Report issued by the clinic, the patient record has not changed since"
+	if !verification.Current {
+		message = "Report issued by the clinic, the patient record has been updated since"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: message, Data: verification})
+	log.Printf("Report code %s verified by %s", code, middleware.EmailFromContext(r.Context()))
+}
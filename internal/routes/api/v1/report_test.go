@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+func TestReportVerificationCode(t *testing.T) {
+
+	patient := store.PatientDetails{TokenID: "123456", Fullname: "Asha Rao", Gender: "female", Age: 34, Contact: "9876543210",
+		Symptoms: "fever", Treatment: "paracetamol", AssignedTo: "Mehta", CreatedAt: "2025-07-20T09:00:00Z", UpdatedAt: "2025-07-20T10:00:00Z"}
+
+	code := func(secret string, patient store.PatientDetails) string {
+		t.Helper()
+		if err := auth.SetReportSecret(secret); err != nil {
+			t.Fatal(err)
+		}
+		code, err := reportVerificationCode(patient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	secret := strings.Repeat("s", 32)
+	original := code(secret, patient)
+	if len(original) != 24 || strings.Count(original, "-") != 4 {
+		t.Errorf("code %q is not five blocks of four", original)
+	}
+	if again := code(secret, patient); again != original {
+		t.Errorf("same record gave %q and %q", original, again)
+	}
+
+	altered := patient
+	altered.Treatment = "amoxicillin"
+	if code(secret, altered) == original {
+		t.Error("altered treatment kept the code")
+	}
+	if code(strings.Repeat("x", 32), patient) == original {
+		t.Error("code does not depend on the report secret")
+	}
+}
+
+func TestFormatVerificationCode(t *testing.T) {
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"0a1b2c3d4e5f6a7b8c9d", "0A1B-2C3D-4E5F-6A7B-8C9D"},
+		{"0a1b-2c3d 4e5f-6a7b-8c9d", "0A1B-2C3D-4E5F-6A7B-8C9D"},
+		{"0A1B2", "0A1B-2"},
+	}
+
+	for _, test := range tests {
+		if got := formatVerificationCode(test.code); got != test.want {
+			t.Errorf("formatVerificationCode(%q) = %q, want %q", test.code, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,722 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/config"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+type cancelAppointmentRequest struct {
+	Reason string `json:"reason"`
+}
+
+type rescheduleAppointmentRequest struct {
+	StartAt string `json:"start_at"`
+}
+
+// Returns current clinic wall clock time, schedules are stored without a time zone
+func clinicNow() time.Time {
+	location := time.UTC
+	clinicConfig, err := config.ClinicConfig()
+	if err == nil {
+		if loaded, err := time.LoadLocation(clinicConfig.Timezone); err == nil {
+			location = loaded
+		} else {
+			log.Printf("Invalid CLINIC_TIMEZONE %q, using UTC: %v", clinicConfig.Timezone, err)
+		}
+	}
+	now := time.Now().In(location)
+	return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, time.UTC)
+}
+
+// Returns date from the query, today in the clinic when absent
+func scheduleDate(r *http.Request) (time.Time, error) {
+	date := strings.TrimSpace(r.URL.Query().Get("date"))
+	if date == "" {
+		now := clinicNow()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	parsed, err := time.Parse(models.DateLayout, date)
+	if err != nil {
+		return time.Time{}, errors.New("date must be in YYYY-MM-DD format")
+	}
+	return parsed, nil
+}
+
+// Parses doctor ID of the path, doctors can only work with their own schedule
+func scheduleDoctorID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+
+	doctorID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["doctor_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+		log.Println("Invalid doctor ID")
+		return uuid.Nil, false
+	}
+
+	if scope := patientScope(r); scope.Valid && scope.UUID != doctorID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access schedule of another doctor"})
+		log.Println("Doctor denied access to schedule of another doctor")
+		return uuid.Nil, false
+	}
+
+	return doctorID, true
+}
+
+// Writes response for errors returned while changing schedules and appointments
+func scheduleErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound), errors.Is(err, store.ErrAppointmentNotFound),
+		errors.Is(err, store.ErrLeaveNotFound), errors.Is(err, store.ErrHolidayNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrSlotTaken), errors.Is(err, store.ErrSlotUnavailable), errors.Is(err, store.ErrAppointmentNotBooked):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusConflict, Message: err.Error()})
+		log.Println(err)
+	case errors.Is(err, store.ErrDoctorNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving schedule"})
+		panic(err)
+	}
+}
+
+// Parses a requested appointment start, which must not be in the past
+func appointmentStart(w http.ResponseWriter, startAt string) (time.Time, bool) {
+
+	start, err := time.Parse(models.DateTimeLayout, strings.TrimSpace(startAt))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "start_at must be in YYYY-MM-DDTHH:MM format"})
+		log.Println(err)
+		return time.Time{}, false
+	}
+	if start.Before(clinicNow()) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "start_at must not be in the past"})
+		log.Println("Appointment requested in the past")
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// GET: Return weekly working hours of a doctor
+func (s *APIRoutes) GetWorkingHours(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	doctorID, ok := scheduleDoctorID(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := s.service.GetWorkingHours(doctorID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Working hours populated successfully")
+}
+
+// PUT: Replace weekly working hours of a doctor
+func (s *APIRoutes) SetWorkingHours(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var hoursReq []models.WorkingHours
+
+	doctorID, ok := scheduleDoctorID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&hoursReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - expected a list of working hours"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := models.ValidateWorkingHoursReq(hoursReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	if err := s.service.SetWorkingHours(doctorID, hoursReq); err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Working hours saved successfully!"})
+	log.Printf("Working hours of doctor %s updated by %s", doctorID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return current and upcoming leave of a doctor
+func (s *APIRoutes) GetDoctorLeave(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	doctorID, ok := scheduleDoctorID(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := s.service.GetLeaves(doctorID, clinicNow().Format(models.DateLayout))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Doctor leave populated successfully")
+}
+
+// POST: Record leave of a doctor, existing appointments are kept for rescheduling
+func (s *APIRoutes) AddDoctorLeave(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var leaveReq models.Leave
+
+	doctorID, ok := scheduleDoctorID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&leaveReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for leave"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+	leaveReq.Reason = strings.TrimSpace(leaveReq.Reason)
+
+	if err := models.ValidateLeaveReq(leaveReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	leaveID, err := s.service.AddLeave(doctorID, &leaveReq, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Leave recorded successfully!", Data: map[string]uuid.UUID{"leave_id": leaveID}})
+	log.Printf("Leave of doctor %s recorded by %s", doctorID, middleware.EmailFromContext(r.Context()))
+}
+
+// DELETE: Remove leave of a doctor
+func (s *APIRoutes) DeleteDoctorLeave(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	doctorID, ok := scheduleDoctorID(w, r)
+	if !ok {
+		return
+	}
+
+	leaveID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["leave_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid leave ID"})
+		log.Println("Invalid leave ID")
+		return
+	}
+
+	if err = s.service.DeleteLeave(doctorID, leaveID.String()); err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Leave removed successfully!"})
+	log.Printf("Leave %s removed by %s", leaveID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return slots of a doctor on a date with their availability
+func (s *APIRoutes) GetDoctorSlots(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	doctorID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["doctor_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+		log.Println("Invalid doctor ID")
+		return
+	}
+
+	date, err := scheduleDate(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	agenda, err := s.service.GetDoctorSlots(doctorID, date, clinicNow())
+	if err != nil {
+		if errors.Is(err, store.ErrDoctorNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	// slots only, patients of booked appointments are part of the agenda
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: map[string]interface{}{
+		"doctor_id": agenda.DoctorID, "date": agenda.Date, "status": agenda.Status, "note": agenda.Note, "slots": agenda.Slots}})
+	log.Println("Doctor slots populated successfully")
+}
+
+// GET: Return the daily agenda of a doctor with appointments and free slots
+func (s *APIRoutes) GetDoctorAgenda(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	doctorID, ok := scheduleDoctorID(w, r)
+	if !ok {
+		return
+	}
+
+	date, err := scheduleDate(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	resp, err := s.service.GetDoctorSlots(doctorID, date, clinicNow())
+	if err != nil {
+		if errors.Is(err, store.ErrDoctorNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Doctor agenda populated successfully")
+}
+
+// GET: Return upcoming clinic holidays
+func (s *APIRoutes) GetHolidays(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	resp, err := s.service.GetHolidays(clinicNow().Format(models.DateLayout))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Holidays populated successfully")
+}
+
+// POST: Add a clinic holiday, no slots are offered on it
+func (s *APIRoutes) AddHoliday(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var holidayReq models.Holiday
+
+	if err := json.NewDecoder(r.Body).Decode(&holidayReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for holiday"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+	holidayReq.Name = strings.TrimSpace(holidayReq.Name)
+
+	if err := models.ValidateHolidayReq(holidayReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	if err := s.service.AddHoliday(&holidayReq); err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Holiday saved successfully!"})
+	log.Printf("Holiday on %s saved by %s", holidayReq.Date, middleware.EmailFromContext(r.Context()))
+}
+
+// DELETE: Remove a clinic holiday
+func (s *APIRoutes) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	date := strings.TrimSpace(mux.Vars(r)["date"])
+	if _, err := time.Parse(models.DateLayout, date); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "date must be in YYYY-MM-DD format"})
+		log.Println(err)
+		return
+	}
+
+	if err := s.service.DeleteHoliday(date); err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Holiday removed successfully!"})
+	log.Printf("Holiday on %s removed by %s", date, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Book a free slot of a doctor for a registered patient
+func (s *APIRoutes) BookAppointment(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var appointmentReq models.Appointment
+
+	if err := json.NewDecoder(r.Body).Decode(&appointmentReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for appointment"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+	appointmentReq.TokenID = strings.TrimSpace(appointmentReq.TokenID)
+	appointmentReq.Reason = strings.TrimSpace(appointmentReq.Reason)
+
+	if err := models.ValidateAppointmentReq(appointmentReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	start, ok := appointmentStart(w, appointmentReq.StartAt)
+	if !ok {
+		return
+	}
+
+	appointmentID, err := s.service.BookAppointment(&appointmentReq, start, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Appointment booked successfully!", Data: map[string]uuid.UUID{"appointment_id": appointmentID}})
+	log.Printf("Appointment %s booked by %s", appointmentID, middleware.EmailFromContext(r.Context()))
+}
+
+// GET: Return appointments filtered by doctor, patient, date and status, doctors only see their own
+func (s *APIRoutes) GetAllAppointments(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	filter := store.AppointmentFilter{
+		TokenID: strings.TrimSpace(query.Get("token_id")),
+		Date:    strings.TrimSpace(query.Get("date")),
+		Status:  strings.TrimSpace(query.Get("status")),
+	}
+
+	if filter.Date != "" {
+		if _, err := time.Parse(models.DateLayout, filter.Date); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "date must be in YYYY-MM-DD format"})
+			log.Println(err)
+			return
+		}
+	}
+
+	switch filter.Status {
+	case "", models.AppointmentStatusBooked, models.AppointmentStatusCancelled, models.AppointmentStatusCompleted, models.AppointmentStatusNoShow:
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "status must be one of booked, cancelled, completed or no_show"})
+		log.Println("Invalid appointment status filter")
+		return
+	}
+
+	if doctorID := strings.TrimSpace(query.Get("doctor_id")); doctorID != "" {
+		parsedDoctorID, err := uuid.Parse(doctorID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid doctor ID"})
+			log.Println("Invalid doctor ID")
+			return
+		}
+		filter.DoctorID = uuid.NullUUID{UUID: parsedDoctorID, Valid: true}
+	}
+
+	// doctors can only list their own appointments
+	if scope := patientScope(r); scope.Valid {
+		if filter.DoctorID.Valid && filter.DoctorID.UUID != scope.UUID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to view appointments of another doctor"})
+			log.Println("Doctor denied access to appointments of another doctor")
+			return
+		}
+		filter.DoctorID = scope
+	}
+
+	resp, err := s.service.GetAppointments(filter, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("All appointments data populated successfully")
+}
+
+// POST: Move a booked appointment to another free slot of the same doctor
+func (s *APIRoutes) RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var rescheduleReq rescheduleAppointmentRequest
+
+	appointmentID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["appointment_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid appointment ID"})
+		log.Println("Invalid appointment ID")
+		return
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&rescheduleReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - start_at is a mandatory field"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	start, ok := appointmentStart(w, rescheduleReq.StartAt)
+	if !ok {
+		return
+	}
+
+	if err = s.service.RescheduleAppointment(appointmentID.String(), start); err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Appointment rescheduled successfully!"})
+	log.Printf("Appointment %s rescheduled by %s", appointmentID, middleware.EmailFromContext(r.Context()))
+}
+
+// POST: Cancel a booked appointment, freeing its slot
+func (s *APIRoutes) CancelAppointment(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var cancelReq cancelAppointmentRequest
+
+	appointmentID, err := uuid.Parse(strings.TrimSpace(mux.Vars(r)["appointment_id"]))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid appointment ID"})
+		log.Println("Invalid appointment ID")
+		return
+	}
+
+	// reason is optional
+	_ = json.NewDecoder(r.Body).Decode(&cancelReq)
+	defer r.Body.Close()
+
+	if err = s.service.CancelAppointment(appointmentID.String(), strings.TrimSpace(cancelReq.Reason)); err != nil {
+		scheduleErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Appointment cancelled successfully!"})
+	log.Printf("Appointment %s cancelled by %s", appointmentID, middleware.EmailFromContext(r.Context()))
+}
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+)
+
+type unlockLoginRequest struct {
+	Email    string `json:"email"`
+	ClientIP string `json:"client_ip"`
+}
+
+// GET: Return login attempts filtered by email, client IP and outcome
+func (s *APIRoutes) GetLoginAttempts(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	failedOnly, _ := strconv.ParseBool(query.Get("failed_only"))
+
+	resp, err := s.service.GetLoginAttempts(strings.TrimSpace(query.Get("email")), strings.TrimSpace(query.Get("client_ip")), failedOnly, int32(limit), int32(offset))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Login attempts populated successfully")
+}
+
+// GET: Return emails and client IPs currently delayed or locked
+func (s *APIRoutes) GetLoginLockouts(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	resp, err := s.service.GetLoginLockouts()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Login lockouts populated successfully")
+}
+
+// POST: Clear failed login counters of an email and/or client IP
+func (s *APIRoutes) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var unlockReq unlockLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&unlockReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for unlock"})
+		log.Println("Invalid Request body for unlock")
+		return
+	}
+
+	unlockReq.Email = strings.TrimSpace(unlockReq.Email)
+	unlockReq.ClientIP = strings.TrimSpace(unlockReq.ClientIP)
+	if unlockReq.Email == "" && unlockReq.ClientIP == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - either of email, client_ip is mandatory"})
+		log.Println("Invalid Request body for unlock, email and client_ip are missing")
+		return
+	}
+
+	unlocked, err := s.service.UnlockLogin(unlockReq.Email, unlockReq.ClientIP)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while unlocking login"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Login unlocked successfully!", Data: map[string]int64{"cleared": unlocked}})
+	log.Printf("Login unlocked for email '%s' / ip '%s' by %s", unlockReq.Email, unlockReq.ClientIP, middleware.EmailFromContext(r.Context()))
+}
@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Stores the first refresh token of a new family and signs an access token for it
+func (l *APIRoutes) startTokenFamily(owner store.RefreshTokenOwner) (map[string]interface{}, error) {
+
+	_, refreshTTL := auth.TokenLifetime()
+
+	refreshToken, refreshTokenHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = l.service.SaveRefreshToken(refreshTokenHash, owner, time.Now().Add(refreshTTL)); err != nil {
+		return nil, err
+	}
+
+	return tokenResponse(owner, refreshToken)
+}
+
+// Signs an access token and bundles it with the refresh token
+func tokenResponse(owner store.RefreshTokenOwner, refreshToken string) (map[string]interface{}, error) {
+
+	accessTTL, _ := auth.TokenLifetime()
+
+	accessToken, err := auth.GenerateToken(owner.Email, owner.UserID, owner.Role, owner.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(accessTTL.Seconds()),
+	}, nil
+}
+
+// POST: Exchange refresh token for a new access and refresh token
+func (l *APIRoutes) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var refreshReq refreshTokenRequest
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	if err := json.NewDecoder(r.Body).Decode(&refreshReq); err != nil || strings.TrimSpace(refreshReq.RefreshToken) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body - refresh_token is a mandatory field"})
+		log.Println("Invalid Request body for token refresh")
+		return
+	}
+
+	_, refreshTTL := auth.TokenLifetime()
+
+	newRefreshToken, newRefreshTokenHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Failed to generate token for authentication"})
+		panic(err)
+	}
+
+	owner, err := l.service.RotateRefreshToken(auth.HashToken(strings.TrimSpace(refreshReq.RefreshToken)), newRefreshTokenHash, time.Now().Add(refreshTTL))
+	if err != nil {
+		if errors.Is(err, store.ErrRefreshTokenInvalid) || errors.Is(err, store.ErrRefreshTokenReused) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusUnauthorized, Message: err.Error()})
+			log.Println(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while refreshing token"})
+		panic(err)
+	}
+
+	tokens, err := tokenResponse(owner, newRefreshToken)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Failed to generate token for authentication"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Authentication token refreshed successfully", Data: tokens})
+	log.Println("Authentication token refreshed successfully")
+}
+
+// POST: Revoke current access token and the session's refresh tokens
+func (l *APIRoutes) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusUnauthorized, Message: "Invalid token"})
+		log.Println("Logout called without token claims")
+		return
+	}
+
+	// refresh token in body is optional, the token family of the access token is revoked either way
+	var logoutReq refreshTokenRequest
+	_ = json.NewDecoder(r.Body).Decode(&logoutReq)
+
+	if refreshToken := strings.TrimSpace(logoutReq.RefreshToken); refreshToken != "" {
+		err := l.service.RevokeRefreshToken(auth.HashToken(refreshToken), claims.UserID)
+		if err != nil && !errors.Is(err, store.ErrRefreshTokenInvalid) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during logout"})
+			panic(err)
+		}
+	}
+
+	err := l.service.RevokeTokenFamily(claims.FamilyID)
+	if err == nil {
+		err = l.service.RevokeAccessToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured during logout"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Logged out successfully"})
+	log.Println("Logged out successfully")
+}
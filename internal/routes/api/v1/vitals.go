@@ -0,0 +1,225 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/harshitrajsinha/medi-go/internal/middleware"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/harshitrajsinha/medi-go/internal/store"
+)
+
+// Validates token ID of the path and that the patient is within the caller's scope
+func (v *APIRoutes) vitalsTokenID(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	tokenID := strings.TrimSpace(mux.Vars(r)["token_id"])
+	if len(tokenID) != 6 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid token ID"})
+		log.Println("Invalid token ID")
+		return "", false
+	}
+
+	// doctors can only access their own patients
+	allowed, err := v.canAccessPatient(r, tokenID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusForbidden, Message: "You are not allowed to access patients of another doctor"})
+		log.Println("Doctor denied access to vitals of patient assigned to another doctor")
+		return "", false
+	}
+	return tokenID, true
+}
+
+// Writes response for errors returned while reading or recording vitals
+func vitalsErrorResponse(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, store.ErrPatientNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusNotFound, Message: err.Error()})
+		log.Println(err)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving vitals"})
+		panic(err)
+	}
+}
+
+// POST: Record vitals of a patient, values outside normal ranges are flagged in the response
+func (v *APIRoutes) RecordVitals(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var vitalsReq models.Vitals
+
+	tokenID, ok := v.vitalsTokenID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&vitalsReq); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for vitals"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	reading, err := models.ParseVitalsReq(vitalsReq)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	vitals, err := v.service.RecordVitals(tokenID, &reading, middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		vitalsErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusCreated, Message: "Vitals recorded successfully!", Data: vitals})
+	log.Printf("Vitals recorded for patient %s by %s with %d flags", tokenID, middleware.EmailFromContext(r.Context()), len(vitals.Flags))
+}
+
+// GET: Return vitals of a patient newest first, optionally for one visit
+func (v *APIRoutes) GetPatientVitals(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	tokenID, ok := v.vitalsTokenID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	encounterID := strings.TrimSpace(query.Get("encounter_id"))
+	if encounterID != "" {
+		if _, err := uuid.Parse(encounterID); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid visit ID"})
+			log.Println("Invalid visit ID")
+			return
+		}
+	}
+
+	resp, err := v.service.GetVitals(tokenID, encounterID, int32(limit), int32(offset))
+	if err != nil {
+		vitalsErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: resp})
+	log.Println("Vitals data populated successfully for token ID- ", tokenID)
+}
+
+// GET: Return normal ranges used to flag vitals
+func (v *APIRoutes) GetVitalRanges(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	ranges, err := v.service.GetVitalRanges()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while reading data"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Data: ranges})
+	log.Println("Vital ranges populated successfully")
+}
+
+// PUT: Replace normal ranges used to flag vitals
+func (v *APIRoutes) SetVitalRanges(w http.ResponseWriter, r *http.Request) {
+
+	// panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error occured: ", r)
+			debug.PrintStack()
+		}
+	}()
+
+	var ranges []models.VitalRange
+
+	if err := json.NewDecoder(r.Body).Decode(&ranges); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: "Invalid Request body for vital ranges"})
+		log.Println(err)
+		return
+	}
+	defer r.Body.Close()
+
+	for i := range ranges {
+		ranges[i].Vital, ranges[i].Gender = strings.ToLower(strings.TrimSpace(ranges[i].Vital)), strings.ToLower(strings.TrimSpace(ranges[i].Gender))
+	}
+
+	if err := models.ValidateVitalRangesReq(ranges); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusBadRequest, Message: err.Error()})
+		log.Println(err)
+		return
+	}
+
+	if err := v.service.SetVitalRanges(ranges); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Code: http.StatusInternalServerError, Message: "Error occured while saving vital ranges"})
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Code: http.StatusOK, Message: "Vital ranges updated successfully!"})
+	log.Printf("Vital ranges replaced by %s", middleware.EmailFromContext(r.Context()))
+}
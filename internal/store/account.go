@@ -0,0 +1,446 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrAdminExists        = errors.New("an admin account already exists")
+	ErrInvalidAccountType = errors.New("account type must be one of following - ['doctors', 'staff']")
+)
+
+type accountQueryResponse struct {
+	AccountID      string `json:"account_id"`
+	Fullname       string `json:"fullname"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	Specialization string `json:"specialization,omitempty"`
+	IsActive       bool   `json:"is_active"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+// Returns table and primary key column backing an account type
+func accountTable(accountType string) (string, string, error) {
+	switch accountType {
+	case models.AccountTypeDoctor:
+		return "doctor", "doctor_id", nil
+	case models.AccountTypeStaff:
+		return "staff", "staff_id", nil
+	}
+	return "", "", ErrInvalidAccountType
+}
+
+// Checks that no doctor or staff account other than excludeID uses the email.
+// Takes a transaction scoped lock on the email so concurrent requests cannot race.
+func isEmailTaken(ctx context.Context, tx *sql.Tx, email string, excludeID string) (bool, error) {
+
+	var taken bool
+
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext(lower($1)))", email)
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM doctor WHERE lower(email)=lower($1) AND doctor_id::text<>$2 UNION ALL SELECT 1 FROM staff WHERE lower(email)=lower($1) AND staff_id::text<>$2)", email, excludeID).Scan(&taken)
+	return taken, err
+}
+
+// Maps unique constraint violations to ErrEmailTaken
+func accountError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+// Queries INSERT to create new doctor or staff account
+func (rec *Store) CreateAccount(accountType string, accountReq *models.Account, passwordHash string) (uuid.UUID, error) {
+
+	var accountID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := accountTable(accountType)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	taken, err := isEmailTaken(ctx, tx, accountReq.Email, "")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if taken {
+		err = ErrEmailTaken
+		return uuid.Nil, err
+	}
+
+	if table == "doctor" {
+		err = tx.QueryRowContext(ctx, "INSERT INTO doctor (fullname, email, specialization, password_hash) VALUES ($1, $2, $3, $4) RETURNING doctor_id",
+			accountReq.Fullname, accountReq.Email, accountReq.Specialization, passwordHash).Scan(&accountID)
+	} else {
+		err = tx.QueryRowContext(ctx, fmt.Sprintf("INSERT INTO %s (fullname, email, role, password_hash) VALUES ($1, $2, $3, $4) RETURNING %s", table, idColumn),
+			accountReq.Fullname, accountReq.Email, accountReq.Role, passwordHash).Scan(&accountID)
+	}
+	if err != nil {
+		err = accountError(err)
+		return uuid.Nil, err
+	}
+
+	return accountID, nil
+}
+
+// Queries INSERT to create the very first admin, only if no admin exists yet
+func (rec *Store) CreateFirstAdmin(accountReq *models.Account, passwordHash string) (uuid.UUID, error) {
+
+	var accountID uuid.UUID
+	var adminExists bool
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	// serialize concurrent bootstrap attempts
+	_, err = tx.ExecContext(ctx, "LOCK TABLE staff IN SHARE ROW EXCLUSIVE MODE")
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM staff WHERE role='admin')").Scan(&adminExists)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if adminExists {
+		err = ErrAdminExists
+		return uuid.Nil, err
+	}
+
+	taken, err := isEmailTaken(ctx, tx, accountReq.Email, "")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if taken {
+		err = ErrEmailTaken
+		return uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO staff (fullname, email, role, password_hash) VALUES ($1, $2, 'admin', $3) RETURNING staff_id",
+		accountReq.Fullname, accountReq.Email, passwordHash).Scan(&accountID)
+	if err != nil {
+		err = accountError(err)
+		return uuid.Nil, err
+	}
+
+	return accountID, nil
+}
+
+// Queries list of doctor or staff accounts
+func (rec *Store) GetAllAccounts(accountType string, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := accountTable(accountType)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	specialization := "''"
+	if table == "doctor" {
+		specialization = "COALESCE(specialization, '')"
+	}
+
+	rows, err := rec.db.QueryContext(ctx, fmt.Sprintf("SELECT %s, fullname, email, role, %s, is_active, created_at, updated_at, count(*) over() as total_records FROM %s ORDER BY created_at LIMIT $1 OFFSET $2", idColumn, specialization, table), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allAccountData := make([]accountQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		var queryData accountQueryResponse
+		err = rows.Scan(&queryData.AccountID, &queryData.Fullname, &queryData.Email, &queryData.Role, &queryData.Specialization, &queryData.IsActive, &queryData.CreatedAt, &queryData.UpdatedAt, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allAccountData = append(allAccountData, queryData)
+	}
+
+	responseData[0] = map[string][]accountQueryResponse{"accounts_data": allAccountData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries UPDATE to update existing doctor or staff account.
+// Changing email, password or role ends every session of the account.
+func (rec *Store) UpdateAccount(accountType string, accountID string, accountReq *models.Account, passwordHash string) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := accountTable(accountType)
+	if err != nil {
+		return -1, err
+	}
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	var setClauses []string
+	var args []interface{}
+
+	if accountReq.Fullname != "" {
+		args = append(args, accountReq.Fullname)
+		setClauses = append(setClauses, fmt.Sprintf("fullname=$%d", len(args)))
+	}
+	if accountReq.Email != "" {
+		var taken bool
+		taken, err = isEmailTaken(ctx, tx, accountReq.Email, accountID)
+		if err != nil {
+			return -1, err
+		}
+		if taken {
+			err = ErrEmailTaken
+			return -1, err
+		}
+		args = append(args, accountReq.Email)
+		setClauses = append(setClauses, fmt.Sprintf("email=$%d", len(args)))
+	}
+	if passwordHash != "" {
+		args = append(args, passwordHash)
+		setClauses = append(setClauses, fmt.Sprintf("password_hash=$%d", len(args)))
+	}
+	if accountReq.Role != "" && table == "staff" {
+		args = append(args, accountReq.Role)
+		setClauses = append(setClauses, fmt.Sprintf("role=$%d", len(args)))
+	}
+	if accountReq.Specialization != "" && table == "doctor" {
+		args = append(args, accountReq.Specialization)
+		setClauses = append(setClauses, fmt.Sprintf("specialization=$%d", len(args)))
+	}
+
+	if len(setClauses) == 0 {
+		return 0, nil
+	}
+
+	args = append(args, accountID)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s=$%d", table, strings.Join(setClauses, ", "), idColumn, len(args))
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		err = accountError(err)
+		return -1, err
+	}
+
+	rowAffected, err := result.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+
+	// sessions carry the old email and role, and may belong to whoever knew the old password.
+	// Access tokens carry their family, so revoking the families ends them too while new logins still work.
+	if rowAffected > 0 && (accountReq.Email != "" || passwordHash != "" || (accountReq.Role != "" && table == "staff")) {
+		if err = rec.revokeUserFamilies(ctx, tx, accountID); err != nil {
+			return -1, err
+		}
+	}
+
+	rec.invalidateDoctorCache(table, accountID)
+
+	return rowAffected, nil
+}
+
+// Queries UPDATE to deactivate or reactivate an account.
+// Deactivation revokes every session of the account.
+func (rec *Store) SetAccountActive(accountType string, accountID string, active bool) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := accountTable(accountType)
+	if err != nil {
+		return -1, err
+	}
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET is_active=$1 WHERE %s=$2", table, idColumn), active, accountID)
+	if err != nil {
+		return -1, err
+	}
+
+	rowAffected, err := result.RowsAffected()
+	if err != nil || rowAffected == 0 {
+		return rowAffected, err
+	}
+
+	if active {
+		// allow fresh logins again, refresh tokens revoked on deactivation stay revoked
+		_, err = tx.ExecContext(ctx, "DELETE FROM revoked_token WHERE token_id=$1", accountID)
+	} else {
+		_, err = tx.ExecContext(ctx, "UPDATE refresh_token SET revoked_at=CURRENT_TIMESTAMP WHERE user_id=$1 AND revoked_at IS NULL", accountID)
+		if err == nil {
+			accessTTL, _ := auth.TokenLifetime()
+			err = rec.revokeTokenID(ctx, tx, accountID, time.Now().Add(accessTTL))
+		}
+	}
+	if err != nil {
+		return -1, err
+	}
+
+	rec.invalidateDoctorCache(table, accountID)
+
+	return rowAffected, nil
+}
+
+// Removes cached doctor details after doctor account changes
+func (rec *Store) invalidateDoctorCache(table string, accountID string) {
+
+	if table != "doctor" || rec.rdb == nil {
+		return
+	}
+
+	redisCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second) // if redis takes too long, the query should be cancelled automatically after 15 seconds
+	defer cancel()
+
+	rec.rdb.Del(redisCtx, fmt.Sprintf("doctor:id:%s", accountID))
+}
+
+// Queries UPDATE to set a new password for the account with the email.
+// Ends every session of the account and clears its failed login counter.
+func (rec *Store) ResetPassword(accountType string, email string, passwordHash string) (uuid.UUID, error) {
+
+	var accountID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := accountTable(accountType)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, fmt.Sprintf("UPDATE %s SET password_hash=$1 WHERE lower(email)=lower($2) RETURNING %s", table, idColumn), passwordHash, email).Scan(&accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAccountNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	if err = rec.revokeUserFamilies(ctx, tx, accountID.String()); err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM login_throttle WHERE subject=$1", emailSubject(email))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return accountID, nil
+}
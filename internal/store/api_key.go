@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var ErrAPIKeyOwnerNotFound = errors.New("owner_id does not belong to an active doctor or staff account")
+
+type apiKeyQueryResponse struct {
+	KeyID      string   `json:"key_id"`
+	Name       string   `json:"name"`
+	KeyPrefix  string   `json:"key_prefix"`
+	OwnerID    string   `json:"owner_id"`
+	OwnerEmail string   `json:"owner_email"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at"`
+	RevokedAt  *string  `json:"revoked_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	LastUsedIP *string  `json:"last_used_ip"`
+	CreatedBy  string   `json:"created_by"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// active doctor and staff accounts an API key can act for
+const apiKeyOwners = "SELECT doctor_id AS account_id, email, role::text AS role FROM doctor WHERE is_active UNION ALL SELECT staff_id, email, role::text FROM staff WHERE is_active"
+
+// Queries INSERT to store a new API key, only the hash of the key is persisted
+func (rec *Store) CreateAPIKey(apiKeyReq *models.APIKey, keyHash string, keyPrefix string, createdBy uuid.UUID) (uuid.UUID, error) {
+
+	var keyID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "INSERT INTO api_key (name, key_prefix, key_hash, owner_id, scopes, expires_at, created_by) SELECT $1, $2, $3, owner.account_id, $5, $6, $7 FROM ("+apiKeyOwners+") AS owner WHERE owner.account_id=$4 RETURNING key_id",
+		apiKeyReq.Name, keyPrefix, keyHash, apiKeyReq.OwnerID, pq.Array(apiKeyReq.Scopes), apiKeyReq.ExpiresAt.UTC(), createdBy).Scan(&keyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrAPIKeyOwnerNotFound
+		}
+		log.Println("Error while inserting api key ", err)
+		return uuid.Nil, err
+	}
+	return keyID, nil
+}
+
+// Queries list of API keys, never returns the keys themselves
+func (rec *Store) GetAllAPIKeys(ownerID uuid.NullUUID, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, `SELECT k.key_id, k.name, k.key_prefix, k.owner_id, COALESCE(d.email, s.email, ''), k.scopes, k.expires_at, k.revoked_at, k.last_used_at, k.last_used_ip, k.created_by, k.created_at, count(*) over() as total_records
+		FROM api_key k LEFT JOIN doctor d ON d.doctor_id=k.owner_id LEFT JOIN staff s ON s.staff_id=k.owner_id
+		WHERE $1::uuid IS NULL OR k.owner_id=$1::uuid ORDER BY k.created_at DESC LIMIT $2 OFFSET $3`, ownerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allAPIKeyData := make([]apiKeyQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		var queryData apiKeyQueryResponse
+		err = rows.Scan(&queryData.KeyID, &queryData.Name, &queryData.KeyPrefix, &queryData.OwnerID, &queryData.OwnerEmail, pq.Array(&queryData.Scopes), &queryData.ExpiresAt,
+			&queryData.RevokedAt, &queryData.LastUsedAt, &queryData.LastUsedIP, &queryData.CreatedBy, &queryData.CreatedAt, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allAPIKeyData = append(allAPIKeyData, queryData)
+	}
+
+	responseData[0] = map[string][]apiKeyQueryResponse{"api_keys_data": allAPIKeyData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries UPDATE to revoke an API key, revoked keys stop working immediately
+func (rec *Store) RevokeAPIKey(keyID string) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "UPDATE api_key SET revoked_at=CURRENT_TIMESTAMP WHERE key_id::text=$1 AND revoked_at IS NULL", keyID)
+	if err != nil {
+		return -1, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Resolves a key hash to the account it acts for and records its use.
+// Returns nil when the key is unknown, revoked, expired or its owner is deactivated.
+func (rec *Store) AuthenticateAPIKey(keyHash string, clientIP string) (*auth.APIKeyPrincipal, error) {
+
+	var principal auth.APIKeyPrincipal
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `WITH used AS (
+			UPDATE api_key SET last_used_at=CURRENT_TIMESTAMP, last_used_ip=$2
+			WHERE key_hash=$1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+			RETURNING key_id, owner_id, scopes
+		)
+		SELECT used.key_id, owner.account_id, owner.email, owner.role, used.scopes FROM used JOIN (`+apiKeyOwners+`) AS owner ON owner.account_id=used.owner_id`,
+		keyHash, clientIP).Scan(&principal.KeyID, &principal.OwnerID, &principal.Email, &principal.Role, pq.Array(&principal.Scopes))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &principal, nil
+}
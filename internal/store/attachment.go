@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/filestore"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("no attachment found for provided ID")
+	ErrStorageUnavailable = errors.New("file storage is not configured")
+)
+
+type attachmentQueryResponse struct {
+	AttachmentID string `json:"attachment_id"`
+	TokenID      string `json:"token_id"`
+	models.Attachment
+	StorageKey string `json:"-"`
+	UploadedBy string `json:"uploaded_by"`
+	CreatedAt  string `json:"created_at"`
+}
+
+const attachmentColumns = `SELECT a.attachment_id, p.token_id, a.category, a.description, a.filename, a.content_type, a.size_bytes, a.checksum_sha256, a.storage_key, a.uploaded_by, a.created_at`
+
+func scanAttachment(row rowScanner, extra ...any) (attachmentQueryResponse, error) {
+	var queryData attachmentQueryResponse
+	dest := []any{&queryData.AttachmentID, &queryData.TokenID, &queryData.Category, &queryData.Description, &queryData.Filename, &queryData.ContentType,
+		&queryData.Size, &queryData.Checksum, &queryData.StorageKey, &queryData.UploadedBy, &queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return queryData, err
+}
+
+// Sets the storage uploaded files are kept in
+func (rec *Store) SetFileStorage(files filestore.FileStorage) {
+	rec.files = files
+}
+
+// Saves an uploaded file to storage and records it against the patient, the file is removed again if recording fails
+func (rec *Store) CreateAttachment(tokenID string, attachmentReq *models.Attachment, content []byte, uploadedBy uuid.UUID) (attachmentQueryResponse, error) {
+
+	var patientID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if rec.files == nil {
+		return attachmentQueryResponse{}, ErrStorageUnavailable
+	}
+
+	err := rec.db.QueryRowContext(ctx, "SELECT patient_id FROM patient WHERE token_id::text=$1", tokenID).Scan(&patientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return attachmentQueryResponse{}, ErrPatientNotFound
+		}
+		return attachmentQueryResponse{}, err
+	}
+
+	// keys do not carry file names or patient details
+	attachmentID := uuid.New()
+	storageKey := "attachments/" + attachmentID.String()
+	if err = rec.files.Put(ctx, storageKey, content, attachmentReq.ContentType); err != nil {
+		return attachmentQueryResponse{}, err
+	}
+
+	queryData, err := scanAttachment(rec.db.QueryRowContext(ctx, `WITH a AS (INSERT INTO attachment (attachment_id, patient_id, category, description, filename, content_type, size_bytes, checksum_sha256, storage_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING *) `+attachmentColumns+` FROM a JOIN patient p ON p.patient_id=a.patient_id`,
+		attachmentID, patientID, attachmentReq.Category, attachmentReq.Description, attachmentReq.Filename, attachmentReq.ContentType, attachmentReq.Size, attachmentReq.Checksum, storageKey, uploadedBy))
+	if err != nil {
+		if delErr := rec.files.Delete(context.Background(), storageKey); delErr != nil {
+			log.Printf("Failed to remove file %s of unsaved attachment: %v", storageKey, delErr)
+		}
+		return attachmentQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Queries attachments of a patient, newest first
+func (rec *Store) GetAttachments(tokenID string, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, attachmentColumns+", count(*) over() as total_records FROM attachment a JOIN patient p ON p.patient_id=a.patient_id WHERE p.token_id::text=$1 ORDER BY a.created_at DESC LIMIT $2 OFFSET $3",
+		tokenID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allAttachmentData := make([]attachmentQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanAttachment(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allAttachmentData = append(allAttachmentData, queryData)
+	}
+
+	responseData[0] = map[string][]attachmentQueryResponse{"attachments_data": allAttachmentData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries details of a single attachment
+func (rec *Store) GetAttachment(attachmentID string) (attachmentQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanAttachment(rec.db.QueryRowContext(ctx, attachmentColumns+" FROM attachment a JOIN patient p ON p.patient_id=a.patient_id WHERE a.attachment_id::text=$1", attachmentID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return attachmentQueryResponse{}, ErrAttachmentNotFound
+		}
+		return attachmentQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Opens the stored file of an attachment with its details, the caller closes the file
+func (rec *Store) OpenAttachment(ctx context.Context, attachmentID string) (io.ReadCloser, attachmentQueryResponse, error) {
+
+	if rec.files == nil {
+		return nil, attachmentQueryResponse{}, ErrStorageUnavailable
+	}
+
+	attachment, err := rec.GetAttachment(attachmentID)
+	if err != nil {
+		return nil, attachmentQueryResponse{}, err
+	}
+
+	file, err := rec.files.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			err = ErrAttachmentNotFound
+		}
+		return nil, attachmentQueryResponse{}, err
+	}
+	return file, attachment, nil
+}
+
+// Queries DELETE to remove an attachment, its file is removed from storage afterwards
+func (rec *Store) DeleteAttachment(attachmentID string) error {
+
+	var storageKey string
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "DELETE FROM attachment WHERE attachment_id::text=$1 RETURNING storage_key", attachmentID).Scan(&storageKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAttachmentNotFound
+		}
+		return err
+	}
+
+	rec.removeAttachmentFiles([]string{storageKey})
+	return nil
+}
+
+// Removes files whose attachment rows are deleted, failures leave orphaned files and are only logged
+func (rec *Store) removeAttachmentFiles(storageKeys []string) {
+
+	if rec.files == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+	defer cancel()
+
+	for _, storageKey := range storageKeys {
+		if err := rec.files.Delete(ctx, storageKey); err != nil {
+			log.Printf("Failed to remove file %s of deleted attachment: %v", storageKey, err)
+		}
+	}
+}
@@ -0,0 +1,605 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrFeeNotFound            = errors.New("no fee found for provided ID")
+	ErrFeeExists              = errors.New("a fee with this code, or an active consultation fee for this specialization, already exists")
+	ErrUnknownFeeCode         = errors.New("no active fee found for fee_code")
+	ErrConsultationFeeMissing = errors.New("no active consultation fee for the doctor's specialization or a default consultation fee")
+	ErrInvoiceNotFound        = errors.New("no invoice found for provided ID")
+	ErrInvoiceExists          = errors.New("visit already has an invoice, void it before creating another")
+	ErrInvoiceStatus          = errors.New("invoice is not in the required status")
+	ErrInvoiceHasPayments     = errors.New("invoice has payments and cannot be voided")
+	ErrOverpayment            = errors.New("amount is more than the balance due")
+	ErrPatientHasInvoices     = errors.New("patient has invoices and cannot be deleted, billing records are kept")
+)
+
+type feeQueryResponse struct {
+	FeeID string `json:"fee_id"`
+	models.Fee
+	UpdatedAt string `json:"updated_at"`
+}
+
+type invoiceQueryResponse struct {
+	InvoiceID   string `json:"invoice_id"`
+	InvoiceNo   string `json:"invoice_no"`
+	TokenID     string `json:"token_id"`
+	PatientName string `json:"patient_name"`
+	EncounterID string `json:"encounter_id"`
+	InvoiceDate string `json:"invoice_date"`
+	Status      string `json:"status"`
+	models.InvoiceTotals
+	AmountPaid float64              `json:"amount_paid"`
+	BalanceDue float64              `json:"balance_due"`
+	Notes      string               `json:"notes"`
+	VoidReason string               `json:"void_reason"`
+	VoidedAt   *string              `json:"voided_at"`
+	CreatedBy  string               `json:"created_by"`
+	CreatedAt  string               `json:"created_at"`
+	Lines      []models.InvoiceLine `json:"lines,omitempty"`
+	Payments   []paymentResponse    `json:"payments,omitempty"`
+}
+
+type paymentResponse struct {
+	PaymentID    string  `json:"payment_id"`
+	ReceiptNo    string  `json:"receipt_no"`
+	InvoiceID    string  `json:"invoice_id"`
+	InvoiceNo    string  `json:"invoice_no"`
+	Amount       float64 `json:"amount"`
+	Method       string  `json:"method"`
+	Reference    string  `json:"reference"`
+	BusinessDate string  `json:"business_date"`
+	ReceivedBy   string  `json:"received_by"`
+	ReceivedAt   string  `json:"received_at"`
+}
+
+// Payment with the invoice it settled
+type paymentQueryResponse struct {
+	paymentResponse
+	InvoiceStatus string  `json:"invoice_status"`
+	BalanceDue    float64 `json:"balance_due"`
+}
+
+// Filters for invoice lists, empty fields are not applied
+type InvoiceFilter struct {
+	TokenID     string
+	Status      string
+	InvoiceDate string // YYYY-MM-DD
+}
+
+type cashClosingTotal struct {
+	Method string  `json:"method"`
+	Count  int     `json:"count"`
+	Total  float64 `json:"total"`
+}
+
+type cashClosingStaffTotal struct {
+	StaffID   string  `json:"staff_id"`
+	StaffName string  `json:"staff_name"`
+	Count     int     `json:"count"`
+	Total     float64 `json:"total"`
+}
+
+type cashClosingReceipt struct {
+	ReceiptNo      string  `json:"receipt_no"`
+	InvoiceNo      string  `json:"invoice_no"`
+	TokenID        string  `json:"token_id"`
+	PatientName    string  `json:"patient_name"`
+	Amount         float64 `json:"amount"`
+	Method         string  `json:"method"`
+	Reference      string  `json:"reference"`
+	ReceivedBy     string  `json:"received_by"`
+	ReceivedByName string  `json:"received_by_name"`
+	ReceivedAt     string  `json:"received_at"`
+}
+
+// Money received on a clinic date with the invoices issued that day
+type cashClosingResponse struct {
+	Date             string                  `json:"date"`
+	PaymentCount     int                     `json:"payment_count"`
+	TotalCollected   float64                 `json:"total_collected"`
+	ByMethod         []cashClosingTotal      `json:"by_method"`
+	ByStaff          []cashClosingStaffTotal `json:"by_staff"`
+	InvoicesIssued   int                     `json:"invoices_issued"`
+	InvoicedTotal    float64                 `json:"invoiced_total"`
+	OutstandingTotal float64                 `json:"outstanding_total"` // still due on the invoices issued that day
+	InvoicesVoided   int                     `json:"invoices_voided"`
+	Receipts         []cashClosingReceipt    `json:"receipts"`
+}
+
+const (
+	feeColumns     = `SELECT fee_id, code, name, category, COALESCE(specialization, ''), amount, tax_rate, active, updated_at`
+	invoiceColumns = `SELECT i.invoice_id, i.invoice_no, p.token_id, p.fullname, i.encounter_id, to_char(i.invoice_date, 'YYYY-MM-DD'), i.status,
+	i.subtotal, i.discount_total, i.tax_total, i.total, i.amount_paid, i.total - i.amount_paid, i.notes, i.void_reason, i.voided_at, i.created_by, i.created_at`
+	invoiceFrom    = ` FROM invoice i JOIN patient p ON p.patient_id=i.patient_id `
+	paymentColumns = `SELECT pay.payment_id, pay.receipt_no, pay.invoice_id, i.invoice_no, pay.amount, pay.method, pay.reference, to_char(pay.business_date, 'YYYY-MM-DD'),
+	pay.received_by, pay.received_at FROM payment pay JOIN invoice i ON i.invoice_id=pay.invoice_id`
+)
+
+func scanFee(row rowScanner) (feeQueryResponse, error) {
+	var queryData feeQueryResponse
+	var active bool
+	err := row.Scan(&queryData.FeeID, &queryData.Code, &queryData.Name, &queryData.Category, &queryData.Specialization, &queryData.Amount, &queryData.TaxRate,
+		&active, &queryData.UpdatedAt)
+	queryData.Active = &active
+	return queryData, err
+}
+
+func scanInvoice(row rowScanner, extra ...any) (invoiceQueryResponse, error) {
+	var queryData invoiceQueryResponse
+	dest := []any{&queryData.InvoiceID, &queryData.InvoiceNo, &queryData.TokenID, &queryData.PatientName, &queryData.EncounterID, &queryData.InvoiceDate,
+		&queryData.Status, &queryData.Subtotal, &queryData.DiscountTotal, &queryData.TaxTotal, &queryData.Total, &queryData.AmountPaid, &queryData.BalanceDue,
+		&queryData.Notes, &queryData.VoidReason, &queryData.VoidedAt, &queryData.CreatedBy, &queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return queryData, err
+}
+
+func scanPayment(row rowScanner) (paymentResponse, error) {
+	var queryData paymentResponse
+	err := row.Scan(&queryData.PaymentID, &queryData.ReceiptNo, &queryData.InvoiceID, &queryData.InvoiceNo, &queryData.Amount, &queryData.Method,
+		&queryData.Reference, &queryData.BusinessDate, &queryData.ReceivedBy, &queryData.ReceivedAt)
+	return queryData, err
+}
+
+// Takes the next number of a yearly billing counter, e.g. INV-2025-000042.
+// The counter row stays locked until the transaction ends so numbers are neither reused nor skipped.
+func nextBillingNumber(ctx context.Context, tx *sql.Tx, counter string, prefix string, year int) (string, error) {
+
+	var value int
+	err := tx.QueryRowContext(ctx, `INSERT INTO billing_counter (counter, year, last_value) VALUES ($1, $2, 1)
+		ON CONFLICT (counter, year) DO UPDATE SET last_value = billing_counter.last_value + 1 RETURNING last_value`, counter, year).Scan(&value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%06d", prefix, year, value), nil
+}
+
+// Queries the fee schedule, filtered by category when set
+func (rec *Store) GetFees(category string, includeInactive bool) ([]feeQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, feeColumns+" FROM fee WHERE ($1 = '' OR category=$1) AND ($2 OR active) ORDER BY category, specialization NULLS FIRST, code", category, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fees := make([]feeQueryResponse, 0)
+	for rows.Next() {
+		queryData, err := scanFee(rows)
+		if err != nil {
+			return nil, err
+		}
+		fees = append(fees, queryData)
+	}
+	return fees, rows.Err()
+}
+
+// Queries INSERT to add a fee to the schedule
+func (rec *Store) CreateFee(feeReq *models.Fee) (feeQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanFee(rec.db.QueryRowContext(ctx, `WITH f AS (INSERT INTO fee (code, name, category, specialization, amount, tax_rate, active)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7) RETURNING *) `+feeColumns+` FROM f`,
+		feeReq.Code, feeReq.Name, feeReq.Category, feeReq.Specialization, feeReq.Amount, feeReq.TaxRate, *feeReq.Active))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return feeQueryResponse{}, ErrFeeExists
+		}
+		return feeQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Queries UPDATE to replace a fee, invoices already issued keep the price they were issued with
+func (rec *Store) UpdateFee(feeID string, feeReq *models.Fee) (feeQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanFee(rec.db.QueryRowContext(ctx, `WITH f AS (UPDATE fee SET code=$2, name=$3, category=$4, specialization=NULLIF($5, ''), amount=$6, tax_rate=$7, active=$8
+		WHERE fee_id::text=$1 RETURNING *) `+feeColumns+` FROM f`,
+		feeID, feeReq.Code, feeReq.Name, feeReq.Category, feeReq.Specialization, feeReq.Amount, feeReq.TaxRate, *feeReq.Active))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.Is(err, sql.ErrNoRows) {
+			return feeQueryResponse{}, ErrFeeNotFound
+		}
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return feeQueryResponse{}, ErrFeeExists
+		}
+		return feeQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Reads an active fee within the invoice transaction
+func activeFee(ctx context.Context, tx *sql.Tx, where string, args ...any) (feeQueryResponse, error) {
+	return scanFee(tx.QueryRowContext(ctx, feeColumns+" FROM fee WHERE active AND "+where, args...))
+}
+
+// Queries INSERT to invoice a visit. Fee schedule items are priced from the schedule and the consultation fee
+// of the visit doctor's specialization is added first, falling back to the default consultation fee.
+func (rec *Store) CreateInvoice(encounterID string, invoiceReq *models.InvoiceRequest, invoiceDate time.Time, createdBy uuid.UUID) (uuid.UUID, error) {
+
+	var patientID, invoiceID uuid.UUID
+	var specialization string
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	// lock the visit so it is not invoiced twice at the same time
+	err = tx.QueryRowContext(ctx, `SELECT e.patient_id, COALESCE(d.specialization, '') FROM encounter e JOIN doctor d ON d.doctor_id=e.doctor_id
+		WHERE e.encounter_id::text=$1 FOR UPDATE OF e`, encounterID).Scan(&patientID, &specialization)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrEncounterNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	lines := make([]models.InvoiceLine, 0, len(invoiceReq.Items)+1)
+	feeLine := func(fee feeQueryResponse, quantity int, discountPercent float64) models.InvoiceLine {
+		return models.InvoiceLine{FeeID: &fee.FeeID, Code: fee.Code, Description: fee.Name, Quantity: quantity, UnitPrice: fee.Amount,
+			DiscountPercent: discountPercent, TaxRate: fee.TaxRate}
+	}
+
+	if invoiceReq.IncludeConsultation == nil || *invoiceReq.IncludeConsultation {
+		fee, feeErr := activeFee(ctx, tx, `category='consultation' AND (specialization IS NULL OR lower(specialization)=lower($1))
+			ORDER BY specialization NULLS LAST LIMIT 1`, specialization)
+		if feeErr != nil {
+			err = feeErr
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrConsultationFeeMissing
+			}
+			return uuid.Nil, err
+		}
+		lines = append(lines, feeLine(fee, 1, 0))
+	}
+
+	for _, item := range invoiceReq.Items {
+		if item.FeeCode == "" {
+			lines = append(lines, models.InvoiceLine{Description: item.Description, Quantity: item.Quantity, UnitPrice: *item.UnitPrice,
+				DiscountPercent: item.DiscountPercent, TaxRate: item.TaxRate})
+			continue
+		}
+		fee, feeErr := activeFee(ctx, tx, "code=$1", item.FeeCode)
+		if feeErr != nil {
+			err = feeErr
+			if errors.Is(err, sql.ErrNoRows) {
+				err = fmt.Errorf("%w %s", ErrUnknownFeeCode, item.FeeCode)
+			}
+			return uuid.Nil, err
+		}
+		lines = append(lines, feeLine(fee, item.Quantity, item.DiscountPercent))
+	}
+
+	for i := range lines {
+		lines[i].LineNo = i + 1
+		models.PriceInvoiceLine(&lines[i])
+	}
+	totals := models.TotalInvoiceLines(lines)
+
+	invoiceNo, err := nextBillingNumber(ctx, tx, "invoice", "INV", invoiceDate.Year())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO invoice (invoice_no, patient_id, encounter_id, invoice_date, subtotal, discount_total, tax_total, total, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING invoice_id`,
+		invoiceNo, patientID, encounterID, invoiceDate.Format(models.DateLayout), totals.Subtotal, totals.DiscountTotal, totals.TaxTotal, totals.Total,
+		invoiceReq.Notes, createdBy).Scan(&invoiceID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "uq_invoice_encounter" {
+			err = ErrInvoiceExists
+		}
+		return uuid.Nil, err
+	}
+
+	for _, line := range lines {
+		_, err = tx.ExecContext(ctx, `INSERT INTO invoice_line (invoice_id, line_no, fee_id, code, description, quantity, unit_price, discount_percent, discount_amount,
+			tax_rate, tax_amount, line_total) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			invoiceID, line.LineNo, line.FeeID, line.Code, line.Description, line.Quantity, line.UnitPrice, line.DiscountPercent, line.DiscountAmount,
+			line.TaxRate, line.TaxAmount, line.LineTotal)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	return invoiceID, nil
+}
+
+// Queries invoices newest first
+func (rec *Store) GetInvoices(filter InvoiceFilter, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, invoiceColumns+", count(*) over() as total_records"+invoiceFrom+`WHERE ($1 = '' OR p.token_id::text=$1) AND ($2 = '' OR i.status=$2)
+		AND ($3 = '' OR i.invoice_date::text=$3) ORDER BY i.created_at DESC LIMIT $4 OFFSET $5`,
+		filter.TokenID, filter.Status, filter.InvoiceDate, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allInvoiceData := make([]invoiceQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanInvoice(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allInvoiceData = append(allInvoiceData, queryData)
+	}
+
+	responseData[0] = map[string][]invoiceQueryResponse{"invoices_data": allInvoiceData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries a single invoice with its lines and payments
+func (rec *Store) GetInvoice(invoiceID string) (invoiceQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanInvoice(rec.db.QueryRowContext(ctx, invoiceColumns+invoiceFrom+"WHERE i.invoice_id::text=$1", invoiceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return invoiceQueryResponse{}, ErrInvoiceNotFound
+		}
+		return invoiceQueryResponse{}, err
+	}
+
+	rows, err := rec.db.QueryContext(ctx, `SELECT line_no, fee_id, code, description, quantity, unit_price, discount_percent, discount_amount, tax_rate, tax_amount, line_total
+		FROM invoice_line WHERE invoice_id=$1 ORDER BY line_no`, queryData.InvoiceID)
+	if err != nil {
+		return invoiceQueryResponse{}, err
+	}
+	defer rows.Close()
+
+	queryData.Lines = make([]models.InvoiceLine, 0)
+	for rows.Next() {
+		var line models.InvoiceLine
+		err = rows.Scan(&line.LineNo, &line.FeeID, &line.Code, &line.Description, &line.Quantity, &line.UnitPrice, &line.DiscountPercent, &line.DiscountAmount,
+			&line.TaxRate, &line.TaxAmount, &line.LineTotal)
+		if err != nil {
+			return invoiceQueryResponse{}, err
+		}
+		queryData.Lines = append(queryData.Lines, line)
+	}
+	rows.Close()
+
+	paymentRows, err := rec.db.QueryContext(ctx, paymentColumns+" WHERE pay.invoice_id=$1 ORDER BY pay.received_at", queryData.InvoiceID)
+	if err != nil {
+		return invoiceQueryResponse{}, err
+	}
+	defer paymentRows.Close()
+
+	queryData.Payments = make([]paymentResponse, 0)
+	for paymentRows.Next() {
+		payment, err := scanPayment(paymentRows)
+		if err != nil {
+			return invoiceQueryResponse{}, err
+		}
+		queryData.Payments = append(queryData.Payments, payment)
+	}
+
+	return queryData, nil
+}
+
+// Queries INSERT to record money received against an invoice, part payments leave the invoice partially paid.
+// The receipt number is taken from the yearly receipt counter of the business date.
+func (rec *Store) RecordPayment(invoiceID string, paymentReq *models.Payment, businessDate time.Time, receivedBy uuid.UUID) (paymentQueryResponse, error) {
+
+	var status, invoiceNo string
+	var total, amountPaid float64
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return paymentQueryResponse{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT invoice_no, status, total, amount_paid FROM invoice WHERE invoice_id::text=$1 FOR UPDATE", invoiceID).Scan(&invoiceNo, &status, &total, &amountPaid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrInvoiceNotFound
+		}
+		return paymentQueryResponse{}, err
+	}
+
+	if status == models.InvoiceStatusPaid || status == models.InvoiceStatusVoid {
+		err = fmt.Errorf("%w, invoice is %s", ErrInvoiceStatus, status)
+		return paymentQueryResponse{}, err
+	}
+
+	balance := models.ToPaise(total) - models.ToPaise(amountPaid)
+	if models.ToPaise(paymentReq.Amount) > balance {
+		err = fmt.Errorf("%w of %.2f", ErrOverpayment, models.FromPaise(balance))
+		return paymentQueryResponse{}, err
+	}
+
+	receiptNo, err := nextBillingNumber(ctx, tx, "receipt", "RCPT", businessDate.Year())
+	if err != nil {
+		return paymentQueryResponse{}, err
+	}
+
+	queryData := paymentQueryResponse{paymentResponse: paymentResponse{ReceiptNo: receiptNo, InvoiceNo: invoiceNo, Amount: paymentReq.Amount,
+		Method: paymentReq.Method, Reference: paymentReq.Reference, BusinessDate: businessDate.Format(models.DateLayout), ReceivedBy: receivedBy.String()}}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO payment (receipt_no, invoice_id, amount, method, reference, business_date, received_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING payment_id, invoice_id, received_at`,
+		receiptNo, invoiceID, paymentReq.Amount, paymentReq.Method, paymentReq.Reference, queryData.BusinessDate, receivedBy).Scan(&queryData.PaymentID, &queryData.InvoiceID, &queryData.ReceivedAt)
+	if err != nil {
+		return paymentQueryResponse{}, err
+	}
+
+	queryData.BalanceDue = models.FromPaise(balance - models.ToPaise(paymentReq.Amount))
+	queryData.InvoiceStatus = models.InvoiceStatusPartiallyPaid
+	if queryData.BalanceDue == 0 {
+		queryData.InvoiceStatus = models.InvoiceStatusPaid
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE invoice SET amount_paid=amount_paid+$2, status=$3 WHERE invoice_id::text=$1", invoiceID, paymentReq.Amount, queryData.InvoiceStatus)
+	if err != nil {
+		return paymentQueryResponse{}, err
+	}
+
+	return queryData, nil
+}
+
+// Queries UPDATE to void an invoice without payments, or explains why it cannot be voided
+func (rec *Store) VoidInvoice(invoiceID string, reason string, voidedBy uuid.UUID) error {
+
+	var voided int
+	var status sql.NullString
+	var amountPaid sql.NullFloat64
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// current sees the row as it was before the update
+	err := rec.db.QueryRowContext(ctx, `WITH current AS (SELECT status, amount_paid FROM invoice WHERE invoice_id::text=$1),
+		voided AS (UPDATE invoice SET status='void', void_reason=$2, voided_by=$3, voided_at=CURRENT_TIMESTAMP
+			WHERE invoice_id::text=$1 AND status<>'void' AND amount_paid=0 RETURNING invoice_id)
+		SELECT (SELECT count(*) FROM voided), (SELECT status FROM current), (SELECT amount_paid FROM current)`, invoiceID, reason, voidedBy).Scan(&voided, &status, &amountPaid)
+	if err != nil {
+		return err
+	}
+	if voided == 1 {
+		return nil
+	}
+	if !status.Valid {
+		return ErrInvoiceNotFound
+	}
+	if status.String == models.InvoiceStatusVoid {
+		return fmt.Errorf("%w, invoice is %s", ErrInvoiceStatus, status.String)
+	}
+	return ErrInvoiceHasPayments
+}
+
+// Queries payments received on a clinic date, with totals by method and by the staff member who received them
+func (rec *Store) GetCashClosing(businessDate string) (cashClosingResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	report := cashClosingResponse{Date: businessDate, ByMethod: []cashClosingTotal{}, ByStaff: []cashClosingStaffTotal{}, Receipts: []cashClosingReceipt{}}
+
+	rows, err := rec.db.QueryContext(ctx, `SELECT pay.receipt_no, i.invoice_no, p.token_id, p.fullname, pay.amount, pay.method, pay.reference, pay.received_by,
+		COALESCE(s.fullname, ''), pay.received_at FROM payment pay JOIN invoice i ON i.invoice_id=pay.invoice_id JOIN patient p ON p.patient_id=i.patient_id
+		LEFT JOIN staff s ON s.staff_id=pay.received_by WHERE pay.business_date=$1 ORDER BY pay.receipt_no`, businessDate)
+	if err != nil {
+		return cashClosingResponse{}, err
+	}
+	defer rows.Close()
+
+	// add up in paise, every method is listed so the cash expected in the drawer is always shown
+	methodTotals := make(map[string]int64)
+	methodCounts := make(map[string]int)
+	staffIndex := make(map[string]int)
+	staffTotals := []int64{}
+	var collected int64
+	for rows.Next() {
+		var receipt cashClosingReceipt
+		err = rows.Scan(&receipt.ReceiptNo, &receipt.InvoiceNo, &receipt.TokenID, &receipt.PatientName, &receipt.Amount, &receipt.Method, &receipt.Reference,
+			&receipt.ReceivedBy, &receipt.ReceivedByName, &receipt.ReceivedAt)
+		if err != nil {
+			return cashClosingResponse{}, err
+		}
+		report.Receipts = append(report.Receipts, receipt)
+
+		amount := models.ToPaise(receipt.Amount)
+		collected += amount
+		methodTotals[receipt.Method] += amount
+		methodCounts[receipt.Method]++
+
+		i, ok := staffIndex[receipt.ReceivedBy]
+		if !ok {
+			i = len(report.ByStaff)
+			staffIndex[receipt.ReceivedBy] = i
+			report.ByStaff = append(report.ByStaff, cashClosingStaffTotal{StaffID: receipt.ReceivedBy, StaffName: receipt.ReceivedByName})
+			staffTotals = append(staffTotals, 0)
+		}
+		report.ByStaff[i].Count++
+		staffTotals[i] += amount
+	}
+	rows.Close()
+
+	for i := range report.ByStaff {
+		report.ByStaff[i].Total = models.FromPaise(staffTotals[i])
+	}
+	for _, method := range models.PaymentMethods {
+		report.ByMethod = append(report.ByMethod, cashClosingTotal{Method: method, Count: methodCounts[method], Total: models.FromPaise(methodTotals[method])})
+	}
+	report.PaymentCount = len(report.Receipts)
+	report.TotalCollected = models.FromPaise(collected)
+
+	err = rec.db.QueryRowContext(ctx, `SELECT count(*) FILTER (WHERE status<>'void'), COALESCE(sum(total) FILTER (WHERE status<>'void'), 0),
+		COALESCE(sum(total - amount_paid) FILTER (WHERE status<>'void'), 0), count(*) FILTER (WHERE status='void') FROM invoice WHERE invoice_date=$1`, businessDate).Scan(
+		&report.InvoicesIssued, &report.InvoicedTotal, &report.OutstandingTotal, &report.InvoicesVoided)
+	if err != nil {
+		return cashClosingResponse{}, err
+	}
+
+	return report, nil
+}
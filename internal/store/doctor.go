@@ -11,6 +11,7 @@ import (
 )
 
 type doctorQueryResponse struct {
+	DoctorID       string `json:"doctor_id,omitempty"`
 	Fullname       string `json:"fullname"`
 	Email          string `json:"email"`
 	Specialization string `json:"specialization"`
@@ -49,8 +50,8 @@ func (d *Store) GetDoctorById(id string) (interface{}, error) {
 		log.Printf("Cache miss for doctor:id:%s", id)
 	}
 
-	err = d.db.QueryRowContext(ctx, "SELECT fullname, email, specialization, created_at, updated_at FROM doctor WHERE doctor_id=$1", id).Scan(
-		&queryData.Fullname, &queryData.Email, &queryData.Specialization, &queryData.CreatedAt, &queryData.UpdatedAt)
+	err = d.db.QueryRowContext(ctx, "SELECT doctor_id, fullname, email, COALESCE(specialization, ''), created_at, updated_at FROM doctor WHERE doctor_id=$1", id).Scan(
+		&queryData.DoctorID, &queryData.Fullname, &queryData.Email, &queryData.Specialization, &queryData.CreatedAt, &queryData.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return queryData, nil // return empty model
@@ -67,3 +68,51 @@ func (d *Store) GetDoctorById(id string) (interface{}, error) {
 
 	return queryData, err
 }
+
+// Queries directory of active doctors filtered by specialization and name
+func (d *Store) GetAllDoctors(specialization string, name string, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT doctor_id, fullname, email, COALESCE(specialization, ''), created_at, updated_at, count(*) over() as total_records FROM doctor WHERE is_active AND ($1::text = '' OR specialization ILIKE $1::text) AND ($2::text = '' OR fullname ILIKE '%' || $2::text || '%') ORDER BY fullname LIMIT $3 OFFSET $4",
+		specialization, name, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allDoctorData := make([]doctorQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	redisCtx, redisCancel := context.WithTimeout(context.Background(), 15*time.Second) // if redis takes too long, the query should be cancelled automatically after 15 seconds
+	defer redisCancel()
+
+	// Get each row data into a slice
+	for rows.Next() {
+		var queryData doctorQueryResponse
+		err = rows.Scan(&queryData.DoctorID, &queryData.Fullname, &queryData.Email, &queryData.Specialization, &queryData.CreatedAt, &queryData.UpdatedAt, &total_records)
+		if err != nil {
+			return nil, err
+		}
+
+		// warm the cache used by doctor lookups
+		if d.rdb != nil {
+			jsonData, _ := json.Marshal(queryData)
+			d.rdb.Set(redisCtx, fmt.Sprintf("doctor:id:%s", queryData.DoctorID), jsonData, 60*time.Minute)
+		}
+
+		allDoctorData = append(allDoctorData, queryData)
+	}
+
+	responseData[0] = map[string][]doctorQueryResponse{"doctors_data": allDoctorData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
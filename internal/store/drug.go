@@ -0,0 +1,354 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+// Prescriptions written within this window count as the patient's active medications
+const activeMedicationWindow = "30 days"
+
+type drugQueryResponse struct {
+	DrugID      string `json:"drug_id"`
+	Name        string `json:"name"`
+	GenericName string `json:"generic_name"`
+	DrugClass   string `json:"drug_class"`
+	Form        string `json:"form"`
+	Strength    string `json:"strength"`
+}
+
+// Result of a catalogue import, existing drugs are updated in place
+type CatalogueImportResult struct {
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"`
+}
+
+// Satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Names a drug is matched by in rules and allergies, lowercase
+type resolvedDrug struct {
+	name string
+	keys []string
+}
+
+type interactionRule struct{ severity, description string }
+
+// Finds the rule between two drugs, rules are keyed by their subjects in sorted order
+func findInteraction(rules map[[2]string]interactionRule, x resolvedDrug, y resolvedDrug) (interactionRule, bool) {
+	for _, a := range x.keys {
+		for _, b := range y.keys {
+			lo, hi := a, b
+			if hi < lo {
+				lo, hi = hi, lo
+			}
+			if found, ok := rules[[2]string{lo, hi}]; ok {
+				return found, true
+			}
+		}
+	}
+	return interactionRule{}, false
+}
+
+// Queries the catalogue by name or generic name
+func (rec *Store) GetDrugs(search string, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, `SELECT drug_id, name, generic_name, drug_class, form, strength, count(*) over() as total_records FROM drug
+		WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR generic_name ILIKE '%' || $1 || '%' ORDER BY name LIMIT $2 OFFSET $3`, search, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allDrugData := make([]drugQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		var queryData drugQueryResponse
+		if err = rows.Scan(&queryData.DrugID, &queryData.Name, &queryData.GenericName, &queryData.DrugClass, &queryData.Form, &queryData.Strength, &total_records); err != nil {
+			return nil, err
+		}
+		allDrugData = append(allDrugData, queryData)
+	}
+
+	responseData[0] = map[string][]drugQueryResponse{"drugs_data": allDrugData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Inserts or updates drugs in one transaction, matched by name ignoring case
+func (rec *Store) ImportDrugs(drugs []models.Drug) (CatalogueImportResult, error) {
+
+	var result CatalogueImportResult
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		}
+	}()
+
+	for _, drug := range drugs {
+		var inserted bool
+		// xmax is 0 only for rows inserted by this statement
+		err = tx.QueryRowContext(ctx, `INSERT INTO drug (name, generic_name, drug_class, form, strength) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (lower(name)) DO UPDATE SET generic_name=EXCLUDED.generic_name, drug_class=EXCLUDED.drug_class, form=EXCLUDED.form, strength=EXCLUDED.strength, updated_at=CURRENT_TIMESTAMP
+			RETURNING xmax = 0`, drug.Name, drug.GenericName, drug.DrugClass, drug.Form, drug.Strength).Scan(&inserted)
+		if err != nil {
+			return CatalogueImportResult{}, err
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return CatalogueImportResult{}, err
+	}
+	return result, nil
+}
+
+// Inserts or updates interaction rules in one transaction
+func (rec *Store) ImportDrugInteractions(interactions []models.DrugInteraction) (CatalogueImportResult, error) {
+
+	var result CatalogueImportResult
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		}
+	}()
+
+	for _, interaction := range interactions {
+		var inserted bool
+		err = tx.QueryRowContext(ctx, `INSERT INTO drug_interaction (subject_a, subject_b, severity, description) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (subject_a, subject_b) DO UPDATE SET severity=EXCLUDED.severity, description=EXCLUDED.description
+			RETURNING xmax = 0`, interaction.SubjectA, interaction.SubjectB, interaction.Severity, interaction.Description).Scan(&inserted)
+		if err != nil {
+			return CatalogueImportResult{}, err
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return CatalogueImportResult{}, err
+	}
+	return result, nil
+}
+
+// Looks up drug names in the catalogue, unknown drugs are matched by their own name only
+func (rec *Store) resolveDrugs(ctx context.Context, names []string) ([]resolvedDrug, error) {
+
+	resolved := make([]resolvedDrug, 0, len(names))
+	for _, name := range names {
+		var generic, class string
+		drug := resolvedDrug{name: name, keys: []string{strings.ToLower(name)}}
+
+		err := rec.db.QueryRowContext(ctx, "SELECT lower(generic_name), lower(drug_class) FROM drug WHERE lower(name)=lower($1) OR lower(generic_name)=lower($1) ORDER BY lower(name)=lower($1) DESC LIMIT 1", name).Scan(&generic, &class)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		for _, key := range []string{generic, class} {
+			if key != "" && key != drug.keys[0] {
+				drug.keys = append(drug.keys, key)
+			}
+		}
+		resolved = append(resolved, drug)
+	}
+	return resolved, nil
+}
+
+// Finds catalogue drugs named in free text such as a treatment plan
+func (rec *Store) FindDrugsInText(text string) ([]string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	// whole word matches, regex characters in names are escaped
+	rows, err := rec.db.QueryContext(ctx, `SELECT name FROM drug
+		WHERE lower($1) ~ ('\m' || regexp_replace(lower(name), '([.*+?^${}()|\[\]\\])', '\\\1', 'g') || '\M')
+		OR lower($1) ~ ('\m' || regexp_replace(lower(generic_name), '([.*+?^${}()|\[\]\\])', '\\\1', 'g') || '\M')
+		ORDER BY name`, text)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Checks drugs about to be prescribed against each other, the patient's active medications and recorded allergies
+func (rec *Store) CheckMedications(tokenID string, drugNames []string) ([]models.MedicationWarning, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	warnings := make([]models.MedicationWarning, 0)
+	if len(drugNames) == 0 {
+		return warnings, nil
+	}
+
+	newDrugs, err := rec.resolveDrugs(ctx, drugNames)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := rec.db.QueryContext(ctx, `SELECT DISTINCT i.drug FROM prescription_item i JOIN prescription rx ON rx.prescription_id=i.prescription_id JOIN patient p ON p.patient_id=rx.patient_id
+		WHERE p.token_id::text=$1 AND rx.status='active' AND rx.created_at > CURRENT_TIMESTAMP - $2::interval`, tokenID, activeMedicationWindow)
+	if err != nil {
+		return nil, err
+	}
+	var activeNames []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		activeNames = append(activeNames, name)
+	}
+	rows.Close()
+
+	activeDrugs, err := rec.resolveDrugs(ctx, activeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// rules touching any of the drugs involved
+	var subjects []string
+	for _, drug := range append(append([]resolvedDrug{}, newDrugs...), activeDrugs...) {
+		subjects = append(subjects, drug.keys...)
+	}
+
+	rules := make(map[[2]string]interactionRule)
+	rows, err = rec.db.QueryContext(ctx, "SELECT subject_a, subject_b, severity, description FROM drug_interaction WHERE subject_a = ANY($1) AND subject_b = ANY($1)", pq.Array(subjects))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var a, b string
+		var found interactionRule
+		if err = rows.Scan(&a, &b, &found.severity, &found.description); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rules[[2]string{a, b}] = found
+	}
+	rows.Close()
+
+	for i, drug := range newDrugs {
+		for _, other := range newDrugs[i+1:] {
+			if found, ok := findInteraction(rules, drug, other); ok {
+				warnings = append(warnings, models.MedicationWarning{Type: models.WarningInteraction, Severity: found.severity, Drug: drug.name, With: other.name, Description: found.description})
+			}
+		}
+		for _, other := range activeDrugs {
+			if strings.EqualFold(drug.name, other.name) {
+				continue // represcribing the same drug
+			}
+			if found, ok := findInteraction(rules, drug, other); ok {
+				warnings = append(warnings, models.MedicationWarning{Type: models.WarningInteraction, Severity: found.severity, Drug: drug.name, With: other.name + " (active medication)", Description: found.description})
+			}
+		}
+	}
+
+	rows, err = rec.db.QueryContext(ctx, "SELECT a.substance, a.reaction, a.severity FROM patient_allergy a JOIN patient p ON p.patient_id=a.patient_id WHERE p.token_id::text=$1", tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var substance, reaction, severity string
+		if err = rows.Scan(&substance, &reaction, &severity); err != nil {
+			return nil, err
+		}
+		for _, drug := range newDrugs {
+			for _, key := range drug.keys {
+				if strings.EqualFold(key, substance) {
+					description := "Patient is allergic to " + substance
+					if reaction != "" {
+						description += ", reaction: " + reaction
+					}
+					warnings = append(warnings, models.MedicationWarning{Type: models.WarningAllergy, Severity: severity, Drug: drug.name, With: substance, Description: description})
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Type < warnings[j].Type })
+	return warnings, nil
+}
+
+// Inserts accepted warnings with the doctor's reason
+func recordMedicationOverride(ctx context.Context, q execer, tokenID string, doctorID uuid.UUID, source string, prescriptionID uuid.NullUUID, warnings []models.MedicationWarning, reason string) error {
+
+	warningsJSON, err := json.Marshal(warnings)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, "INSERT INTO medication_override (patient_id, doctor_id, source, prescription_id, warnings, reason) SELECT patient_id, $2, $3, $4, $5, $6 FROM patient WHERE token_id::text=$1",
+		tokenID, doctorID, source, prescriptionID, string(warningsJSON), reason)
+	return err
+}
@@ -0,0 +1,39 @@
+package store
+
+import "testing"
+
+func TestFindInteraction(t *testing.T) {
+
+	rules := map[[2]string]interactionRule{
+		{"nsaid", "warfarin"}:        {severity: "major", description: "bleeding risk"},
+		{"clarithromycin", "statin"}: {severity: "moderate", description: "myopathy risk"},
+	}
+
+	warfarin := resolvedDrug{name: "Warf", keys: []string{"warfarin", "anticoagulant"}}
+	ibuprofen := resolvedDrug{name: "Brufen", keys: []string{"ibuprofen", "nsaid"}}
+	atorvastatin := resolvedDrug{name: "Atorva", keys: []string{"atorvastatin", "statin"}}
+	clarithromycin := resolvedDrug{name: "Claribid", keys: []string{"clarithromycin", "macrolide"}}
+	paracetamol := resolvedDrug{name: "Crocin", keys: []string{"paracetamol"}}
+
+	tests := []struct {
+		name     string
+		x, y     resolvedDrug
+		severity string
+	}{
+		{"later key of both drugs", warfarin, ibuprofen, "major"},
+		{"later key of both drugs reversed", ibuprofen, warfarin, "major"},
+		{"first key of one drug", clarithromycin, atorvastatin, "moderate"},
+		{"first key of one drug reversed", atorvastatin, clarithromycin, "moderate"},
+		{"no rule", warfarin, paracetamol, ""},
+		{"no rule reversed", paracetamol, warfarin, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found, ok := findInteraction(rules, test.x, test.y)
+			if ok != (test.severity != "") || found.severity != test.severity {
+				t.Errorf("findInteraction(%s, %s) = %q, %v, want %q", test.x.name, test.y.name, found.severity, ok, test.severity)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrEncounterNotFound    = errors.New("no visit found for provided ID")
+	ErrEncounterAlreadyOpen = errors.New("patient already has an open visit, close it before opening a new one")
+	ErrEncounterClosed      = errors.New("visit is closed and can no longer be changed")
+	ErrDoctorNotFound       = errors.New("doctor_id does not belong to an active doctor")
+)
+
+type encounterQueryResponse struct {
+	EncounterID    string  `json:"encounter_id"`
+	TokenID        string  `json:"token_id"`
+	PatientName    string  `json:"patient_name"`
+	DoctorID       string  `json:"doctor_id"`
+	DoctorName     string  `json:"doctor_name"`
+	VisitDate      string  `json:"visit_date"`
+	ChiefComplaint string  `json:"chief_complaint"`
+	Findings       string  `json:"findings"`
+	Treatment      string  `json:"treatment"`
+	Status         string  `json:"status"`
+	OpenedBy       string  `json:"opened_by"`
+	ClosedBy       *string `json:"closed_by"`
+	ClosedAt       *string `json:"closed_at"`
+	UpdatedAt      string  `json:"updated_at"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// Filters for listing visits, zero values match everything
+type EncounterFilter struct {
+	TokenID   string
+	DoctorID  uuid.NullUUID
+	Status    string
+	VisitDate string // YYYY-MM-DD
+}
+
+const (
+	encounterColumns = `SELECT e.encounter_id, p.token_id, p.fullname, e.doctor_id, d.fullname, to_char(e.visit_date, 'YYYY-MM-DD'), e.chief_complaint, e.findings, e.treatment,
+	e.status, e.opened_by, e.closed_by, e.closed_at, e.updated_at, e.created_at`
+	encounterFrom = ` FROM encounter e JOIN patient p ON p.patient_id=e.patient_id JOIN doctor d ON d.doctor_id=e.doctor_id `
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEncounter(row rowScanner, extra ...any) (encounterQueryResponse, error) {
+	var queryData encounterQueryResponse
+	dest := []any{&queryData.EncounterID, &queryData.TokenID, &queryData.PatientName, &queryData.DoctorID, &queryData.DoctorName, &queryData.VisitDate,
+		&queryData.ChiefComplaint, &queryData.Findings, &queryData.Treatment, &queryData.Status, &queryData.OpenedBy, &queryData.ClosedBy, &queryData.ClosedAt,
+		&queryData.UpdatedAt, &queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return queryData, err
+}
+
+// Inserts an open visit within a patient transaction
+func openEncounter(ctx context.Context, tx *sql.Tx, patientID uuid.UUID, doctorID uuid.UUID, chiefComplaint string, openedBy uuid.UUID) (uuid.UUID, error) {
+
+	var encounterID uuid.UUID
+	err := tx.QueryRowContext(ctx, "INSERT INTO encounter (patient_id, doctor_id, chief_complaint, opened_by) VALUES ($1, $2, $3, $4) RETURNING encounter_id",
+		patientID, doctorID, chiefComplaint, openedBy).Scan(&encounterID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return uuid.Nil, ErrEncounterAlreadyOpen
+		}
+		return uuid.Nil, err
+	}
+	return encounterID, nil
+}
+
+// Queries INSERT to open a visit for a returning patient.
+// The visit's doctor becomes the patient's assigned doctor.
+func (rec *Store) OpenEncounter(tokenID string, encounterReq *models.Encounter, openedBy uuid.UUID, doctorScope uuid.NullUUID) (uuid.UUID, error) {
+
+	var patientID, assignedTo uuid.UUID
+	var doctorActive bool
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT patient_id, assigned_to FROM patient WHERE token_id=$1 FOR UPDATE", tokenID).Scan(&patientID, &assignedTo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrPatientNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	doctorID := encounterReq.DoctorID
+	if doctorID == uuid.Nil {
+		doctorID = assignedTo
+	}
+	// doctors open visits only for their own patients, with themselves
+	if doctorScope.Valid && (assignedTo != doctorScope.UUID || doctorID != doctorScope.UUID) {
+		err = ErrPatientNotFound
+		return uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM doctor WHERE doctor_id=$1 AND is_active)", doctorID).Scan(&doctorActive)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !doctorActive {
+		err = ErrDoctorNotFound
+		return uuid.Nil, err
+	}
+
+	encounterID, err := openEncounter(ctx, tx, patientID, doctorID, encounterReq.ChiefComplaint, openedBy)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// patient row mirrors the current visit for existing dashboards
+	_, err = tx.ExecContext(ctx, "UPDATE patient SET assigned_to=$1, symptoms=$2, treatment='' WHERE patient_id=$3", doctorID, encounterReq.ChiefComplaint, patientID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return encounterID, nil
+}
+
+// Queries list of visits, newest first
+func (rec *Store) GetEncounters(filter EncounterFilter, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, encounterColumns+", count(*) over() as total_records"+encounterFrom+`
+		WHERE ($1 = '' OR p.token_id::text=$1) AND ($2::uuid IS NULL OR e.doctor_id=$2::uuid) AND ($3 = '' OR e.status=$3)
+		AND (NULLIF($4, '')::date IS NULL OR e.visit_date=NULLIF($4, '')::date)
+		ORDER BY e.created_at DESC LIMIT $5 OFFSET $6`,
+		filter.TokenID, filter.DoctorID, filter.Status, filter.VisitDate, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allEncounterData := make([]encounterQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanEncounter(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allEncounterData = append(allEncounterData, queryData)
+	}
+
+	responseData[0] = map[string][]encounterQueryResponse{"visits_data": allEncounterData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries a single visit
+func (rec *Store) GetEncounter(encounterID string) (encounterQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanEncounter(rec.db.QueryRowContext(ctx, encounterColumns+encounterFrom+"WHERE e.encounter_id::text=$1", encounterID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return encounterQueryResponse{}, ErrEncounterNotFound
+		}
+		return encounterQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Queries UPDATE on an open visit, restricted to visits of doctorScope when it is set.
+// Closing the visit is done by passing closedBy. Warnings the doctor accepted for the treatment are recorded with the update.
+func (rec *Store) UpdateEncounter(encounterID string, fields map[string]string, closedBy uuid.NullUUID, doctorScope uuid.NullUUID, userID uuid.UUID, warnings []models.MedicationWarning, overrideReason string) error {
+
+	var status string
+	var patientID, doctorID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT status, patient_id, doctor_id FROM encounter WHERE encounter_id::text=$1 FOR UPDATE", encounterID).Scan(&status, &patientID, &doctorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrEncounterNotFound
+		}
+		return err
+	}
+	if doctorScope.Valid && doctorID != doctorScope.UUID {
+		err = ErrEncounterNotFound
+		return err
+	}
+	if status != models.EncounterStatusOpen {
+		err = ErrEncounterClosed
+		return err
+	}
+
+	var setClauses []string
+	var args []interface{}
+
+	// sorted for a stable query text
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		switch column {
+		case "chief_complaint", "findings", "treatment":
+			args = append(args, fields[column])
+			setClauses = append(setClauses, fmt.Sprintf("%s=$%d", column, len(args)))
+		}
+	}
+	if closedBy.Valid {
+		args = append(args, models.EncounterStatusClosed, closedBy.UUID)
+		setClauses = append(setClauses, fmt.Sprintf("status=$%d, closed_by=$%d, closed_at=CURRENT_TIMESTAMP", len(args)-1, len(args)))
+	}
+	if len(setClauses) == 0 {
+		return nil
+	}
+
+	args = append(args, encounterID)
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE encounter SET %s WHERE encounter_id::text=$%d", strings.Join(setClauses, ", "), len(args)), args...)
+	if err != nil {
+		return err
+	}
+
+	// keep patient row in sync with the current visit
+	if treatment, ok := fields["treatment"]; ok {
+		_, err = tx.ExecContext(ctx, "UPDATE patient SET treatment=$1 WHERE patient_id=$2", treatment, patientID)
+	}
+	if complaint, ok := fields["chief_complaint"]; ok && err == nil {
+		_, err = tx.ExecContext(ctx, "UPDATE patient SET symptoms=$1 WHERE patient_id=$2", complaint, patientID)
+	}
+	if err != nil || len(warnings) == 0 {
+		return err
+	}
+
+	var tokenID string
+	if err = tx.QueryRowContext(ctx, "SELECT token_id::text FROM patient WHERE patient_id=$1", patientID).Scan(&tokenID); err != nil {
+		return err
+	}
+	err = recordMedicationOverride(ctx, tx, tokenID, userID, "treatment", uuid.NullUUID{}, warnings, overrideReason)
+	return err
+}
@@ -0,0 +1,384 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrHistoryRecordNotFound = errors.New("no medical history record found for provided ID")
+	ErrAllergyExists         = errors.New("allergy to this substance is already recorded")
+)
+
+type allergyQueryResponse struct {
+	AllergyID  string `json:"allergy_id"`
+	Substance  string `json:"substance"`
+	Reaction   string `json:"reaction"`
+	Severity   string `json:"severity"`
+	NotedOn    string `json:"noted_on"`
+	RecordedBy string `json:"recorded_by"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+type conditionQueryResponse struct {
+	ConditionID string `json:"condition_id"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	DiagnosedOn string `json:"diagnosed_on"`
+	ResolvedOn  string `json:"resolved_on"`
+	Notes       string `json:"notes"`
+	RecordedBy  string `json:"recorded_by"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type surgeryQueryResponse struct {
+	SurgeryID   string `json:"surgery_id"`
+	Procedure   string `json:"procedure"`
+	PerformedOn string `json:"performed_on"`
+	Hospital    string `json:"hospital"`
+	Notes       string `json:"notes"`
+	RecordedBy  string `json:"recorded_by"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type familyHistoryQueryResponse struct {
+	FamilyHistoryID string `json:"family_history_id"`
+	Relation        string `json:"relation"`
+	Condition       string `json:"condition"`
+	AgeAtOnset      *int   `json:"age_at_onset"`
+	Notes           string `json:"notes"`
+	RecordedBy      string `json:"recorded_by"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+type medicalHistoryResponse struct {
+	Allergies     []allergyQueryResponse       `json:"allergies"`
+	Conditions    []conditionQueryResponse     `json:"conditions"`
+	Surgeries     []surgeryQueryResponse       `json:"surgeries"`
+	FamilyHistory []familyHistoryQueryResponse `json:"family_history"`
+}
+
+// Returns table and primary key column backing a kind of history record
+func historyTable(recordType string) (string, string, error) {
+	switch recordType {
+	case models.HistoryAllergies:
+		return "patient_allergy", "allergy_id", nil
+	case models.HistoryConditions:
+		return "patient_condition", "condition_id", nil
+	case models.HistorySurgeries:
+		return "patient_surgery", "surgery_id", nil
+	case models.HistoryFamilyHistory:
+		return "patient_family_history", "family_history_id", nil
+	}
+	return "", "", fmt.Errorf("unknown medical history record type %q", recordType)
+}
+
+// Maps unique violations on allergies and missing rows to store errors
+func historyError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrHistoryRecordNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrAllergyExists
+	}
+	return err
+}
+
+// Date columns are read as YYYY-MM-DD, empty when unknown
+const historyDate = "COALESCE(to_char(%s, 'YYYY-MM-DD'), '')"
+
+func (rec *Store) getAllergies(ctx context.Context, patientID uuid.UUID) ([]allergyQueryResponse, error) {
+
+	rows, err := rec.db.QueryContext(ctx, fmt.Sprintf("SELECT allergy_id, substance, reaction, severity, %s, recorded_by, created_at, updated_at FROM patient_allergy WHERE patient_id=$1 ORDER BY created_at", fmt.Sprintf(historyDate, "noted_on")), patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allergies := make([]allergyQueryResponse, 0)
+	for rows.Next() {
+		var queryData allergyQueryResponse
+		if err = rows.Scan(&queryData.AllergyID, &queryData.Substance, &queryData.Reaction, &queryData.Severity, &queryData.NotedOn, &queryData.RecordedBy, &queryData.CreatedAt, &queryData.UpdatedAt); err != nil {
+			return nil, err
+		}
+		allergies = append(allergies, queryData)
+	}
+	return allergies, nil
+}
+
+func (rec *Store) getConditions(ctx context.Context, patientID uuid.UUID) ([]conditionQueryResponse, error) {
+
+	rows, err := rec.db.QueryContext(ctx, fmt.Sprintf("SELECT condition_id, name, status, %s, %s, notes, recorded_by, created_at, updated_at FROM patient_condition WHERE patient_id=$1 ORDER BY status='resolved', diagnosed_on DESC NULLS LAST, created_at",
+		fmt.Sprintf(historyDate, "diagnosed_on"), fmt.Sprintf(historyDate, "resolved_on")), patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conditions := make([]conditionQueryResponse, 0)
+	for rows.Next() {
+		var queryData conditionQueryResponse
+		if err = rows.Scan(&queryData.ConditionID, &queryData.Name, &queryData.Status, &queryData.DiagnosedOn, &queryData.ResolvedOn, &queryData.Notes, &queryData.RecordedBy, &queryData.CreatedAt, &queryData.UpdatedAt); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, queryData)
+	}
+	return conditions, nil
+}
+
+func (rec *Store) getSurgeries(ctx context.Context, patientID uuid.UUID) ([]surgeryQueryResponse, error) {
+
+	rows, err := rec.db.QueryContext(ctx, fmt.Sprintf("SELECT surgery_id, procedure, %s, hospital, notes, recorded_by, created_at, updated_at FROM patient_surgery WHERE patient_id=$1 ORDER BY performed_on DESC NULLS LAST, created_at",
+		fmt.Sprintf(historyDate, "performed_on")), patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	surgeries := make([]surgeryQueryResponse, 0)
+	for rows.Next() {
+		var queryData surgeryQueryResponse
+		if err = rows.Scan(&queryData.SurgeryID, &queryData.Procedure, &queryData.PerformedOn, &queryData.Hospital, &queryData.Notes, &queryData.RecordedBy, &queryData.CreatedAt, &queryData.UpdatedAt); err != nil {
+			return nil, err
+		}
+		surgeries = append(surgeries, queryData)
+	}
+	return surgeries, nil
+}
+
+func (rec *Store) getFamilyHistory(ctx context.Context, patientID uuid.UUID) ([]familyHistoryQueryResponse, error) {
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT family_history_id, relation, condition, age_at_onset, notes, recorded_by, created_at, updated_at FROM patient_family_history WHERE patient_id=$1 ORDER BY created_at", patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	familyHistory := make([]familyHistoryQueryResponse, 0)
+	for rows.Next() {
+		var queryData familyHistoryQueryResponse
+		var ageAtOnset sql.NullInt32
+		if err = rows.Scan(&queryData.FamilyHistoryID, &queryData.Relation, &queryData.Condition, &ageAtOnset, &queryData.Notes, &queryData.RecordedBy, &queryData.CreatedAt, &queryData.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if ageAtOnset.Valid {
+			age := int(ageAtOnset.Int32)
+			queryData.AgeAtOnset = &age
+		}
+		familyHistory = append(familyHistory, queryData)
+	}
+	return familyHistory, nil
+}
+
+// Resolves the patient of a token ID for history queries
+func (rec *Store) historyPatientID(ctx context.Context, tokenID string) (uuid.UUID, error) {
+
+	var patientID uuid.UUID
+	err := rec.db.QueryRowContext(ctx, "SELECT patient_id FROM patient WHERE token_id::text=$1", tokenID).Scan(&patientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrPatientNotFound
+	}
+	return patientID, err
+}
+
+// Queries allergies, conditions, surgeries and family history of a patient
+func (rec *Store) GetMedicalHistory(tokenID string) (medicalHistoryResponse, error) {
+
+	var history medicalHistoryResponse
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	patientID, err := rec.historyPatientID(ctx, tokenID)
+	if err != nil {
+		return history, err
+	}
+
+	if history.Allergies, err = rec.getAllergies(ctx, patientID); err != nil {
+		return history, err
+	}
+	if history.Conditions, err = rec.getConditions(ctx, patientID); err != nil {
+		return history, err
+	}
+	if history.Surgeries, err = rec.getSurgeries(ctx, patientID); err != nil {
+		return history, err
+	}
+	if history.FamilyHistory, err = rec.getFamilyHistory(ctx, patientID); err != nil {
+		return history, err
+	}
+	return history, nil
+}
+
+// Queries one kind of history records of a patient
+func (rec *Store) GetHistoryRecords(recordType string, tokenID string) (interface{}, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	patientID, err := rec.historyPatientID(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch recordType {
+	case models.HistoryAllergies:
+		return rec.getAllergies(ctx, patientID)
+	case models.HistoryConditions:
+		return rec.getConditions(ctx, patientID)
+	case models.HistorySurgeries:
+		return rec.getSurgeries(ctx, patientID)
+	case models.HistoryFamilyHistory:
+		return rec.getFamilyHistory(ctx, patientID)
+	}
+	_, _, err = historyTable(recordType)
+	return nil, err
+}
+
+// Queries INSERT to record an allergy of a patient
+func (rec *Store) AddAllergy(tokenID string, allergyReq *models.Allergy, recordedBy uuid.UUID) (uuid.UUID, error) {
+
+	var allergyID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "INSERT INTO patient_allergy (patient_id, substance, reaction, severity, noted_on, recorded_by) SELECT patient_id, $2, $3, $4, NULLIF($5, '')::date, $6 FROM patient WHERE token_id::text=$1 RETURNING allergy_id",
+		tokenID, allergyReq.Substance, allergyReq.Reaction, allergyReq.Severity, allergyReq.NotedOn, recordedBy).Scan(&allergyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrPatientNotFound
+	}
+	if err != nil {
+		return uuid.Nil, historyError(err)
+	}
+	return allergyID, nil
+}
+
+// Queries UPDATE to correct an allergy of a patient
+func (rec *Store) UpdateAllergy(tokenID string, allergyID string, allergyReq *models.Allergy) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `UPDATE patient_allergy a SET substance=$3, reaction=$4, severity=$5, noted_on=NULLIF($6, '')::date FROM patient p
+		WHERE p.patient_id=a.patient_id AND p.token_id::text=$1 AND a.allergy_id::text=$2 RETURNING a.allergy_id`,
+		tokenID, allergyID, allergyReq.Substance, allergyReq.Reaction, allergyReq.Severity, allergyReq.NotedOn).Scan(new(uuid.UUID))
+	return historyError(err)
+}
+
+// Queries INSERT to record a chronic or past condition of a patient
+func (rec *Store) AddCondition(tokenID string, conditionReq *models.Condition, recordedBy uuid.UUID) (uuid.UUID, error) {
+
+	var conditionID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `INSERT INTO patient_condition (patient_id, name, status, diagnosed_on, resolved_on, notes, recorded_by)
+		SELECT patient_id, $2, $3, NULLIF($4, '')::date, NULLIF($5, '')::date, $6, $7 FROM patient WHERE token_id::text=$1 RETURNING condition_id`,
+		tokenID, conditionReq.Name, conditionReq.Status, conditionReq.DiagnosedOn, conditionReq.ResolvedOn, conditionReq.Notes, recordedBy).Scan(&conditionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrPatientNotFound
+	}
+	return conditionID, err
+}
+
+// Queries UPDATE to change a condition of a patient
+func (rec *Store) UpdateCondition(tokenID string, conditionID string, conditionReq *models.Condition) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `UPDATE patient_condition c SET name=$3, status=$4, diagnosed_on=NULLIF($5, '')::date, resolved_on=NULLIF($6, '')::date, notes=$7 FROM patient p
+		WHERE p.patient_id=c.patient_id AND p.token_id::text=$1 AND c.condition_id::text=$2 RETURNING c.condition_id`,
+		tokenID, conditionID, conditionReq.Name, conditionReq.Status, conditionReq.DiagnosedOn, conditionReq.ResolvedOn, conditionReq.Notes).Scan(new(uuid.UUID))
+	return historyError(err)
+}
+
+// Queries INSERT to record a past surgery of a patient
+func (rec *Store) AddSurgery(tokenID string, surgeryReq *models.Surgery, recordedBy uuid.UUID) (uuid.UUID, error) {
+
+	var surgeryID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `INSERT INTO patient_surgery (patient_id, procedure, performed_on, hospital, notes, recorded_by)
+		SELECT patient_id, $2, NULLIF($3, '')::date, $4, $5, $6 FROM patient WHERE token_id::text=$1 RETURNING surgery_id`,
+		tokenID, surgeryReq.Procedure, surgeryReq.PerformedOn, surgeryReq.Hospital, surgeryReq.Notes, recordedBy).Scan(&surgeryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrPatientNotFound
+	}
+	return surgeryID, err
+}
+
+// Queries UPDATE to change a past surgery of a patient
+func (rec *Store) UpdateSurgery(tokenID string, surgeryID string, surgeryReq *models.Surgery) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `UPDATE patient_surgery s SET procedure=$3, performed_on=NULLIF($4, '')::date, hospital=$5, notes=$6 FROM patient p
+		WHERE p.patient_id=s.patient_id AND p.token_id::text=$1 AND s.surgery_id::text=$2 RETURNING s.surgery_id`,
+		tokenID, surgeryID, surgeryReq.Procedure, surgeryReq.PerformedOn, surgeryReq.Hospital, surgeryReq.Notes).Scan(new(uuid.UUID))
+	return historyError(err)
+}
+
+// Queries INSERT to record a condition of a patient's relative
+func (rec *Store) AddFamilyHistory(tokenID string, familyReq *models.FamilyHistory, recordedBy uuid.UUID) (uuid.UUID, error) {
+
+	var familyHistoryID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `INSERT INTO patient_family_history (patient_id, relation, condition, age_at_onset, notes, recorded_by)
+		SELECT patient_id, $2, $3, $4, $5, $6 FROM patient WHERE token_id::text=$1 RETURNING family_history_id`,
+		tokenID, familyReq.Relation, familyReq.Condition, familyReq.AgeAtOnset, familyReq.Notes, recordedBy).Scan(&familyHistoryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrPatientNotFound
+	}
+	return familyHistoryID, err
+}
+
+// Queries UPDATE to change a family history record of a patient
+func (rec *Store) UpdateFamilyHistory(tokenID string, familyHistoryID string, familyReq *models.FamilyHistory) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, `UPDATE patient_family_history f SET relation=$3, condition=$4, age_at_onset=$5, notes=$6 FROM patient p
+		WHERE p.patient_id=f.patient_id AND p.token_id::text=$1 AND f.family_history_id::text=$2 RETURNING f.family_history_id`,
+		tokenID, familyHistoryID, familyReq.Relation, familyReq.Condition, familyReq.AgeAtOnset, familyReq.Notes).Scan(new(uuid.UUID))
+	return historyError(err)
+}
+
+// Queries DELETE to remove a history record entered in error
+func (rec *Store) DeleteHistoryRecord(recordType string, tokenID string, recordID string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := historyTable(recordType)
+	if err != nil {
+		return err
+	}
+
+	result, err := rec.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s h USING patient p WHERE p.patient_id=h.patient_id AND p.token_id::text=$1 AND h.%s::text=$2", table, idColumn), tokenID, recordID)
+	if err != nil {
+		return err
+	}
+	if rowAffected, err := result.RowsAffected(); err != nil || rowAffected == 0 {
+		if err == nil {
+			err = ErrHistoryRecordNotFound
+		}
+		return err
+	}
+	return nil
+}
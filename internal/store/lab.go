@@ -0,0 +1,318 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrLabOrderNotFound        = errors.New("no lab order found for provided ID")
+	ErrLabOrderStatus          = errors.New("lab order is not in the required status")
+	ErrAbnormalNotAcknowledged = errors.New("lab order has abnormal results, set acknowledge_abnormal to review it")
+)
+
+type labOrderQueryResponse struct {
+	LabOrderID           string             `json:"lab_order_id"`
+	TokenID              string             `json:"token_id"`
+	PatientName          string             `json:"patient_name"`
+	DoctorID             string             `json:"doctor_id"`
+	DoctorName           string             `json:"doctor_name"`
+	EncounterID          *string            `json:"encounter_id"`
+	Panel                string             `json:"panel"`
+	Priority             string             `json:"priority"`
+	Status               string             `json:"status"`
+	Notes                string             `json:"notes"`
+	Abnormal             bool               `json:"abnormal"`
+	AbnormalAcknowledged bool               `json:"abnormal_acknowledged"`
+	ReviewNote           string             `json:"review_note"`
+	CollectedAt          *string            `json:"collected_at"`
+	ResultedAt           *string            `json:"resulted_at"`
+	ReviewedAt           *string            `json:"reviewed_at"`
+	CreatedAt            string             `json:"created_at"`
+	Results              []models.LabResult `json:"results"`
+}
+
+// Filters for lab order lists, empty fields are not applied
+type LabOrderFilter struct {
+	TokenID  string
+	DoctorID uuid.NullUUID
+	Status   string
+	Abnormal bool // only orders with results outside reference ranges
+}
+
+const (
+	labOrderColumns = `SELECT o.lab_order_id, p.token_id, p.fullname, o.doctor_id, d.fullname, o.encounter_id, o.panel, o.priority, o.status, o.notes,
+	EXISTS (SELECT 1 FROM lab_result r WHERE r.lab_order_id=o.lab_order_id AND r.flag<>'normal'), o.abnormal_acknowledged, o.review_note,
+	o.collected_at, o.resulted_at, o.reviewed_at, o.created_at`
+	labOrderFrom = ` FROM lab_order o JOIN patient p ON p.patient_id=o.patient_id JOIN doctor d ON d.doctor_id=o.doctor_id `
+)
+
+func scanLabOrder(row rowScanner, extra ...any) (labOrderQueryResponse, error) {
+	var queryData labOrderQueryResponse
+	dest := []any{&queryData.LabOrderID, &queryData.TokenID, &queryData.PatientName, &queryData.DoctorID, &queryData.DoctorName, &queryData.EncounterID,
+		&queryData.Panel, &queryData.Priority, &queryData.Status, &queryData.Notes, &queryData.Abnormal, &queryData.AbnormalAcknowledged, &queryData.ReviewNote,
+		&queryData.CollectedAt, &queryData.ResultedAt, &queryData.ReviewedAt, &queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	queryData.Results = []models.LabResult{}
+	return queryData, err
+}
+
+// Reads results of the listed lab orders in line order
+func (rec *Store) attachLabResults(ctx context.Context, orders []labOrderQueryResponse) error {
+
+	if len(orders) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(orders))
+	ids := make([]string, 0, len(orders))
+	for i, order := range orders {
+		index[order.LabOrderID] = i
+		ids = append(ids, order.LabOrderID)
+	}
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT lab_order_id, analyte, value, unit, ref_low, ref_high, flag FROM lab_result WHERE lab_order_id::text = ANY($1) ORDER BY lab_order_id, line_no", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var labOrderID string
+		var result models.LabResult
+		if err = rows.Scan(&labOrderID, &result.Analyte, &result.Value, &result.Unit, &result.RefLow, &result.RefHigh, &result.Flag); err != nil {
+			return err
+		}
+		i := index[labOrderID]
+		orders[i].Results = append(orders[i].Results, result)
+	}
+	return nil
+}
+
+// Queries INSERT to order a test panel for one of the doctor's patients, linked to the patient's open visit when there is one
+func (rec *Store) CreateLabOrder(tokenID string, labOrderReq *models.LabOrder, doctorID uuid.UUID) (uuid.UUID, error) {
+
+	var labOrderID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// doctors order tests only for their own patients
+	err := rec.db.QueryRowContext(ctx, `INSERT INTO lab_order (patient_id, doctor_id, encounter_id, panel, priority, notes)
+		SELECT p.patient_id, $2, (SELECT encounter_id FROM encounter e WHERE e.patient_id=p.patient_id AND e.status='open'), $3, $4, $5
+		FROM patient p WHERE p.token_id::text=$1 AND p.assigned_to=$2 RETURNING lab_order_id`,
+		tokenID, doctorID, labOrderReq.Panel, labOrderReq.Priority, labOrderReq.Notes).Scan(&labOrderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrPatientNotFound
+		}
+		return uuid.Nil, err
+	}
+	return labOrderID, nil
+}
+
+// Queries lab orders with their results, most urgent and newest first
+func (rec *Store) GetLabOrders(filter LabOrderFilter, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, labOrderColumns+", count(*) over() as total_records"+labOrderFrom+`WHERE ($1 = '' OR p.token_id::text=$1) AND ($2::uuid IS NULL OR o.doctor_id=$2)
+		AND ($3 = '' OR o.status=$3) AND (NOT $4 OR EXISTS (SELECT 1 FROM lab_result r WHERE r.lab_order_id=o.lab_order_id AND r.flag<>'normal'))
+		ORDER BY CASE o.priority WHEN 'stat' THEN 0 WHEN 'urgent' THEN 1 ELSE 2 END, o.created_at DESC LIMIT $5 OFFSET $6`,
+		filter.TokenID, filter.DoctorID, filter.Status, filter.Abnormal, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allLabOrderData := make([]labOrderQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanLabOrder(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allLabOrderData = append(allLabOrderData, queryData)
+	}
+	rows.Close()
+
+	if err = rec.attachLabResults(ctx, allLabOrderData); err != nil {
+		return nil, err
+	}
+
+	responseData[0] = map[string][]labOrderQueryResponse{"lab_orders_data": allLabOrderData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries a single lab order with its results
+func (rec *Store) GetLabOrder(labOrderID string) (labOrderQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanLabOrder(rec.db.QueryRowContext(ctx, labOrderColumns+labOrderFrom+"WHERE o.lab_order_id::text=$1", labOrderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return labOrderQueryResponse{}, ErrLabOrderNotFound
+		}
+		return labOrderQueryResponse{}, err
+	}
+
+	orders := []labOrderQueryResponse{queryData}
+	if err = rec.attachLabResults(ctx, orders); err != nil {
+		return labOrderQueryResponse{}, err
+	}
+	return orders[0], nil
+}
+
+// Queries UPDATE to mark the sample of an ordered test as collected
+func (rec *Store) CollectLabOrder(labOrderID string, collectedBy uuid.UUID) error {
+
+	var collected int
+	var status sql.NullString
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// current sees the row as it was before the update
+	err := rec.db.QueryRowContext(ctx, `WITH current AS (SELECT status FROM lab_order WHERE lab_order_id::text=$1),
+		collected AS (UPDATE lab_order SET status='collected', collected_by=$2, collected_at=CURRENT_TIMESTAMP WHERE lab_order_id::text=$1 AND status='ordered' RETURNING lab_order_id)
+		SELECT (SELECT count(*) FROM collected), (SELECT status FROM current)`, labOrderID, collectedBy).Scan(&collected, &status)
+	if err != nil {
+		return err
+	}
+	if collected == 1 {
+		return nil
+	}
+	if !status.Valid {
+		return ErrLabOrderNotFound
+	}
+	return fmt.Errorf("%w, order is %s", ErrLabOrderStatus, status.String)
+}
+
+// Queries INSERT to record results of a collected sample, results are flagged against their reference ranges
+func (rec *Store) RecordLabResults(labOrderID string, results []models.LabResult, resultedBy uuid.UUID) ([]models.LabResult, error) {
+
+	var status string
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT status FROM lab_order WHERE lab_order_id::text=$1 FOR UPDATE", labOrderID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrLabOrderNotFound
+		}
+		return nil, err
+	}
+	if status != models.LabStatusCollected {
+		err = fmt.Errorf("%w, order is %s", ErrLabOrderStatus, status)
+		return nil, err
+	}
+
+	flagged := make([]models.LabResult, 0, len(results))
+	for i, result := range results {
+		result.Flag = models.FlagLabResult(result)
+		_, err = tx.ExecContext(ctx, "INSERT INTO lab_result (lab_order_id, line_no, analyte, value, unit, ref_low, ref_high, flag) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			labOrderID, i+1, result.Analyte, result.Value, result.Unit, result.RefLow, result.RefHigh, result.Flag)
+		if err != nil {
+			return nil, err
+		}
+		flagged = append(flagged, result)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE lab_order SET status='resulted', resulted_by=$2, resulted_at=CURRENT_TIMESTAMP WHERE lab_order_id::text=$1", labOrderID, resultedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return flagged, nil
+}
+
+// Queries UPDATE to record the ordering doctor's review of results, abnormal results must be acknowledged
+func (rec *Store) ReviewLabOrder(labOrderID string, reviewReq *models.LabReview, doctorID uuid.UUID) error {
+
+	var status string
+	var orderedBy uuid.UUID
+	var abnormal bool
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, `SELECT status, doctor_id, EXISTS (SELECT 1 FROM lab_result r WHERE r.lab_order_id=o.lab_order_id AND r.flag<>'normal')
+		FROM lab_order o WHERE lab_order_id::text=$1 FOR UPDATE`, labOrderID).Scan(&status, &orderedBy, &abnormal)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrLabOrderNotFound
+		}
+		return err
+	}
+	// only the ordering doctor reviews results
+	if orderedBy != doctorID {
+		err = ErrLabOrderNotFound
+		return err
+	}
+	if status != models.LabStatusResulted {
+		err = fmt.Errorf("%w, order is %s", ErrLabOrderStatus, status)
+		return err
+	}
+	if abnormal && !reviewReq.AcknowledgeAbnormal {
+		err = ErrAbnormalNotAcknowledged
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE lab_order SET status='reviewed', reviewed_at=CURRENT_TIMESTAMP, abnormal_acknowledged=$2, review_note=$3 WHERE lab_order_id::text=$1",
+		labOrderID, abnormal, reviewReq.Note)
+	return err
+}
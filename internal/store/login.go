@@ -9,6 +9,8 @@ import (
 	"github.com/harshitrajsinha/medi-go/internal/models"
 )
 
+var ErrAccountNotFound = errors.New("no active account found for credentials")
+
 func (rec *Store) GetLoginInfo(loginReq *models.Credentials) (LoginResponse, error) {
 
 	loginResponse := LoginResponse{}
@@ -17,15 +19,17 @@ func (rec *Store) GetLoginInfo(loginReq *models.Credentials) (LoginResponse, err
 	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
 	defer cancel()
 
-	if loginReq.Role == "doctor" {
-		err = rec.db.QueryRowContext(ctx, "SELECT doctor_id, password_hash FROM doctor WHERE role='doctor' AND email=$1", loginReq.Email).Scan(&loginResponse.UserID, &loginResponse.HashedPassword)
+	if loginReq.Role == models.RoleDoctor {
+		err = rec.db.QueryRowContext(ctx, "SELECT doctor_id, password_hash, role, mfa_required, EXISTS (SELECT 1 FROM user_mfa WHERE user_id=doctor_id AND enabled) FROM doctor WHERE role='doctor' AND is_active AND email=$1", loginReq.Email).Scan(&loginResponse.UserID, &loginResponse.HashedPassword, &loginResponse.Role, &loginResponse.MFARequired, &loginResponse.MFAEnabled)
+	} else if loginReq.Role == models.RoleAdmin {
+		err = rec.db.QueryRowContext(ctx, "SELECT staff_id, password_hash, role, mfa_required, EXISTS (SELECT 1 FROM user_mfa WHERE user_id=staff_id AND enabled) FROM staff WHERE role='admin' AND is_active AND email=$1", loginReq.Email).Scan(&loginResponse.UserID, &loginResponse.HashedPassword, &loginResponse.Role, &loginResponse.MFARequired, &loginResponse.MFAEnabled)
 	} else {
-		err = rec.db.QueryRowContext(ctx, "SELECT staff_id, password_hash FROM staff WHERE role='receptionist' AND email=$1", loginReq.Email).Scan(&loginResponse.UserID, &loginResponse.HashedPassword)
+		err = rec.db.QueryRowContext(ctx, "SELECT staff_id, password_hash, role, mfa_required, EXISTS (SELECT 1 FROM user_mfa WHERE user_id=staff_id AND enabled) FROM staff WHERE role='receptionist' AND is_active AND email=$1", loginReq.Email).Scan(&loginResponse.UserID, &loginResponse.HashedPassword, &loginResponse.Role, &loginResponse.MFARequired, &loginResponse.MFAEnabled)
 	}
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return loginResponse, errors.New("no data found based on request") // return empty model
+			return loginResponse, ErrAccountNotFound // return empty model
 		}
 		return loginResponse, err // return empty model
 	}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// Failed login policy, counters reset after failureWindow without failures
+const (
+	failureWindow = 15 * time.Minute
+	maxLoginDelay = 60 * time.Second
+	lockoutPeriod = 30 * time.Minute
+
+	emailDelayAfter = 3  // failures per email before delays start
+	emailLockAfter  = 10 // failures per email before the account is locked
+	ipDelayAfter    = 10 // failures per client IP before delays start
+	ipLockAfter     = 50 // failures per client IP before the IP is locked
+)
+
+type LoginThrottle struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+type loginAttemptResponse struct {
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	ClientIP  string `json:"client_ip"`
+	Succeeded bool   `json:"succeeded"`
+	Reason    string `json:"reason,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type loginLockoutResponse struct {
+	Subject      string `json:"subject"`
+	FailedCount  int    `json:"failed_count"`
+	LastFailedAt string `json:"last_failed_at"`
+	BlockedUntil string `json:"blocked_until"`
+	Locked       bool   `json:"locked"`
+}
+
+func emailSubject(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func ipSubject(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// Returns how long a subject is blocked for after failures, doubling from one second up to maxLoginDelay
+func throttleDelay(failures int, delayAfter int, lockAfter int) (time.Duration, bool) {
+	if failures >= lockAfter {
+		return lockoutPeriod, true
+	}
+	if failures < delayAfter {
+		return 0, false
+	}
+	// capped before converting, large powers overflow time.Duration
+	seconds := math.Min(math.Pow(2, float64(failures-delayAfter)), maxLoginDelay.Seconds())
+	return time.Duration(seconds) * time.Second, false
+}
+
+// Queries whether login for email from client IP is currently delayed or locked
+func (rec *Store) CheckLoginThrottle(email string, clientIP string) (LoginThrottle, error) {
+
+	var throttle LoginThrottle
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT blocked_until, locked FROM login_throttle WHERE subject IN ($1, $2) AND blocked_until > CURRENT_TIMESTAMP", emailSubject(email), ipSubject(clientIP))
+	if err != nil {
+		return throttle, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blockedUntil time.Time
+		var locked bool
+		if err = rows.Scan(&blockedUntil, &locked); err != nil {
+			return throttle, err
+		}
+		if retryAfter := time.Until(blockedUntil); retryAfter > throttle.RetryAfter {
+			throttle.RetryAfter = retryAfter
+		}
+		throttle.Locked = throttle.Locked || locked
+	}
+
+	return throttle, rows.Err()
+}
+
+// Queries INSERT of failed attempt and bumps the counters of email and client IP
+func (rec *Store) RecordLoginFailure(email string, role string, clientIP string, reason string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO login_attempt (email, role, client_ip, succeeded, reason) VALUES ($1, $2, $3, FALSE, $4)", strings.ToLower(email), role, clientIP, reason)
+	if err != nil {
+		return err
+	}
+
+	if err = bumpFailures(ctx, tx, emailSubject(email), emailDelayAfter, emailLockAfter); err != nil {
+		return err
+	}
+	err = bumpFailures(ctx, tx, ipSubject(clientIP), ipDelayAfter, ipLockAfter)
+	return err
+}
+
+func bumpFailures(ctx context.Context, tx *sql.Tx, subject string, delayAfter int, lockAfter int) error {
+
+	var failures int
+
+	// counters start over once the previous failure falls out of the window
+	err := tx.QueryRowContext(ctx, `INSERT INTO login_throttle (subject, failed_count, last_failed_at) VALUES ($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (subject) DO UPDATE SET
+			failed_count = CASE WHEN login_throttle.last_failed_at < CURRENT_TIMESTAMP - make_interval(secs => $2) THEN 1 ELSE login_throttle.failed_count + 1 END,
+			last_failed_at = CURRENT_TIMESTAMP
+		RETURNING failed_count`, subject, failureWindow.Seconds()).Scan(&failures)
+	if err != nil {
+		return err
+	}
+
+	delay, locked := throttleDelay(failures, delayAfter, lockAfter)
+	if delay == 0 {
+		return nil
+	}
+	if locked {
+		log.Printf("Login locked for %s after %d failed attempts", subject, failures)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE login_throttle SET blocked_until = CURRENT_TIMESTAMP + make_interval(secs => $2), locked = $3 WHERE subject=$1", subject, delay.Seconds(), locked)
+	return err
+}
+
+// Queries INSERT of successful attempt and clears the failure counter of email
+func (rec *Store) RecordLoginSuccess(email string, role string, clientIP string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	_, err := rec.db.ExecContext(ctx, "INSERT INTO login_attempt (email, role, client_ip, succeeded) VALUES ($1, $2, $3, TRUE)", strings.ToLower(email), role, clientIP)
+	if err != nil {
+		return err
+	}
+
+	// the client IP counter is kept so one valid account cannot reset it
+	_, err = rec.db.ExecContext(ctx, "DELETE FROM login_throttle WHERE subject=$1", emailSubject(email))
+	return err
+}
+
+// Queries list of login attempts filtered by email, client IP and outcome
+func (rec *Store) GetLoginAttempts(email string, clientIP string, failedOnly bool, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT email, role, client_ip, succeeded, reason, created_at, count(*) over() as total_records FROM login_attempt WHERE ($1::text = '' OR email = lower($1::text)) AND ($2::text = '' OR client_ip = $2::text) AND (NOT $3::boolean OR NOT succeeded) ORDER BY created_at DESC LIMIT $4 OFFSET $5",
+		email, clientIP, failedOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allAttemptData := make([]loginAttemptResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	for rows.Next() {
+		var queryData loginAttemptResponse
+		err = rows.Scan(&queryData.Email, &queryData.Role, &queryData.ClientIP, &queryData.Succeeded, &queryData.Reason, &queryData.CreatedAt, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allAttemptData = append(allAttemptData, queryData)
+	}
+
+	responseData[0] = map[string][]loginAttemptResponse{"login_attempts": allAttemptData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries emails and client IPs that are currently delayed or locked
+func (rec *Store) GetLoginLockouts() (interface{}, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT subject, failed_count, last_failed_at, blocked_until, locked FROM login_throttle WHERE blocked_until > CURRENT_TIMESTAMP ORDER BY blocked_until DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allLockoutData := make([]loginLockoutResponse, 0)
+	for rows.Next() {
+		var queryData loginLockoutResponse
+		err = rows.Scan(&queryData.Subject, &queryData.FailedCount, &queryData.LastFailedAt, &queryData.BlockedUntil, &queryData.Locked)
+		if err != nil {
+			return nil, err
+		}
+		allLockoutData = append(allLockoutData, queryData)
+	}
+
+	return map[string][]loginLockoutResponse{"lockouts": allLockoutData}, nil
+}
+
+// Queries DELETE to clear failure counters of an email and/or client IP
+func (rec *Store) UnlockLogin(email string, clientIP string) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if email == "" && clientIP == "" {
+		return 0, errors.New("email or client IP is required to unlock login")
+	}
+
+	result, err := rec.db.ExecContext(ctx, "DELETE FROM login_throttle WHERE subject IN ($1, $2)", emailSubject(email), ipSubject(clientIP))
+	if err != nil {
+		return -1, fmt.Errorf("unlock login: %w", err)
+	}
+
+	return result.RowsAffected()
+}
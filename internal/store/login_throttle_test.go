@@ -0,0 +1,39 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleDelay(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		failures   int
+		delayAfter int
+		lockAfter  int
+		delay      time.Duration
+		locked     bool
+	}{
+		{"email below delay threshold", 2, emailDelayAfter, emailLockAfter, 0, false},
+		{"email at delay threshold", 3, emailDelayAfter, emailLockAfter, time.Second, false},
+		{"email doubles", 5, emailDelayAfter, emailLockAfter, 4 * time.Second, false},
+		{"email before lock", 9, emailDelayAfter, emailLockAfter, maxLoginDelay, false},
+		{"email at lock threshold", 10, emailDelayAfter, emailLockAfter, lockoutPeriod, true},
+		{"ip below delay threshold", 9, ipDelayAfter, ipLockAfter, 0, false},
+		{"ip at delay threshold", 10, ipDelayAfter, ipLockAfter, time.Second, false},
+		{"ip capped delay", 20, ipDelayAfter, ipLockAfter, maxLoginDelay, false},
+		{"ip before lock", 49, ipDelayAfter, ipLockAfter, maxLoginDelay, false},
+		{"ip at lock threshold", 50, ipDelayAfter, ipLockAfter, lockoutPeriod, true},
+		{"ip past lock threshold", 51, ipDelayAfter, ipLockAfter, lockoutPeriod, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delay, locked := throttleDelay(test.failures, test.delayAfter, test.lockAfter)
+			if delay != test.delay || locked != test.locked {
+				t.Errorf("throttleDelay(%d, %d, %d) = %v, %v, want %v, %v", test.failures, test.delayAfter, test.lockAfter, delay, locked, test.delay, test.locked)
+			}
+		})
+	}
+}
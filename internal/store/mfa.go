@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFANotEnrolled     = errors.New("two-factor authentication is not enrolled")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFARequiredByAdmin = errors.New("two-factor authentication is required for this account")
+	ErrTOTPReplayed       = errors.New("code was already used, wait for the next one")
+)
+
+type MFASecret struct {
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+// Queries INSERT of a new, not yet confirmed TOTP secret replacing any pending one
+func (rec *Store) StartMFAEnrollment(userID uuid.UUID, secret string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret, last_used_step=0, created_at=CURRENT_TIMESTAMP WHERE NOT user_mfa.enabled", userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rowAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowAffected == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// Queries TOTP secret of a user
+func (rec *Store) GetMFASecret(userID uuid.UUID) (MFASecret, error) {
+
+	var mfaSecret MFASecret
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "SELECT secret, enabled, last_used_step FROM user_mfa WHERE user_id=$1", userID).Scan(&mfaSecret.Secret, &mfaSecret.Enabled, &mfaSecret.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mfaSecret, ErrMFANotEnrolled
+		}
+		return mfaSecret, err
+	}
+	return mfaSecret, nil
+}
+
+// Queries UPDATE to remember the last accepted time step, a step can only be used once
+func (rec *Store) MarkTOTPStepUsed(userID uuid.UUID, step int64) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "UPDATE user_mfa SET last_used_step=$2 WHERE user_id=$1 AND last_used_step < $2", userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowAffected == 0 {
+		return ErrTOTPReplayed
+	}
+	return nil
+}
+
+// Enables pending TOTP secret and stores a fresh set of recovery codes
+func (rec *Store) ConfirmMFAEnrollment(userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, "UPDATE user_mfa SET enabled=TRUE, enabled_at=CURRENT_TIMESTAMP, last_used_step=$2 WHERE user_id=$1 AND NOT enabled", userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowAffected == 0 {
+		err = ErrMFAAlreadyEnabled
+		return err
+	}
+
+	err = replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes)
+	return err
+}
+
+// Replaces every recovery code of a user with a fresh set
+func (rec *Store) ReplaceRecoveryCodes(userID uuid.UUID, recoveryCodeHashes []string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Println("Transaction rollback error: ", rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, recoveryCodeHashes []string) error {
+
+	_, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_code WHERE user_id=$1", userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO mfa_recovery_code (code_hash, user_id) VALUES ($1, $2)", codeHash, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Queries UPDATE to consume a recovery code, returns false if it is unknown or already used
+func (rec *Store) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "UPDATE mfa_recovery_code SET used_at=CURRENT_TIMESTAMP WHERE code_hash=$1 AND user_id=$2 AND used_at IS NULL", codeHash, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowAffected, err := result.RowsAffected()
+	return rowAffected > 0, err
+}
+
+// Queries DELETE of TOTP secret and recovery codes of a user
+func (rec *Store) DisableMFA(userID uuid.UUID) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "DELETE FROM user_mfa WHERE user_id=$1", userID)
+	if err != nil {
+		return -1, err
+	}
+	return result.RowsAffected()
+}
+
+// Queries whether an admin requires two-factor authentication for the user
+func (rec *Store) IsMFARequired(userID uuid.UUID) (bool, error) {
+
+	var required bool
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM doctor WHERE doctor_id=$1 AND mfa_required UNION ALL SELECT 1 FROM staff WHERE staff_id=$1 AND mfa_required)", userID).Scan(&required)
+	return required, err
+}
+
+// Queries UPDATE to require or stop requiring two-factor authentication for an account
+func (rec *Store) SetMFARequired(accountType string, accountID string, required bool) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := accountTable(accountType)
+	if err != nil {
+		return -1, err
+	}
+
+	result, err := rec.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET mfa_required=$1 WHERE %s=$2", table, idColumn), required, accountID)
+	if err != nil {
+		return -1, err
+	}
+	return result.RowsAffected()
+}
+
+// Queries DELETE of TOTP secret and recovery codes of an account, returns the number of accounts found
+func (rec *Store) ResetAccountMFA(accountType string, accountID string) (int64, error) {
+
+	var found int64
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	table, idColumn, err := accountTable(accountType)
+	if err != nil {
+		return -1, err
+	}
+
+	query := fmt.Sprintf("WITH account AS (SELECT %s AS user_id FROM %s WHERE %s=$1), reset AS (DELETE FROM user_mfa WHERE user_id IN (SELECT user_id FROM account)) SELECT COUNT(*) FROM account", idColumn, table, idColumn)
+	if err = rec.db.QueryRowContext(ctx, query, accountID).Scan(&found); err != nil {
+		return -1, err
+	}
+	return found, nil
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS patient;
+DROP FUNCTION IF EXISTS set_patient_updated_at();
+
+DROP TABLE IF EXISTS staff;
+DROP FUNCTION IF EXISTS staff_set_updated_at();
+
+DROP TABLE IF EXISTS doctor;
+DROP FUNCTION IF EXISTS doctor_set_updated_at();
+
+DROP TYPE IF EXISTS gender;
+DROP TYPE IF EXISTS role;
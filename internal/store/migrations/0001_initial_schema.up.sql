@@ -0,0 +1,131 @@
+-- Create ENUM type for staff role
+-- CREATE TYPE IF NOT EXISTS role AS ENUM ('doctor', 'receptionist');
+DO $$ 
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'role') THEN
+        CREATE TYPE role AS ENUM ('doctor', 'receptionist');
+    END IF;
+END $$;
+
+-- Create ENUM type for patient's gender
+-- CREATE TYPE IF NOT EXISTS gender AS ENUM ('male', 'female', 'other');
+DO $$ 
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'gender') THEN
+        CREATE TYPE gender AS ENUM ('male', 'female', 'other');
+    END IF;
+END $$;
+
+-- DROP TABLE IF EXISTS doctor;
+
+-- Create table doctor
+CREATE TABLE IF NOT EXISTS doctor (
+    doctor_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    fullname VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    role   role NOT NULL DEFAULT 'doctor',
+    specialization TEXT NULL,
+    password_hash TEXT NOT NULL UNIQUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create trigger to update updated_at column for doctor table
+CREATE OR REPLACE FUNCTION doctor_set_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for doctor table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_doctor_set_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_doctor_set_updated_at
+        BEFORE UPDATE ON doctor
+        FOR EACH ROW
+        EXECUTE FUNCTION doctor_set_updated_at();
+    END IF;
+END
+$$;
+
+-- DROP TABLE IF EXISTS staff;
+
+-- Create table staff
+CREATE TABLE IF NOT EXISTS staff (
+    staff_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    fullname VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    role   role NOT NULL DEFAULT 'receptionist',
+    password_hash TEXT NOT NULL UNIQUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create trigger to update updated_at column for staff table
+CREATE OR REPLACE FUNCTION staff_set_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for staff table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_staff_set_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_staff_set_updated_at
+        BEFORE UPDATE ON staff
+        FOR EACH ROW
+        EXECUTE FUNCTION staff_set_updated_at();
+    END IF;
+END
+$$;
+
+-- Create table patient
+CREATE TABLE IF NOT EXISTS patient (
+    patient_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    fullname VARCHAR(255) NOT NULL,
+    gender gender NOT NULL,
+    age INT NOT NULL,
+    contact VARCHAR(10) NOT NULL,
+    symptoms TEXT NULL,
+    treatment TEXT NULL DEFAULT '',
+    assigned_to UUID NOT NULL,
+    created_by UUID NOT NULL,
+    token_id INT NOT NULL UNIQUE DEFAULT floor(random() * 900000 + 100000)::int,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_assigned_to FOREIGN KEY (assigned_to) REFERENCES doctor(doctor_id) ON DELETE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY (created_by) REFERENCES staff(staff_id) ON DELETE CASCADE
+);
+
+-- Create trigger to update updated_at column for staff table
+CREATE OR REPLACE FUNCTION set_patient_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for staff table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_patient_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_patient_updated_at
+        BEFORE UPDATE ON patient
+        FOR EACH ROW
+        EXECUTE FUNCTION set_patient_updated_at();
+    END IF;
+END
+$$;
@@ -0,0 +1,6 @@
+-- Postgres cannot drop a value from an ENUM, 'admin' stays in type role
+-- and admins are turned back into receptionists
+UPDATE staff SET role='receptionist' WHERE role='admin';
+
+ALTER TABLE staff DROP COLUMN IF EXISTS is_active;
+ALTER TABLE doctor DROP COLUMN IF EXISTS is_active;
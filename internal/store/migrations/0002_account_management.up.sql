@@ -0,0 +1,8 @@
+-- Admins are staff members that manage doctor and staff accounts
+ALTER TYPE role ADD VALUE IF NOT EXISTS 'admin';
+
+-- Deactivated doctors cannot log in
+ALTER TABLE doctor ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Deactivated staff members cannot log in
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,21 @@
+-- Create table refresh_token (rotating refresh tokens, grouped into families per login)
+CREATE TABLE IF NOT EXISTS refresh_token (
+    token_hash TEXT NOT NULL UNIQUE PRIMARY KEY,
+    family_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role   role NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_family ON refresh_token (family_id);
+
+-- Create table revoked_token (denylist of access token IDs and token family IDs)
+CREATE TABLE IF NOT EXISTS revoked_token (
+    token_id TEXT NOT NULL UNIQUE PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
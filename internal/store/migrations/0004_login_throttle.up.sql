@@ -0,0 +1,22 @@
+-- Create table login_attempt (audit trail of login attempts)
+CREATE TABLE IF NOT EXISTS login_attempt (
+    attempt_id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    reason VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempt_email ON login_attempt (email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempt_client_ip ON login_attempt (client_ip, created_at);
+
+-- Create table login_throttle (failed login counters per email and per client IP)
+CREATE TABLE IF NOT EXISTS login_throttle (
+    subject VARCHAR(320) NOT NULL UNIQUE PRIMARY KEY, -- 'email:<email>' or 'ip:<client ip>'
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    blocked_until TIMESTAMP NULL,
+    locked BOOLEAN NOT NULL DEFAULT FALSE
+);
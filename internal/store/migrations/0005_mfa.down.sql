@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS mfa_recovery_code;
+DROP TABLE IF EXISTS user_mfa;
+
+ALTER TABLE staff DROP COLUMN IF EXISTS mfa_required;
+ALTER TABLE doctor DROP COLUMN IF EXISTS mfa_required;
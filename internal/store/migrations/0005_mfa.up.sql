@@ -0,0 +1,24 @@
+-- Admins can require doctors to log in with a TOTP code
+ALTER TABLE doctor ADD COLUMN IF NOT EXISTS mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Admins can require staff members to log in with a TOTP code
+ALTER TABLE staff ADD COLUMN IF NOT EXISTS mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create table user_mfa (TOTP secrets of doctors and staff members)
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID NOT NULL UNIQUE PRIMARY KEY, -- doctor_id or staff_id
+    secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    enabled_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create table mfa_recovery_code (single use codes for a lost authenticator)
+CREATE TABLE IF NOT EXISTS mfa_recovery_code (
+    code_hash TEXT NOT NULL UNIQUE PRIMARY KEY,
+    user_id UUID NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_mfa FOREIGN KEY (user_id) REFERENCES user_mfa(user_id) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+-- Create table api_key (hashed machine credentials acting for an account within their scopes)
+CREATE TABLE IF NOT EXISTS api_key (
+    key_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    owner_id UUID NOT NULL, -- doctor_id or staff_id the key acts for
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    last_used_ip TEXT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_owner ON api_key (owner_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS encounter;
+DROP FUNCTION IF EXISTS set_encounter_updated_at();
@@ -0,0 +1,57 @@
+-- Create table encounter (one row per visit, patient row keeps demographics)
+CREATE TABLE IF NOT EXISTS encounter (
+    encounter_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    doctor_id UUID NOT NULL,
+    visit_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    chief_complaint TEXT NOT NULL,
+    findings TEXT NOT NULL DEFAULT '',
+    treatment TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    opened_by UUID NOT NULL, -- doctor_id or staff_id
+    closed_by UUID NULL,
+    closed_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_encounter_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE,
+    CONSTRAINT fk_encounter_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id)
+);
+
+-- A patient has at most one open visit at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_encounter_open_per_patient ON encounter (patient_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_encounter_patient ON encounter (patient_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_encounter_doctor ON encounter (doctor_id, visit_date);
+
+-- Create trigger to update updated_at column for encounter table
+CREATE OR REPLACE FUNCTION set_encounter_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for encounter table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_encounter_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_encounter_updated_at
+        BEFORE UPDATE ON encounter
+        FOR EACH ROW
+        EXECUTE FUNCTION set_encounter_updated_at();
+    END IF;
+END
+$$;
+
+-- Carry over the visit recorded on each existing patient row, treated patients count as closed visits
+INSERT INTO encounter (patient_id, doctor_id, visit_date, chief_complaint, treatment, status, opened_by, closed_by, closed_at, updated_at, created_at)
+SELECT patient_id, assigned_to, created_at::date, COALESCE(symptoms, ''), COALESCE(treatment, ''),
+    CASE WHEN COALESCE(treatment, '') = '' THEN 'open' ELSE 'closed' END,
+    created_by,
+    CASE WHEN COALESCE(treatment, '') = '' THEN NULL ELSE assigned_to END,
+    CASE WHEN COALESCE(treatment, '') = '' THEN NULL ELSE updated_at END,
+    updated_at, created_at
+FROM patient
+WHERE NOT EXISTS (SELECT 1 FROM encounter WHERE encounter.patient_id = patient.patient_id);
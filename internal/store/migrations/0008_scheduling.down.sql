@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS appointment;
+DROP FUNCTION IF EXISTS set_appointment_updated_at();
+DROP TABLE IF EXISTS clinic_holiday;
+DROP TABLE IF EXISTS doctor_leave;
+DROP TABLE IF EXISTS doctor_working_hours;
@@ -0,0 +1,85 @@
+-- Needed to combine doctor equality with time range overlap in exclusion constraints
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Create table doctor_working_hours (weekly consulting windows, split into slots)
+CREATE TABLE IF NOT EXISTS doctor_working_hours (
+    working_hours_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 is Sunday
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    slot_minutes INT NOT NULL DEFAULT 15 CHECK (slot_minutes BETWEEN 5 AND 240),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_working_hours_range CHECK (start_time < end_time),
+    CONSTRAINT fk_working_hours_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_working_hours_doctor ON doctor_working_hours (doctor_id, weekday);
+
+-- Create table doctor_leave (days a doctor is not available, inclusive)
+CREATE TABLE IF NOT EXISTS doctor_leave (
+    leave_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_leave_range CHECK (start_date <= end_date),
+    CONSTRAINT fk_leave_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_leave_doctor ON doctor_leave (doctor_id, start_date, end_date);
+
+-- Create table clinic_holiday (days the clinic is closed for every doctor)
+CREATE TABLE IF NOT EXISTS clinic_holiday (
+    holiday_date DATE NOT NULL UNIQUE PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create table appointment (booked slots, times are clinic wall clock times)
+CREATE TABLE IF NOT EXISTS appointment (
+    appointment_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    patient_id UUID NOT NULL,
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'booked' CHECK (status IN ('booked', 'cancelled', 'completed', 'no_show')),
+    reason TEXT NOT NULL DEFAULT '',
+    cancel_reason TEXT NOT NULL DEFAULT '',
+    booked_by UUID NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_appointment_range CHECK (start_at < end_at),
+    CONSTRAINT fk_appointment_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id) ON DELETE CASCADE,
+    CONSTRAINT fk_appointment_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE,
+    -- neither a doctor nor a patient can hold two overlapping booked appointments
+    CONSTRAINT excl_appointment_doctor EXCLUDE USING gist (doctor_id WITH =, tsrange(start_at, end_at) WITH &&) WHERE (status = 'booked'),
+    CONSTRAINT excl_appointment_patient EXCLUDE USING gist (patient_id WITH =, tsrange(start_at, end_at) WITH &&) WHERE (status = 'booked')
+);
+
+CREATE INDEX IF NOT EXISTS idx_appointment_doctor_day ON appointment (doctor_id, start_at);
+
+-- Create trigger to update updated_at column for appointment table
+CREATE OR REPLACE FUNCTION set_appointment_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for appointment table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_appointment_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_appointment_updated_at
+        BEFORE UPDATE ON appointment
+        FOR EACH ROW
+        EXECUTE FUNCTION set_appointment_updated_at();
+    END IF;
+END
+$$;
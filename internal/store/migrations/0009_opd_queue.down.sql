@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS queue_entry;
+DROP FUNCTION IF EXISTS set_queue_entry_updated_at();
+DROP TABLE IF EXISTS queue_counter;
+ALTER TABLE doctor DROP COLUMN IF EXISTS queue_prefix;
+ALTER TABLE patient ALTER COLUMN token_id SET DEFAULT floor(random() * 900000 + 100000)::int;
+DROP FUNCTION IF EXISTS next_patient_token();
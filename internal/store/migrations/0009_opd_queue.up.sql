@@ -0,0 +1,73 @@
+-- Pick patient token IDs that are not taken yet instead of failing inserts on collisions
+CREATE OR REPLACE FUNCTION next_patient_token()
+RETURNS INT AS $$
+DECLARE
+  candidate INT;
+BEGIN
+  LOOP
+    candidate := floor(random() * 900000 + 100000)::int;
+    EXIT WHEN NOT EXISTS (SELECT 1 FROM patient WHERE token_id = candidate);
+  END LOOP;
+  RETURN candidate;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE patient ALTER COLUMN token_id SET DEFAULT next_patient_token();
+
+-- Letters in front of queue numbers (GP-014), derived from specialization when not set
+ALTER TABLE doctor ADD COLUMN IF NOT EXISTS queue_prefix VARCHAR(6) NULL;
+
+-- Create table queue_counter (last queue number issued per doctor and day)
+CREATE TABLE IF NOT EXISTS queue_counter (
+    doctor_id UUID NOT NULL,
+    queue_date DATE NOT NULL,
+    last_number INT NOT NULL,
+    PRIMARY KEY (doctor_id, queue_date),
+    CONSTRAINT fk_queue_counter_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id) ON DELETE CASCADE
+);
+
+-- Create table queue_entry (a patient waiting to see a doctor on a day)
+CREATE TABLE IF NOT EXISTS queue_entry (
+    queue_entry_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    patient_id UUID NOT NULL,
+    queue_date DATE NOT NULL,
+    queue_number INT NOT NULL,
+    token VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'called', 'in_consultation', 'done', 'no_show')),
+    issued_by UUID NOT NULL,
+    called_at TIMESTAMP NULL,
+    started_at TIMESTAMP NULL,
+    finished_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_queue_entry_number UNIQUE (doctor_id, queue_date, queue_number),
+    CONSTRAINT fk_queue_entry_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id) ON DELETE CASCADE,
+    CONSTRAINT fk_queue_entry_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE
+);
+
+-- A patient holds at most one active place in a doctor's queue per day
+CREATE UNIQUE INDEX IF NOT EXISTS uq_queue_entry_active_patient ON queue_entry (doctor_id, queue_date, patient_id) WHERE status IN ('waiting', 'called', 'in_consultation');
+
+-- Create trigger to update updated_at column for queue_entry table
+CREATE OR REPLACE FUNCTION set_queue_entry_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for queue_entry table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_queue_entry_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_queue_entry_updated_at
+        BEFORE UPDATE ON queue_entry
+        FOR EACH ROW
+        EXECUTE FUNCTION set_queue_entry_updated_at();
+    END IF;
+END
+$$;
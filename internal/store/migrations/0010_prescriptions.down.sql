@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS prescription_item;
+DROP TABLE IF EXISTS prescription;
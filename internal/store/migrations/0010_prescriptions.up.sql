@@ -0,0 +1,34 @@
+-- Create table prescription (one prescribing event of a doctor for a patient)
+CREATE TABLE IF NOT EXISTS prescription (
+    prescription_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    doctor_id UUID NOT NULL,
+    encounter_id UUID NULL, -- visit open when prescribed
+    notes TEXT NOT NULL DEFAULT '',
+    status VARCHAR(8) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'voided')),
+    void_reason TEXT NOT NULL DEFAULT '',
+    voided_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_prescription_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE,
+    CONSTRAINT fk_prescription_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id),
+    CONSTRAINT fk_prescription_encounter FOREIGN KEY (encounter_id) REFERENCES encounter(encounter_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_prescription_patient ON prescription (patient_id, created_at DESC);
+
+-- Create table prescription_item (a drug line of a prescription)
+CREATE TABLE IF NOT EXISTS prescription_item (
+    prescription_item_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    prescription_id UUID NOT NULL,
+    line_no INT NOT NULL,
+    drug TEXT NOT NULL,
+    strength TEXT NOT NULL,
+    route VARCHAR(16) NOT NULL,
+    dose TEXT NOT NULL,
+    frequency TEXT NOT NULL,
+    duration TEXT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    instructions TEXT NOT NULL DEFAULT '',
+    CONSTRAINT uq_prescription_item_line UNIQUE (prescription_id, line_no),
+    CONSTRAINT fk_prescription_item_prescription FOREIGN KEY (prescription_id) REFERENCES prescription(prescription_id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS medication_override;
+DROP TABLE IF EXISTS patient_allergy;
+DROP TABLE IF EXISTS drug_interaction;
+DROP TABLE IF EXISTS drug;
@@ -0,0 +1,57 @@
+-- Create table drug (local drug catalogue, loaded from CSV)
+CREATE TABLE IF NOT EXISTS drug (
+    drug_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    name TEXT NOT NULL,
+    generic_name TEXT NOT NULL,
+    drug_class TEXT NOT NULL DEFAULT '',
+    form TEXT NOT NULL DEFAULT '',
+    strength TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_drug_name ON drug (lower(name));
+CREATE INDEX IF NOT EXISTS idx_drug_generic_name ON drug (lower(generic_name));
+
+-- Create table drug_interaction (rules between two generic names or drug classes, stored lowercase with subject_a <= subject_b)
+CREATE TABLE IF NOT EXISTS drug_interaction (
+    interaction_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    subject_a TEXT NOT NULL,
+    subject_b TEXT NOT NULL,
+    severity VARCHAR(16) NOT NULL CHECK (severity IN ('minor', 'moderate', 'major', 'contraindicated')),
+    description TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_drug_interaction UNIQUE (subject_a, subject_b),
+    CONSTRAINT chk_drug_interaction_order CHECK (subject_a <= subject_b)
+);
+
+-- Create table patient_allergy (substances a patient reacts to, a drug, generic name or drug class)
+CREATE TABLE IF NOT EXISTS patient_allergy (
+    allergy_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    substance TEXT NOT NULL,
+    reaction TEXT NOT NULL DEFAULT '',
+    severity VARCHAR(16) NOT NULL DEFAULT 'moderate' CHECK (severity IN ('mild', 'moderate', 'severe')),
+    recorded_by UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_patient_allergy_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_patient_allergy_substance ON patient_allergy (patient_id, lower(substance));
+
+-- Create table medication_override (warnings a doctor accepted with a reason)
+CREATE TABLE IF NOT EXISTS medication_override (
+    override_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    doctor_id UUID NOT NULL,
+    source VARCHAR(16) NOT NULL CHECK (source IN ('prescription', 'treatment')),
+    prescription_id UUID NULL,
+    warnings JSONB NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_medication_override_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE,
+    CONSTRAINT fk_medication_override_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id),
+    CONSTRAINT fk_medication_override_prescription FOREIGN KEY (prescription_id) REFERENCES prescription(prescription_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_medication_override_patient ON medication_override (patient_id, created_at DESC);
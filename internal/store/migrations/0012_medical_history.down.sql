@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS patient_family_history;
+DROP TABLE IF EXISTS patient_surgery;
+DROP TABLE IF EXISTS patient_condition;
+DROP TRIGGER IF EXISTS trigger_set_patient_allergy_updated_at ON patient_allergy;
+DROP FUNCTION IF EXISTS set_medical_history_updated_at();
+ALTER TABLE patient_allergy DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE patient_allergy DROP COLUMN IF EXISTS noted_on;
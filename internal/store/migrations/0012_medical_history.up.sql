@@ -0,0 +1,78 @@
+-- Date an allergy was first noted, unknown when NULL
+ALTER TABLE patient_allergy ADD COLUMN IF NOT EXISTS noted_on DATE NULL;
+ALTER TABLE patient_allergy ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Create table patient_condition (chronic or past conditions of a patient)
+CREATE TABLE IF NOT EXISTS patient_condition (
+    condition_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'controlled', 'resolved')),
+    diagnosed_on DATE NULL,
+    resolved_on DATE NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    recorded_by UUID NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_patient_condition_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE,
+    CONSTRAINT chk_patient_condition_dates CHECK (resolved_on IS NULL OR diagnosed_on IS NULL OR resolved_on >= diagnosed_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_condition_patient ON patient_condition (patient_id);
+
+-- Create table patient_surgery (past surgeries and procedures of a patient)
+CREATE TABLE IF NOT EXISTS patient_surgery (
+    surgery_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    procedure TEXT NOT NULL,
+    performed_on DATE NULL,
+    hospital TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    recorded_by UUID NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_patient_surgery_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_surgery_patient ON patient_surgery (patient_id);
+
+-- Create table patient_family_history (conditions of blood relatives)
+CREATE TABLE IF NOT EXISTS patient_family_history (
+    family_history_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    relation VARCHAR(32) NOT NULL,
+    condition TEXT NOT NULL,
+    age_at_onset INT NULL CHECK (age_at_onset >= 0 AND age_at_onset <= 125),
+    notes TEXT NOT NULL DEFAULT '',
+    recorded_by UUID NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_patient_family_history_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_family_history_patient ON patient_family_history (patient_id);
+
+-- Create trigger to update updated_at column for medical history tables
+CREATE OR REPLACE FUNCTION set_medical_history_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for medical history tables
+DO $$
+DECLARE
+    history_table TEXT;
+BEGIN
+    FOREACH history_table IN ARRAY ARRAY['patient_allergy', 'patient_condition', 'patient_surgery', 'patient_family_history'] LOOP
+        IF NOT EXISTS (
+            SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_' || history_table || '_updated_at'
+        ) THEN
+            EXECUTE format('CREATE TRIGGER %I BEFORE UPDATE ON %I FOR EACH ROW EXECUTE FUNCTION set_medical_history_updated_at()',
+                'trigger_set_' || history_table || '_updated_at', history_table);
+        END IF;
+    END LOOP;
+END
+$$;
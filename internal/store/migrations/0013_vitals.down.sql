@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS vital_range;
+DROP TABLE IF EXISTS vital_sign;
@@ -0,0 +1,51 @@
+-- Create table vital_sign (vitals taken together, stored in mmHg, °C, bpm, %, kg and cm)
+CREATE TABLE IF NOT EXISTS vital_sign (
+    vital_sign_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    encounter_id UUID NULL, -- visit open when recorded
+    systolic NUMERIC(4,1) NULL,
+    diastolic NUMERIC(4,1) NULL,
+    temperature NUMERIC(4,1) NULL,
+    pulse NUMERIC(4,1) NULL,
+    spo2 NUMERIC(4,1) NULL,
+    weight NUMERIC(5,1) NULL,
+    height NUMERIC(4,1) NULL,
+    recorded_by UUID NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_vital_sign_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE,
+    CONSTRAINT fk_vital_sign_encounter FOREIGN KEY (encounter_id) REFERENCES encounter(encounter_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_vital_sign_patient ON vital_sign (patient_id, recorded_at DESC);
+
+-- Create table vital_range (normal range of a vital for an age band, gender NULL applies to all)
+CREATE TABLE IF NOT EXISTS vital_range (
+    vital_range_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    vital VARCHAR(16) NOT NULL CHECK (vital IN ('systolic', 'diastolic', 'temperature', 'pulse', 'spo2', 'bmi')),
+    gender VARCHAR(8) NULL CHECK (gender IN ('male', 'female', 'other')),
+    min_age INT NOT NULL CHECK (min_age >= 0),
+    max_age INT NOT NULL CHECK (max_age <= 125),
+    low NUMERIC(5,1) NOT NULL,
+    high NUMERIC(5,1) NOT NULL,
+    CONSTRAINT chk_vital_range_age CHECK (min_age <= max_age),
+    CONSTRAINT chk_vital_range_value CHECK (low < high)
+);
+
+-- Default ranges, admins can replace them through the API
+INSERT INTO vital_range (vital, gender, min_age, max_age, low, high)
+SELECT * FROM (VALUES
+    ('systolic', NULL, 1, 12, 85, 120),
+    ('systolic', NULL, 13, 17, 90, 130),
+    ('systolic', NULL, 18, 125, 90, 139),
+    ('diastolic', NULL, 1, 12, 50, 80),
+    ('diastolic', NULL, 13, 17, 55, 85),
+    ('diastolic', NULL, 18, 125, 60, 89),
+    ('temperature', NULL, 0, 125, 36.1, 37.5),
+    ('pulse', NULL, 0, 0, 100, 160),
+    ('pulse', NULL, 1, 5, 80, 140),
+    ('pulse', NULL, 6, 12, 70, 120),
+    ('pulse', NULL, 13, 125, 60, 100),
+    ('spo2', NULL, 0, 125, 95, 100),
+    ('bmi', NULL, 18, 125, 18.5, 24.9)
+) AS defaults (vital, gender, min_age, max_age, low, high)
+WHERE NOT EXISTS (SELECT 1 FROM vital_range);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lab_result;
+DROP TABLE IF EXISTS lab_order;
+DROP FUNCTION IF EXISTS set_lab_order_updated_at();
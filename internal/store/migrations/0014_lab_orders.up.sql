@@ -0,0 +1,64 @@
+-- Create table lab_order (a test panel ordered by a doctor for a patient)
+CREATE TABLE IF NOT EXISTS lab_order (
+    lab_order_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    doctor_id UUID NOT NULL,
+    encounter_id UUID NULL, -- visit open when ordered
+    panel TEXT NOT NULL,
+    priority VARCHAR(8) NOT NULL DEFAULT 'routine' CHECK (priority IN ('routine', 'urgent', 'stat')),
+    status VARCHAR(16) NOT NULL DEFAULT 'ordered' CHECK (status IN ('ordered', 'collected', 'resulted', 'reviewed')),
+    notes TEXT NOT NULL DEFAULT '',
+    collected_by UUID NULL,
+    collected_at TIMESTAMP NULL,
+    resulted_by UUID NULL,
+    resulted_at TIMESTAMP NULL,
+    reviewed_at TIMESTAMP NULL,
+    abnormal_acknowledged BOOLEAN NOT NULL DEFAULT FALSE,
+    review_note TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_lab_order_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE,
+    CONSTRAINT fk_lab_order_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id),
+    CONSTRAINT fk_lab_order_encounter FOREIGN KEY (encounter_id) REFERENCES encounter(encounter_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_lab_order_patient ON lab_order (patient_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_lab_order_doctor_status ON lab_order (doctor_id, status);
+
+-- Create table lab_result (a measured analyte of a lab order)
+CREATE TABLE IF NOT EXISTS lab_result (
+    lab_result_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    lab_order_id UUID NOT NULL,
+    line_no INT NOT NULL,
+    analyte TEXT NOT NULL,
+    value NUMERIC(12,4) NOT NULL,
+    unit VARCHAR(32) NOT NULL,
+    ref_low NUMERIC(12,4) NULL,
+    ref_high NUMERIC(12,4) NULL,
+    flag VARCHAR(8) NOT NULL CHECK (flag IN ('normal', 'low', 'high')),
+    CONSTRAINT fk_lab_result_order FOREIGN KEY (lab_order_id) REFERENCES lab_order(lab_order_id) ON DELETE CASCADE,
+    CONSTRAINT uq_lab_result_line UNIQUE (lab_order_id, line_no)
+);
+
+-- Create trigger to update updated_at column for lab_order table
+CREATE OR REPLACE FUNCTION set_lab_order_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for lab_order table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_lab_order_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_lab_order_updated_at
+        BEFORE UPDATE ON lab_order
+        FOR EACH ROW
+        EXECUTE FUNCTION set_lab_order_updated_at();
+    END IF;
+END
+$$;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS clinical_note_version;
+DROP TABLE IF EXISTS clinical_note;
+DROP FUNCTION IF EXISTS prevent_signed_note_change();
+DROP FUNCTION IF EXISTS prevent_signed_note_delete();
+DROP FUNCTION IF EXISTS set_clinical_note_updated_at();
@@ -0,0 +1,108 @@
+-- Create table clinical_note (a SOAP note written by a doctor for a patient)
+CREATE TABLE IF NOT EXISTS clinical_note (
+    note_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    doctor_id UUID NOT NULL,
+    encounter_id UUID NULL, -- visit open when written
+    status VARCHAR(8) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'signed')),
+    signed_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_clinical_note_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE, -- drafts only, signed notes block the delete
+    CONSTRAINT fk_clinical_note_doctor FOREIGN KEY (doctor_id) REFERENCES doctor(doctor_id),
+    CONSTRAINT fk_clinical_note_encounter FOREIGN KEY (encounter_id) REFERENCES encounter(encounter_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_clinical_note_patient ON clinical_note (patient_id, created_at DESC);
+
+-- Create table clinical_note_version (content of a note, version 1 is the original and later versions are amendments)
+CREATE TABLE IF NOT EXISTS clinical_note_version (
+    note_id UUID NOT NULL,
+    version INT NOT NULL CHECK (version >= 1),
+    subjective TEXT NOT NULL DEFAULT '',
+    objective TEXT NOT NULL DEFAULT '',
+    assessment TEXT NOT NULL DEFAULT '',
+    plan TEXT NOT NULL DEFAULT '',
+    amendment_reason TEXT NULL CHECK ((version = 1) = (amendment_reason IS NULL)),
+    authored_by UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (note_id, version),
+    CONSTRAINT fk_clinical_note_version_note FOREIGN KEY (note_id) REFERENCES clinical_note(note_id) ON DELETE CASCADE,
+    CONSTRAINT fk_clinical_note_version_author FOREIGN KEY (authored_by) REFERENCES doctor(doctor_id)
+);
+
+-- Create trigger to update updated_at column for clinical_note table
+CREATE OR REPLACE FUNCTION set_clinical_note_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for clinical_note table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_clinical_note_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_clinical_note_updated_at
+        BEFORE UPDATE ON clinical_note
+        FOR EACH ROW
+        EXECUTE FUNCTION set_clinical_note_updated_at();
+    END IF;
+END
+$$;
+
+-- Create trigger to keep versions of signed notes immutable, changes must be added as amendments
+CREATE OR REPLACE FUNCTION prevent_signed_note_change()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM clinical_note WHERE note_id = OLD.note_id AND status = 'signed') THEN
+    RAISE EXCEPTION 'versions of signed note % cannot be changed', OLD.note_id USING ERRCODE = 'restrict_violation';
+  END IF;
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to clinical_note_version table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_prevent_signed_note_change'
+    ) THEN
+        CREATE TRIGGER trigger_prevent_signed_note_change
+        BEFORE UPDATE OR DELETE ON clinical_note_version
+        FOR EACH ROW
+        EXECUTE FUNCTION prevent_signed_note_change();
+    END IF;
+END
+$$;
+
+-- Create trigger to keep signed notes for medico-legal review, also when their patient is deleted
+CREATE OR REPLACE FUNCTION prevent_signed_note_delete()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF OLD.status = 'signed' THEN
+    RAISE EXCEPTION 'signed note % cannot be deleted', OLD.note_id USING ERRCODE = 'restrict_violation';
+  END IF;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to clinical_note table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_prevent_signed_note_delete'
+    ) THEN
+        CREATE TRIGGER trigger_prevent_signed_note_delete
+        BEFORE DELETE ON clinical_note
+        FOR EACH ROW
+        EXECUTE FUNCTION prevent_signed_note_delete();
+    END IF;
+END
+$$;
@@ -0,0 +1,17 @@
+-- Create table attachment (a document or image kept for a patient, the file itself is in file storage)
+CREATE TABLE IF NOT EXISTS attachment (
+    attachment_id UUID NOT NULL UNIQUE PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    category VARCHAR(16) NOT NULL DEFAULT 'other' CHECK (category IN ('prescription', 'report', 'imaging', 'id', 'other')),
+    description TEXT NOT NULL DEFAULT '',
+    filename TEXT NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    checksum_sha256 CHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    uploaded_by UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_attachment_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachment_patient ON attachment (patient_id, created_at DESC);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS payment;
+DROP TABLE IF EXISTS invoice_line;
+DROP TABLE IF EXISTS invoice;
+DROP TABLE IF EXISTS billing_counter;
+DROP TABLE IF EXISTS fee;
+DROP FUNCTION IF EXISTS set_invoice_updated_at();
+DROP FUNCTION IF EXISTS set_fee_updated_at();
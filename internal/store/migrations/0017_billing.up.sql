@@ -0,0 +1,138 @@
+-- Create table fee (fee schedule, prices are copied onto invoices so changes do not alter issued invoices)
+CREATE TABLE IF NOT EXISTS fee (
+    fee_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    category VARCHAR(16) NOT NULL CHECK (category IN ('consultation', 'procedure', 'lab_test')),
+    specialization TEXT NULL, -- consultations only, NULL is the default consultation fee
+    amount NUMERIC(12,2) NOT NULL CHECK (amount >= 0),
+    tax_rate NUMERIC(5,2) NOT NULL DEFAULT 0 CHECK (tax_rate >= 0 AND tax_rate <= 100), -- percent
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_fee_specialization CHECK (specialization IS NULL OR category = 'consultation')
+);
+
+-- one active consultation fee per specialization
+CREATE UNIQUE INDEX IF NOT EXISTS uq_fee_consultation ON fee (lower(COALESCE(specialization, ''))) WHERE category = 'consultation' AND active;
+
+-- Create table billing_counter (yearly invoice and receipt numbers, updated inside the billing transaction so numbers have no gaps)
+CREATE TABLE IF NOT EXISTS billing_counter (
+    counter VARCHAR(16) NOT NULL,
+    year INT NOT NULL,
+    last_value INT NOT NULL,
+    PRIMARY KEY (counter, year)
+);
+
+-- Create table invoice (charges of a patient visit, invoices and payments are kept so patients with them cannot be deleted)
+CREATE TABLE IF NOT EXISTS invoice (
+    invoice_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    invoice_no VARCHAR(32) NOT NULL UNIQUE,
+    patient_id UUID NOT NULL,
+    encounter_id UUID NOT NULL,
+    invoice_date DATE NOT NULL, -- clinic date the invoice was issued
+    status VARCHAR(16) NOT NULL DEFAULT 'issued' CHECK (status IN ('issued', 'partially_paid', 'paid', 'void')),
+    subtotal NUMERIC(12,2) NOT NULL,
+    discount_total NUMERIC(12,2) NOT NULL,
+    tax_total NUMERIC(12,2) NOT NULL,
+    total NUMERIC(12,2) NOT NULL,
+    amount_paid NUMERIC(12,2) NOT NULL DEFAULT 0,
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID NOT NULL,
+    void_reason TEXT NOT NULL DEFAULT '',
+    voided_by UUID NULL,
+    voided_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_invoice_amount_paid CHECK (amount_paid >= 0 AND amount_paid <= total),
+    CONSTRAINT fk_invoice_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE RESTRICT,
+    CONSTRAINT fk_invoice_encounter FOREIGN KEY (encounter_id) REFERENCES encounter(encounter_id) ON DELETE RESTRICT
+);
+
+-- a visit is billed once, a voided invoice can be replaced
+CREATE UNIQUE INDEX IF NOT EXISTS uq_invoice_encounter ON invoice (encounter_id) WHERE status <> 'void';
+CREATE INDEX IF NOT EXISTS idx_invoice_patient ON invoice (patient_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_invoice_date ON invoice (invoice_date);
+
+-- Create table invoice_line (a priced item of an invoice)
+CREATE TABLE IF NOT EXISTS invoice_line (
+    invoice_line_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    invoice_id UUID NOT NULL,
+    line_no INT NOT NULL,
+    fee_id UUID NULL, -- NULL for items not in the fee schedule
+    code VARCHAR(32) NOT NULL DEFAULT '',
+    description TEXT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(12,2) NOT NULL,
+    discount_percent NUMERIC(5,2) NOT NULL DEFAULT 0,
+    discount_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    tax_rate NUMERIC(5,2) NOT NULL DEFAULT 0,
+    tax_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    line_total NUMERIC(12,2) NOT NULL,
+    CONSTRAINT fk_invoice_line_invoice FOREIGN KEY (invoice_id) REFERENCES invoice(invoice_id) ON DELETE CASCADE,
+    CONSTRAINT fk_invoice_line_fee FOREIGN KEY (fee_id) REFERENCES fee(fee_id) ON DELETE SET NULL,
+    CONSTRAINT uq_invoice_line UNIQUE (invoice_id, line_no)
+);
+
+-- Create table payment (money received against an invoice)
+CREATE TABLE IF NOT EXISTS payment (
+    payment_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
+    receipt_no VARCHAR(32) NOT NULL UNIQUE,
+    invoice_id UUID NOT NULL,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    method VARCHAR(8) NOT NULL CHECK (method IN ('cash', 'card', 'upi')),
+    reference TEXT NOT NULL DEFAULT '', -- card slip or UPI transaction number
+    business_date DATE NOT NULL, -- clinic date the money was received, used for cash closing
+    received_by UUID NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_payment_invoice FOREIGN KEY (invoice_id) REFERENCES invoice(invoice_id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_invoice ON payment (invoice_id);
+CREATE INDEX IF NOT EXISTS idx_payment_business_date ON payment (business_date);
+
+-- Create trigger to update updated_at column for fee table
+CREATE OR REPLACE FUNCTION set_fee_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for fee table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_fee_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_fee_updated_at
+        BEFORE UPDATE ON fee
+        FOR EACH ROW
+        EXECUTE FUNCTION set_fee_updated_at();
+    END IF;
+END
+$$;
+
+-- Create trigger to update updated_at column for invoice table
+CREATE OR REPLACE FUNCTION set_invoice_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at := CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Attach trigger to updated_at column for invoice table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_invoice_updated_at'
+    ) THEN
+        CREATE TRIGGER trigger_set_invoice_updated_at
+        BEFORE UPDATE ON invoice
+        FOR EACH ROW
+        EXECUTE FUNCTION set_invoice_updated_at();
+    END IF;
+END
+$$;
@@ -0,0 +1,11 @@
+-- Create table report_verification (verification codes printed on patient reports, looked up to confirm a copy was issued by the clinic)
+CREATE TABLE IF NOT EXISTS report_verification (
+    code VARCHAR(32) NOT NULL PRIMARY KEY,
+    patient_id UUID NOT NULL,
+    record_updated_at TIMESTAMP NOT NULL, -- version of the patient record the report was rendered from
+    generated_by TEXT NOT NULL,
+    generated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- first time a report with this code was issued
+    CONSTRAINT fk_report_verification_patient FOREIGN KEY (patient_id) REFERENCES patient(patient_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_verification_patient ON report_verification (patient_id);
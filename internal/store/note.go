@@ -0,0 +1,309 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+)
+
+var (
+	ErrNoteNotFound          = errors.New("no clinical note found for provided ID")
+	ErrNoteVersionNotFound   = errors.New("no version of clinical note found for provided number")
+	ErrNoteSigned            = errors.New("clinical note is signed, record changes as an amendment")
+	ErrNoteNotSigned         = errors.New("clinical note is a draft, edit it instead of amending")
+	ErrPatientHasSignedNotes = errors.New("patient has signed clinical notes and cannot be deleted, signed notes are kept for medico-legal review")
+)
+
+type noteQueryResponse struct {
+	NoteID      string  `json:"note_id"`
+	TokenID     string  `json:"token_id"`
+	PatientName string  `json:"patient_name"`
+	DoctorID    string  `json:"doctor_id"`
+	DoctorName  string  `json:"doctor_name"`
+	EncounterID *string `json:"encounter_id"`
+	Status      string  `json:"status"`
+	Version     int     `json:"version"` // latest version, above 1 when amended
+	models.SOAPNote
+	AmendmentReason *string `json:"amendment_reason"`
+	SignedAt        *string `json:"signed_at"`
+	VersionedAt     string  `json:"versioned_at"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+type noteVersionResponse struct {
+	NoteID  string `json:"note_id"`
+	Version int    `json:"version"`
+	models.SOAPNote
+	AmendmentReason *string `json:"amendment_reason"`
+	AuthoredBy      string  `json:"authored_by"`
+	AuthorName      string  `json:"author_name"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+// Notes are read with their latest version
+const (
+	noteColumns = `SELECT n.note_id, p.token_id, p.fullname, n.doctor_id, d.fullname, n.encounter_id, n.status, v.version,
+	v.subjective, v.objective, v.assessment, v.plan, v.amendment_reason, n.signed_at, v.created_at, n.created_at`
+	noteFrom = ` FROM clinical_note n JOIN patient p ON p.patient_id=n.patient_id JOIN doctor d ON d.doctor_id=n.doctor_id
+	JOIN LATERAL (SELECT * FROM clinical_note_version cv WHERE cv.note_id=n.note_id ORDER BY cv.version DESC LIMIT 1) v ON true `
+)
+
+func scanNote(row rowScanner, extra ...any) (noteQueryResponse, error) {
+	var queryData noteQueryResponse
+	dest := []any{&queryData.NoteID, &queryData.TokenID, &queryData.PatientName, &queryData.DoctorID, &queryData.DoctorName, &queryData.EncounterID, &queryData.Status, &queryData.Version,
+		&queryData.Subjective, &queryData.Objective, &queryData.Assessment, &queryData.Plan, &queryData.AmendmentReason, &queryData.SignedAt, &queryData.VersionedAt, &queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return queryData, err
+}
+
+const noteVersionColumns = `SELECT v.note_id, v.version, v.subjective, v.objective, v.assessment, v.plan, v.amendment_reason, v.authored_by, d.fullname, v.created_at
+	FROM clinical_note_version v JOIN doctor d ON d.doctor_id=v.authored_by `
+
+func scanNoteVersion(row rowScanner) (noteVersionResponse, error) {
+	var queryData noteVersionResponse
+	err := row.Scan(&queryData.NoteID, &queryData.Version, &queryData.Subjective, &queryData.Objective, &queryData.Assessment, &queryData.Plan,
+		&queryData.AmendmentReason, &queryData.AuthoredBy, &queryData.AuthorName, &queryData.CreatedAt)
+	return queryData, err
+}
+
+// Queries INSERT to start a draft note for one of the doctor's patients, linked to the patient's open visit when there is one
+func (rec *Store) CreateNote(tokenID string, noteReq *models.SOAPNote, doctorID uuid.UUID) (uuid.UUID, error) {
+
+	var noteID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// doctors write notes only for their own patients
+	err := rec.db.QueryRowContext(ctx, `WITH note AS (INSERT INTO clinical_note (patient_id, doctor_id, encounter_id)
+		SELECT p.patient_id, $2, (SELECT encounter_id FROM encounter e WHERE e.patient_id=p.patient_id AND e.status='open') FROM patient p WHERE p.token_id::text=$1 AND p.assigned_to=$2 RETURNING note_id)
+		INSERT INTO clinical_note_version (note_id, version, subjective, objective, assessment, plan, authored_by) SELECT note_id, 1, $3, $4, $5, $6, $2 FROM note RETURNING note_id`,
+		tokenID, doctorID, noteReq.Subjective, noteReq.Objective, noteReq.Assessment, noteReq.Plan).Scan(&noteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrPatientNotFound
+		}
+		return uuid.Nil, err
+	}
+	return noteID, nil
+}
+
+// Queries notes of a patient with their latest version, newest first
+func (rec *Store) GetNotes(tokenID string, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, noteColumns+", count(*) over() as total_records"+noteFrom+"WHERE p.token_id::text=$1 ORDER BY n.created_at DESC LIMIT $2 OFFSET $3", tokenID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allNoteData := make([]noteQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanNote(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allNoteData = append(allNoteData, queryData)
+	}
+
+	responseData[0] = map[string][]noteQueryResponse{"notes_data": allNoteData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries a single note with its latest version
+func (rec *Store) GetNote(noteID string) (noteQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanNote(rec.db.QueryRowContext(ctx, noteColumns+noteFrom+"WHERE n.note_id::text=$1", noteID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return noteQueryResponse{}, ErrNoteNotFound
+		}
+		return noteQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Queries every version of a note, the original first
+func (rec *Store) GetNoteVersions(noteID string) ([]noteVersionResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, noteVersionColumns+"WHERE v.note_id::text=$1 ORDER BY v.version", noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]noteVersionResponse, 0)
+	for rows.Next() {
+		queryData, err := scanNoteVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, queryData)
+	}
+	if len(versions) == 0 {
+		return nil, ErrNoteNotFound
+	}
+	return versions, nil
+}
+
+// Queries one version of a note
+func (rec *Store) GetNoteVersion(noteID string, version int) (noteVersionResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanNoteVersion(rec.db.QueryRowContext(ctx, noteVersionColumns+"WHERE v.note_id::text=$1 AND v.version=$2", noteID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return noteVersionResponse{}, ErrNoteVersionNotFound
+		}
+		return noteVersionResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Locks a note of the doctor for changes and returns its status, notes of other doctors are reported as not found
+func lockNote(ctx context.Context, tx *sql.Tx, noteID string, doctorID uuid.UUID) (string, error) {
+
+	var status string
+	err := tx.QueryRowContext(ctx, "SELECT status FROM clinical_note WHERE note_id::text=$1 AND doctor_id=$2 FOR UPDATE", noteID, doctorID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNoteNotFound
+	}
+	return status, err
+}
+
+// Queries UPDATE to replace the content of a draft note written by the doctor
+func (rec *Store) UpdateNoteDraft(noteID string, noteReq *models.SOAPNote, doctorID uuid.UUID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	status, err := lockNote(ctx, tx, noteID, doctorID)
+	if err != nil {
+		return err
+	}
+	if status != models.NoteStatusDraft {
+		err = ErrNoteSigned
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE clinical_note_version SET subjective=$2, objective=$3, assessment=$4, plan=$5, created_at=CURRENT_TIMESTAMP WHERE note_id::text=$1 AND version=1",
+		noteID, noteReq.Subjective, noteReq.Objective, noteReq.Assessment, noteReq.Plan)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE clinical_note SET updated_at=CURRENT_TIMESTAMP WHERE note_id::text=$1", noteID)
+	return err
+}
+
+// Queries UPDATE to sign a draft note written by the doctor, its content cannot change afterwards
+func (rec *Store) SignNote(noteID string, doctorID uuid.UUID) error {
+
+	var signed int
+	var status sql.NullString
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// current sees the row as it was before the update
+	err := rec.db.QueryRowContext(ctx, `WITH current AS (SELECT status FROM clinical_note WHERE note_id::text=$1 AND doctor_id=$2),
+		signed AS (UPDATE clinical_note SET status='signed', signed_at=CURRENT_TIMESTAMP WHERE note_id::text=$1 AND doctor_id=$2 AND status='draft' RETURNING note_id)
+		SELECT (SELECT count(*) FROM signed), (SELECT status FROM current)`, noteID, doctorID).Scan(&signed, &status)
+	if err != nil {
+		return err
+	}
+	if signed == 1 {
+		return nil
+	}
+	if !status.Valid {
+		return ErrNoteNotFound
+	}
+	return ErrNoteSigned
+}
+
+// Queries INSERT to add an amendment to a signed note written by the doctor, earlier versions are kept as they were
+func (rec *Store) AmendNote(noteID string, amendmentReq *models.NoteAmendment, doctorID uuid.UUID) (int, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	// the lock on the note serialises amendments so version numbers do not clash
+	status, err := lockNote(ctx, tx, noteID, doctorID)
+	if err != nil {
+		return 0, err
+	}
+	if status != models.NoteStatusSigned {
+		err = ErrNoteNotSigned
+		return 0, err
+	}
+
+	var version int
+	err = tx.QueryRowContext(ctx, `INSERT INTO clinical_note_version (note_id, version, subjective, objective, assessment, plan, amendment_reason, authored_by)
+		SELECT note_id, max(version) + 1, $2, $3, $4, $5, $6, $7 FROM clinical_note_version WHERE note_id::text=$1 GROUP BY note_id RETURNING version`,
+		noteID, amendmentReq.Subjective, amendmentReq.Objective, amendmentReq.Assessment, amendmentReq.Plan, amendmentReq.Reason, doctorID).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE clinical_note SET updated_at=CURRENT_TIMESTAMP WHERE note_id::text=$1", noteID)
+	return version, err
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
 )
 
 type patientQueryResponse struct {
@@ -24,10 +26,18 @@ type patientQueryResponse struct {
 	TokenID    string `json:"token_id,omitempty"`
 	CreatedAt  string `json:"created_at,omitempty"`
 	UpdatedAt  string `json:"updated_at,omitempty"`
+
+	MedicalHistory *medicalHistoryResponse `json:"medical_history,omitempty"`
+	VitalFlags     []models.VitalFlag      `json:"vital_flags,omitempty"` // out-of-range values of the latest vitals
 }
 
-// Queries list of patients
-func (rec *Store) GetAllPatients(limit int32, offset int32) (interface{}, error) {
+// Patient details as returned by GetPatientByTokenID, for rendering them outside the store
+type PatientDetails = patientQueryResponse
+
+var ErrPatientNotFound = errors.New("no patient found for provided token ID")
+
+// Queries list of patients, restricted to patients of assignedTo when it is set
+func (rec *Store) GetAllPatients(limit int32, offset int32, assignedTo uuid.NullUUID) (interface{}, error) {
 
 	var total_records int32
 	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
@@ -37,7 +47,7 @@ func (rec *Store) GetAllPatients(limit int32, offset int32) (interface{}, error)
 		limit = 10
 	}
 
-	rows, err := rec.db.QueryContext(ctx, "SELECT fullname, gender, age, contact, symptoms, treatment, assigned_to, token_id, updated_at, created_at, count(*) over() as total_records FROM patient ORDER BY created_at LIMIT $1 OFFSET $2", limit, offset)
+	rows, err := rec.db.QueryContext(ctx, "SELECT fullname, gender, age, contact, symptoms, treatment, assigned_to, token_id, updated_at, created_at, count(*) over() as total_records FROM patient WHERE ($3::uuid IS NULL OR assigned_to=$3::uuid) ORDER BY created_at LIMIT $1 OFFSET $2", limit, offset, assignedTo)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return patientQueryResponse{}, errors.New("no such data found") // return empty model
@@ -121,6 +131,20 @@ func (rec *Store) GetAllPatientsByDoc(doctorID uuid.UUID, limit int32, offset in
 		// store each row
 		allPatientData = append(allPatientData, queryData)
 	}
+	rows.Close()
+
+	// Flag abnormal vitals so doctors can see who needs attention first
+	tokenIDs := make([]string, 0, len(allPatientData))
+	for _, patient := range allPatientData {
+		tokenIDs = append(tokenIDs, patient.TokenID)
+	}
+	vitalFlags, err := rec.latestVitalFlags(ctx, tokenIDs)
+	if err != nil {
+		return patientQueryResponse{}, err
+	}
+	for i := range allPatientData {
+		allPatientData[i].VitalFlags = vitalFlags[allPatientData[i].TokenID]
+	}
 
 	responseData[0] = map[string][]patientQueryResponse{"patients_data": allPatientData}
 	responseData[1] = map[string]int32{"total_no_records": total_records}
@@ -141,7 +165,7 @@ func (rec *Store) GetPatientByTokenID(token_id string) (interface{}, error) {
 		&queryData.Fullname, &queryData.Gender, &queryData.Age, &queryData.Contact, &queryData.Symptoms, &queryData.Treatment, &assignedDoctor, &queryData.TokenID, &queryData.UpdatedAt, &queryData.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return queryData, errors.New("no data found based on request") // return empty model
+			return queryData, ErrPatientNotFound // return empty model
 		}
 		return queryData, err // return empty model
 	}
@@ -155,7 +179,78 @@ func (rec *Store) GetPatientByTokenID(token_id string) (interface{}, error) {
 		queryData.AssignedTo = doctor_name
 	}
 
-	return queryData, err
+	// Get allergies, conditions, surgeries and family history
+	history, err := rec.GetMedicalHistory(token_id)
+	if err != nil {
+		return queryData, err
+	}
+	queryData.MedicalHistory = &history
+
+	return queryData, nil
+}
+
+// Checks contact number of the patient with token ID, unknown token IDs never match
+func (rec *Store) VerifyPatientContact(tokenID string, contact string) (bool, error) {
+
+	var storedContact string
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "SELECT contact FROM patient WHERE token_id=$1", tokenID).Scan(&storedContact)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare([]byte(storedContact), []byte(contact)) == 1, nil
+}
+
+// Queries reduced patient details for callers that are not authenticated as staff
+func (rec *Store) GetPublicPatientView(tokenID string) (interface{}, error) {
+
+	patientData, err := rec.GetPatientByTokenID(tokenID)
+	if err != nil {
+		return patientQueryResponse{}, err
+	}
+
+	fullView := patientData.(patientQueryResponse)
+	return patientQueryResponse{
+		Fullname:   fullView.Fullname,
+		Treatment:  fullView.Treatment,
+		AssignedTo: fullView.AssignedTo,
+		TokenID:    fullView.TokenID,
+		CreatedAt:  fullView.CreatedAt,
+		UpdatedAt:  fullView.UpdatedAt,
+	}, nil
+}
+
+// Queries doctor assigned to the patient with token ID
+func (rec *Store) GetPatientAssignee(tokenID string) (uuid.UUID, error) {
+
+	var assignedTo uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "SELECT assigned_to FROM patient WHERE token_id=$1", tokenID).Scan(&assignedTo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrPatientNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	return assignedTo, nil
+}
+
+// Times a patient insert is retried when the random token ID is already taken
+const maxTokenIDAttempts = 5
+
+// Checks if err is a unique violation of the patient's token ID
+func isTokenIDTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "patient_token_id_key"
 }
 
 // Queries INSERT to create new patient
@@ -184,14 +279,37 @@ func (rec *Store) CreatePatient(patientMod *models.Patient) (int64, error) {
 		}
 	}()
 
-	var query string = "INSERT INTO patient (fullname, gender, age, contact, symptoms, assigned_to, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING token_id"
-	err = tx.QueryRowContext(ctx, query, patientMod.Fullname, patientMod.Gender, patientMod.Age, patientMod.Contact, patientMod.Symptoms, patientMod.Assigned_to, patientMod.Created_by).Scan(&tokenID)
+	var patientID uuid.UUID
+	var query string = "INSERT INTO patient (fullname, gender, age, contact, symptoms, assigned_to, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING token_id, patient_id"
+
+	// token IDs are drawn at random by the database, a taken one is drawn again
+	for attempt := 1; attempt <= maxTokenIDAttempts; attempt++ {
+		if _, err = tx.ExecContext(ctx, "SAVEPOINT new_patient"); err != nil {
+			break
+		}
+		err = tx.QueryRowContext(ctx, query, patientMod.Fullname, patientMod.Gender, patientMod.Age, patientMod.Contact, patientMod.Symptoms, patientMod.Assigned_to, patientMod.Created_by).Scan(&tokenID, &patientID)
+		if !isTokenIDTaken(err) {
+			break
+		}
+		log.Printf("Token ID already taken, drawing another (attempt %d)", attempt)
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT new_patient"); rbErr != nil {
+			err = rbErr
+			break
+		}
+	}
 
 	if err != nil {
 		log.Println("Error while inserting data ", err)
 		return -1, err
 	}
 
+	// registration is the patient's first visit
+	_, err = openEncounter(ctx, tx, patientID, patientMod.Assigned_to, patientMod.Symptoms, patientMod.Created_by)
+	if err != nil {
+		log.Println("Error while opening visit ", err)
+		return -1, err
+	}
+
 	// rowsAffected, err := result.RowsAffected()
 	// if err != nil {
 	// 	log.Println("Error while inserting data ", err)
@@ -201,8 +319,9 @@ func (rec *Store) CreatePatient(patientMod *models.Patient) (int64, error) {
 	return tokenID, nil
 }
 
-// Queries UPDATE to update existing patient record
-func (rec *Store) UpdatePatient(tokenID string, patientReq *models.Patient) (int64, error) {
+// Queries UPDATE to update existing patient record, restricted to patients of assignedTo when it is set.
+// Warnings the user accepted for the treatment are recorded with the update.
+func (rec *Store) UpdatePatient(tokenID string, patientReq *models.Patient, assignedTo uuid.NullUUID, userID uuid.UUID, warnings []models.MedicationWarning, overrideReason string) (int64, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
 	defer cancel()
@@ -302,15 +421,44 @@ func (rec *Store) UpdatePatient(tokenID string, patientReq *models.Patient) (int
 
 	query.WriteString(fmt.Sprintf("WHERE token_id=$%d ", argCount))
 	args = append(args, tokenID)
+	argCount++
+
+	if assignedTo.Valid {
+		query.WriteString(fmt.Sprintf("AND assigned_to=$%d ", argCount))
+		args = append(args, assignedTo.UUID)
+	}
+
+	query.WriteString("RETURNING patient_id")
 
-	result, err := tx.ExecContext(ctx, query.String(), args...)
+	var patientID uuid.UUID
+	err = tx.QueryRowContext(ctx, query.String(), args...).Scan(&patientID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+			return 0, nil
+		}
 		log.Println("Error while updating data ", err)
 		return -1, err
 	}
 
-	rowAffected, err := result.RowsAffected()
-	return rowAffected, nil
+	// symptoms and treatment belong to the open visit, patient row only mirrors it
+	if patientReq.Symptoms != "" || patientReq.Treatment != "" {
+		_, err = tx.ExecContext(ctx, "UPDATE encounter SET chief_complaint=COALESCE(NULLIF($1, ''), chief_complaint), treatment=COALESCE(NULLIF($2, ''), treatment) WHERE patient_id=$3 AND status='open'",
+			patientReq.Symptoms, patientReq.Treatment, patientID)
+		if err != nil {
+			log.Println("Error while updating visit ", err)
+			return -1, err
+		}
+	}
+
+	if len(warnings) > 0 {
+		err = recordMedicationOverride(ctx, tx, tokenID, userID, "treatment", uuid.NullUUID{}, warnings, overrideReason)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return 1, nil
 }
 
 // Queries DELETE to remove patient record
@@ -325,6 +473,14 @@ func (rec *Store) DeletePatient(tokenID string) (int64, error) {
 		return -1, err
 	}
 
+	// attachment files are removed once the patient's rows are gone
+	var storageKeys []string
+	defer func() {
+		if err == nil {
+			rec.removeAttachmentFiles(storageKeys)
+		}
+	}()
+
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
@@ -333,13 +489,56 @@ func (rec *Store) DeletePatient(tokenID string) (int64, error) {
 		} else {
 			if cmErr := tx.Commit(); cmErr != nil {
 				log.Println("Commit rollback error: ", cmErr)
+				err = cmErr
 			}
 		}
 	}()
 
+	rows, err := tx.QueryContext(ctx, "SELECT a.storage_key FROM attachment a JOIN patient p ON p.patient_id=a.patient_id WHERE p.token_id=$1", tokenID)
+	if err != nil {
+		return -1, err
+	}
+	for rows.Next() {
+		var storageKey string
+		if err = rows.Scan(&storageKey); err != nil {
+			rows.Close()
+			return -1, err
+		}
+		storageKeys = append(storageKeys, storageKey)
+	}
+	rows.Close()
+
+	// invoices and receipted payments must stay for cash closing and their number sequences
+	var hasInvoices bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM invoice i JOIN patient p ON p.patient_id=i.patient_id WHERE p.token_id=$1)", tokenID).Scan(&hasInvoices)
+	if err != nil {
+		return -1, err
+	}
+	if hasInvoices {
+		err = ErrPatientHasInvoices
+		return -1, err
+	}
+
+	var hasSignedNotes bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM clinical_note n JOIN patient p ON p.patient_id=n.patient_id WHERE p.token_id=$1 AND n.status='signed')", tokenID).Scan(&hasSignedNotes)
+	if err != nil {
+		return -1, err
+	}
+	if hasSignedNotes {
+		err = ErrPatientHasSignedNotes
+		return -1, err
+	}
+
 	var query string = "DELETE FROM patient WHERE token_id=$1"
 	result, err := tx.ExecContext(ctx, query, tokenID)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			err = ErrPatientHasInvoices // invoiced while the patient was being deleted
+		}
+		if errors.As(err, &pqErr) && pqErr.Code == "23001" {
+			err = ErrPatientHasSignedNotes // note signed while the patient was being deleted
+		}
 		return -1, err
 	}
 	rowAffected, err := result.RowsAffected()
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestIsTokenIDTaken(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no error", nil, false},
+		{"token id taken", &pq.Error{Code: "23505", Constraint: "patient_token_id_key"}, true},
+		{"wrapped", fmt.Errorf("insert: %w", &pq.Error{Code: "23505", Constraint: "patient_token_id_key"}), true},
+		{"other unique constraint", &pq.Error{Code: "23505", Constraint: "patient_pkey"}, false},
+		{"foreign key", &pq.Error{Code: "23503", Constraint: "fk_assigned_to"}, false},
+		{"not a database error", errors.New("connection reset"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isTokenIDTaken(test.err); got != test.want {
+				t.Errorf("isTokenIDTaken(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
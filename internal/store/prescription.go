@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPrescriptionNotFound = errors.New("no prescription found for provided ID")
+	ErrPrescriptionVoided   = errors.New("prescription is already voided")
+)
+
+type prescriptionQueryResponse struct {
+	PrescriptionID string                    `json:"prescription_id"`
+	TokenID        string                    `json:"token_id"`
+	PatientName    string                    `json:"patient_name"`
+	PatientAge     int                       `json:"patient_age"`
+	PatientGender  string                    `json:"patient_gender"`
+	DoctorID       string                    `json:"doctor_id"`
+	DoctorName     string                    `json:"doctor_name"`
+	Specialization string                    `json:"specialization"`
+	EncounterID    *string                   `json:"encounter_id"`
+	Notes          string                    `json:"notes"`
+	Status         string                    `json:"status"`
+	VoidReason     string                    `json:"void_reason,omitempty"`
+	VoidedAt       *string                   `json:"voided_at"`
+	CreatedAt      string                    `json:"created_at"`
+	Items          []models.PrescriptionItem `json:"items"`
+}
+
+const (
+	prescriptionColumns = `SELECT rx.prescription_id, p.token_id, p.fullname, p.age, p.gender, rx.doctor_id, d.fullname, COALESCE(d.specialization, ''), rx.encounter_id,
+	rx.notes, rx.status, rx.void_reason, rx.voided_at, rx.created_at`
+	prescriptionFrom = ` FROM prescription rx JOIN patient p ON p.patient_id=rx.patient_id JOIN doctor d ON d.doctor_id=rx.doctor_id `
+)
+
+func scanPrescription(row rowScanner, extra ...any) (prescriptionQueryResponse, error) {
+	var queryData prescriptionQueryResponse
+	dest := []any{&queryData.PrescriptionID, &queryData.TokenID, &queryData.PatientName, &queryData.PatientAge, &queryData.PatientGender, &queryData.DoctorID,
+		&queryData.DoctorName, &queryData.Specialization, &queryData.EncounterID, &queryData.Notes, &queryData.Status, &queryData.VoidReason, &queryData.VoidedAt,
+		&queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	queryData.Items = []models.PrescriptionItem{}
+	return queryData, err
+}
+
+// Reads items of the listed prescriptions in line order
+func (rec *Store) attachPrescriptionItems(ctx context.Context, prescriptions []prescriptionQueryResponse) error {
+
+	if len(prescriptions) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(prescriptions))
+	ids := make([]string, 0, len(prescriptions))
+	for i, prescription := range prescriptions {
+		index[prescription.PrescriptionID] = i
+		ids = append(ids, prescription.PrescriptionID)
+	}
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT prescription_id, drug, strength, route, dose, frequency, duration, quantity, instructions FROM prescription_item WHERE prescription_id::text = ANY($1) ORDER BY prescription_id, line_no", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var prescriptionID string
+		var item models.PrescriptionItem
+		if err = rows.Scan(&prescriptionID, &item.Drug, &item.Strength, &item.Route, &item.Dose, &item.Frequency, &item.Duration, &item.Quantity, &item.Instructions); err != nil {
+			return err
+		}
+		i := index[prescriptionID]
+		prescriptions[i].Items = append(prescriptions[i].Items, item)
+	}
+	return nil
+}
+
+// Queries INSERT to record a prescription of a doctor for one of their patients.
+// The prescription is linked to the patient's open visit when there is one, warnings the doctor overrode are recorded with it.
+func (rec *Store) CreatePrescription(tokenID string, prescriptionReq *models.Prescription, doctorID uuid.UUID, warnings []models.MedicationWarning) (uuid.UUID, error) {
+
+	var patientID, assignedTo, prescriptionID uuid.UUID
+	var encounterID uuid.NullUUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT patient_id, assigned_to FROM patient WHERE token_id::text=$1", tokenID).Scan(&patientID, &assignedTo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrPatientNotFound
+		}
+		return uuid.Nil, err
+	}
+	// doctors prescribe only for their own patients
+	if assignedTo != doctorID {
+		err = ErrPatientNotFound
+		return uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT encounter_id FROM encounter WHERE patient_id=$1 AND status='open'", patientID).Scan(&encounterID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO prescription (patient_id, doctor_id, encounter_id, notes) VALUES ($1, $2, $3, $4) RETURNING prescription_id",
+		patientID, doctorID, encounterID, prescriptionReq.Notes).Scan(&prescriptionID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	for i, item := range prescriptionReq.Items {
+		_, err = tx.ExecContext(ctx, "INSERT INTO prescription_item (prescription_id, line_no, drug, strength, route, dose, frequency, duration, quantity, instructions) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+			prescriptionID, i+1, item.Drug, item.Strength, item.Route, item.Dose, item.Frequency, item.Duration, item.Quantity, item.Instructions)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if len(warnings) > 0 {
+		err = recordMedicationOverride(ctx, tx, tokenID, doctorID, "prescription", uuid.NullUUID{UUID: prescriptionID, Valid: true}, warnings, prescriptionReq.OverrideReason)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	return prescriptionID, nil
+}
+
+// Queries prescriptions of a patient with their items, newest first
+func (rec *Store) GetPrescriptions(tokenID string, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, prescriptionColumns+", count(*) over() as total_records"+prescriptionFrom+"WHERE p.token_id::text=$1 ORDER BY rx.created_at DESC LIMIT $2 OFFSET $3", tokenID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allPrescriptionData := make([]prescriptionQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanPrescription(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allPrescriptionData = append(allPrescriptionData, queryData)
+	}
+	rows.Close()
+
+	if err = rec.attachPrescriptionItems(ctx, allPrescriptionData); err != nil {
+		return nil, err
+	}
+
+	responseData[0] = map[string][]prescriptionQueryResponse{"prescriptions_data": allPrescriptionData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries a single prescription with its items
+func (rec *Store) GetPrescription(prescriptionID string) (prescriptionQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	queryData, err := scanPrescription(rec.db.QueryRowContext(ctx, prescriptionColumns+prescriptionFrom+"WHERE rx.prescription_id::text=$1", prescriptionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return prescriptionQueryResponse{}, ErrPrescriptionNotFound
+		}
+		return prescriptionQueryResponse{}, err
+	}
+
+	prescriptions := []prescriptionQueryResponse{queryData}
+	if err = rec.attachPrescriptionItems(ctx, prescriptions); err != nil {
+		return prescriptionQueryResponse{}, err
+	}
+	return prescriptions[0], nil
+}
+
+// Queries UPDATE to void an active prescription, only the prescribing doctor can void it
+func (rec *Store) VoidPrescription(prescriptionID string, reason string, doctorID uuid.UUID) error {
+
+	var voided int
+	var status sql.NullString
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// current sees the row as it was before the update
+	err := rec.db.QueryRowContext(ctx, `WITH current AS (SELECT status FROM prescription WHERE prescription_id::text=$1 AND doctor_id=$3),
+		voided AS (UPDATE prescription SET status='voided', void_reason=$2, voided_at=CURRENT_TIMESTAMP WHERE prescription_id::text=$1 AND doctor_id=$3 AND status='active' RETURNING prescription_id)
+		SELECT (SELECT count(*) FROM voided), (SELECT status FROM current)`, prescriptionID, reason, doctorID).Scan(&voided, &status)
+	if err != nil {
+		return err
+	}
+	if voided == 1 {
+		return nil
+	}
+	if !status.Valid {
+		return ErrPrescriptionNotFound
+	}
+	return ErrPrescriptionVoided
+}
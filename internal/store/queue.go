@@ -0,0 +1,305 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrQueueEntryNotFound     = errors.New("no queue entry found for provided ID")
+	ErrAlreadyQueued          = errors.New("patient is already in the doctor's queue today")
+	ErrQueueEmpty             = errors.New("no patient is waiting in the queue")
+	ErrConsultationInProgress = errors.New("finish the current consultation before calling the next patient")
+	ErrQueueTransition        = errors.New("queue entry cannot move to the requested status")
+)
+
+type queueQueryResponse struct {
+	QueueEntryID string  `json:"queue_entry_id"`
+	Token        string  `json:"token"`
+	QueueNumber  int     `json:"queue_number"`
+	QueueDate    string  `json:"queue_date"`
+	Status       string  `json:"status"`
+	DoctorID     string  `json:"doctor_id"`
+	DoctorName   string  `json:"doctor_name"`
+	TokenID      string  `json:"token_id"`
+	PatientName  string  `json:"patient_name"`
+	IssuedBy     string  `json:"issued_by"`
+	CalledAt     *string `json:"called_at"`
+	StartedAt    *string `json:"started_at"`
+	FinishedAt   *string `json:"finished_at"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// Filters for listing a day's queue, zero values match everything
+type QueueFilter struct {
+	DoctorID uuid.NullUUID
+	Date     string // YYYY-MM-DD
+	Status   string
+}
+
+const (
+	queueColumns = `SELECT q.queue_entry_id, q.token, q.queue_number, to_char(q.queue_date, 'YYYY-MM-DD'), q.status, q.doctor_id, d.fullname, p.token_id, p.fullname,
+	q.issued_by, q.called_at, q.started_at, q.finished_at, q.created_at`
+	queueFrom = ` FROM queue_entry q JOIN doctor d ON d.doctor_id=q.doctor_id JOIN patient p ON p.patient_id=q.patient_id `
+)
+
+// Prefix of a doctor's queue numbers, initials of the specialization when not set
+const queuePrefixExpr = `COALESCE(queue_prefix, NULLIF(left(regexp_replace(initcap(COALESCE(specialization, '')), '[^A-Z]', '', 'g'), 6), ''), 'OPD')`
+
+func scanQueueEntry(row rowScanner, extra ...any) (queueQueryResponse, error) {
+	var queryData queueQueryResponse
+	dest := []any{&queryData.QueueEntryID, &queryData.Token, &queryData.QueueNumber, &queryData.QueueDate, &queryData.Status, &queryData.DoctorID, &queryData.DoctorName,
+		&queryData.TokenID, &queryData.PatientName, &queryData.IssuedBy, &queryData.CalledAt, &queryData.StartedAt, &queryData.FinishedAt, &queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return queryData, err
+}
+
+// Queries INSERT to place a patient in a doctor's queue for a day.
+// Numbers come from a per doctor, per day counter row so concurrent issues never share a number.
+func (rec *Store) IssueQueueToken(queueReq *models.QueueEntry, queueDate string, issuedBy uuid.UUID) (queueQueryResponse, error) {
+
+	var patientID, assignedTo uuid.UUID
+	var prefix string
+	var number int
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT patient_id, assigned_to FROM patient WHERE token_id::text=$1", queueReq.TokenID).Scan(&patientID, &assignedTo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrPatientNotFound
+		}
+		return queueQueryResponse{}, err
+	}
+
+	doctorID := queueReq.DoctorID
+	if doctorID == uuid.Nil {
+		doctorID = assignedTo
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT "+queuePrefixExpr+" FROM doctor WHERE doctor_id=$1 AND is_active", doctorID).Scan(&prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrDoctorNotFound
+		}
+		return queueQueryResponse{}, err
+	}
+
+	// the counter row stays locked until commit, a rolled back issue gives its number back
+	err = tx.QueryRowContext(ctx, `INSERT INTO queue_counter (doctor_id, queue_date, last_number) VALUES ($1, $2::date, 1)
+		ON CONFLICT (doctor_id, queue_date) DO UPDATE SET last_number=queue_counter.last_number + 1 RETURNING last_number`, doctorID, queueDate).Scan(&number)
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+
+	var entryID uuid.UUID
+	err = tx.QueryRowContext(ctx, "INSERT INTO queue_entry (doctor_id, patient_id, queue_date, queue_number, token, issued_by) VALUES ($1, $2, $3::date, $4, $5, $6) RETURNING queue_entry_id",
+		doctorID, patientID, queueDate, number, fmt.Sprintf("%s-%03d", prefix, number), issuedBy).Scan(&entryID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			err = ErrAlreadyQueued
+		}
+		return queueQueryResponse{}, err
+	}
+
+	queryData, err := scanQueueEntry(tx.QueryRowContext(ctx, queueColumns+queueFrom+"WHERE q.queue_entry_id=$1", entryID))
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Queries entries of the queue in order of their numbers
+func (rec *Store) GetQueue(filter QueueFilter, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, queueColumns+", count(*) over() as total_records"+queueFrom+`
+		WHERE q.queue_date=$1::date AND ($2::uuid IS NULL OR q.doctor_id=$2::uuid) AND ($3 = '' OR q.status=$3)
+		ORDER BY d.fullname, q.queue_number LIMIT $4 OFFSET $5`,
+		filter.Date, filter.DoctorID, filter.Status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allQueueData := make([]queueQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanQueueEntry(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allQueueData = append(allQueueData, queryData)
+	}
+
+	responseData[0] = map[string][]queueQueryResponse{"queue_data": allQueueData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Queries UPDATE to call the lowest waiting number of a doctor's queue for a day
+func (rec *Store) CallNextPatient(doctorID uuid.UUID, queueDate string) (queueQueryResponse, error) {
+
+	var busy bool
+	var entryID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM queue_entry WHERE doctor_id=$1 AND queue_date=$2::date AND status='in_consultation')", doctorID, queueDate).Scan(&busy)
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+	if busy {
+		err = ErrConsultationInProgress
+		return queueQueryResponse{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, `UPDATE queue_entry SET status='called', called_at=CURRENT_TIMESTAMP WHERE queue_entry_id=(
+		SELECT queue_entry_id FROM queue_entry WHERE doctor_id=$1 AND queue_date=$2::date AND status='waiting' ORDER BY queue_number LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING queue_entry_id`, doctorID, queueDate).Scan(&entryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrQueueEmpty
+		}
+		return queueQueryResponse{}, err
+	}
+
+	queryData, err := scanQueueEntry(tx.QueryRowContext(ctx, queueColumns+queueFrom+"WHERE q.queue_entry_id=$1", entryID))
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Queries UPDATE to move a queue entry to another status, restricted to entries of doctorScope when it is set
+func (rec *Store) UpdateQueueStatus(entryID string, status string, doctorScope uuid.NullUUID) (queueQueryResponse, error) {
+
+	var current string
+	var doctorID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT status, doctor_id FROM queue_entry WHERE queue_entry_id::text=$1 FOR UPDATE", entryID).Scan(&current, &doctorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrQueueEntryNotFound
+		}
+		return queueQueryResponse{}, err
+	}
+	if doctorScope.Valid && doctorID != doctorScope.UUID {
+		err = ErrQueueEntryNotFound
+		return queueQueryResponse{}, err
+	}
+	if !models.CanTransitionQueue(current, status) {
+		err = fmt.Errorf("%w, %s to %s", ErrQueueTransition, current, status)
+		return queueQueryResponse{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE queue_entry SET status=$1,
+		called_at=CASE WHEN $1='called' THEN CURRENT_TIMESTAMP WHEN $1='waiting' THEN NULL ELSE called_at END,
+		started_at=CASE WHEN $1='in_consultation' THEN CURRENT_TIMESTAMP ELSE started_at END,
+		finished_at=CASE WHEN $1 IN ('done', 'no_show') THEN CURRENT_TIMESTAMP ELSE finished_at END
+		WHERE queue_entry_id::text=$2`, status, entryID)
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+
+	queryData, err := scanQueueEntry(tx.QueryRowContext(ctx, queueColumns+queueFrom+"WHERE q.queue_entry_id::text=$1", entryID))
+	if err != nil {
+		return queueQueryResponse{}, err
+	}
+	return queryData, nil
+}
+
+// Queries UPDATE to set letters used in front of a doctor's queue numbers
+func (rec *Store) SetQueuePrefix(doctorID uuid.UUID, prefix string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "UPDATE doctor SET queue_prefix=$1 WHERE doctor_id=$2", prefix, doctorID)
+	if err != nil {
+		return err
+	}
+	if rowAffected, err := result.RowsAffected(); err != nil || rowAffected == 0 {
+		if err == nil {
+			err = ErrDoctorNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+)
+
+// Redis channel shared by all instances to announce queue changes
+const queueChannel = "medigo:queue:changed"
+
+// Wakes local listeners when a queue changes, listeners read the current board themselves
+type queueNotifier struct {
+	mu        sync.Mutex
+	listeners map[chan struct{}]struct{}
+	relay     sync.Once
+}
+
+// Tokens of one doctor's queue, no patient details are included
+type queueBoardResponse struct {
+	DoctorID   string                `json:"doctor_id"`
+	DoctorName string                `json:"doctor_name"`
+	NowServing []string              `json:"now_serving"`
+	Waiting    []queuePositionResult `json:"waiting"`
+}
+
+type queuePositionResult struct {
+	Token    string `json:"token"`
+	Position int    `json:"position"`
+}
+
+func (n *queueNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for listener := range n.listeners {
+		// a pending wake up already covers this change
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Registers a listener woken on every queue change, the returned function removes it
+func (rec *Store) SubscribeQueueChanges() (<-chan struct{}, func()) {
+
+	if rec.rdb != nil {
+		rec.queueEvents.relay.Do(rec.relayQueueChanges)
+	}
+
+	listener := make(chan struct{}, 1)
+	rec.queueEvents.mu.Lock()
+	rec.queueEvents.listeners[listener] = struct{}{}
+	rec.queueEvents.mu.Unlock()
+
+	return listener, func() {
+		rec.queueEvents.mu.Lock()
+		delete(rec.queueEvents.listeners, listener)
+		rec.queueEvents.mu.Unlock()
+	}
+}
+
+// Announces a queue change to listeners of every instance
+func (rec *Store) PublishQueueChange() {
+
+	if rec.rdb == nil {
+		rec.queueEvents.notify()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := rec.rdb.Publish(ctx, queueChannel, "changed").Err(); err != nil {
+		log.Println("Error publishing queue change: ", err)
+		rec.queueEvents.notify() // at least update displays of this instance
+	}
+}
+
+// Forwards queue changes published by any instance to local listeners
+func (rec *Store) relayQueueChanges() {
+	pubsub := rec.rdb.Subscribe(context.Background(), queueChannel)
+	go func() {
+		// the channel is kept open across reconnects by the redis client
+		for range pubsub.Channel() {
+			rec.queueEvents.notify()
+		}
+	}()
+}
+
+// Queries tokens being served and waiting for each doctor on a day, restricted to doctorID when it is set
+func (rec *Store) GetQueueBoard(queueDate string, doctorID uuid.NullUUID) ([]queueBoardResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, `SELECT q.doctor_id, d.fullname, q.token, q.status FROM queue_entry q JOIN doctor d ON d.doctor_id=q.doctor_id
+		WHERE q.queue_date=$1::date AND ($2::uuid IS NULL OR q.doctor_id=$2::uuid) AND q.status IN ('waiting', 'called', 'in_consultation')
+		ORDER BY d.fullname, q.doctor_id, q.queue_number`, queueDate, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	board := make([]queueBoardResponse, 0)
+	for rows.Next() {
+		var id, name, token, status string
+		if err = rows.Scan(&id, &name, &token, &status); err != nil {
+			return nil, err
+		}
+
+		if len(board) == 0 || board[len(board)-1].DoctorID != id {
+			board = append(board, queueBoardResponse{DoctorID: id, DoctorName: name, NowServing: []string{}, Waiting: []queuePositionResult{}})
+		}
+		doctorBoard := &board[len(board)-1]
+
+		if status == models.QueueStatusWaiting {
+			doctorBoard.Waiting = append(doctorBoard.Waiting, queuePositionResult{Token: token, Position: len(doctorBoard.Waiting) + 1})
+		} else {
+			doctorBoard.NowServing = append(doctorBoard.NowServing, token)
+		}
+	}
+	return board, nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrReportNotFound = errors.New("no report issued with provided verification code")
+
+// Report a verification code was issued for, Current tells whether the patient record changed since
+type ReportVerification struct {
+	Code            string `json:"code"`
+	TokenID         string `json:"token_id"`
+	RecordUpdatedAt string `json:"record_updated_at"`
+	GeneratedBy     string `json:"generated_by"`
+	GeneratedAt     string `json:"generated_at"`
+	Current         bool   `json:"current"`
+}
+
+// Queries INSERT of a verification code printed on a patient report, reissued codes keep their first record
+func (rec *Store) RecordReportVerification(code string, tokenID string, recordUpdatedAt string, generatedBy string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "INSERT INTO report_verification (code, patient_id, record_updated_at, generated_by) SELECT $1, patient_id, $3::timestamp, $4 FROM patient WHERE token_id::text=$2 ON CONFLICT (code) DO NOTHING",
+		code, tokenID, recordUpdatedAt, generatedBy)
+	if err != nil {
+		return err
+	}
+
+	// nothing inserted is fine for a reissued code, not for a patient deleted meanwhile
+	if inserted, err := result.RowsAffected(); err != nil || inserted > 0 {
+		return err
+	}
+	var exists bool
+	if err = rec.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM report_verification WHERE code=$1)", code).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPatientNotFound
+	}
+	return nil
+}
+
+// Queries the report a verification code was issued for
+func (rec *Store) GetReportVerification(code string) (ReportVerification, error) {
+
+	var verification ReportVerification
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "SELECT r.code, p.token_id::text, r.record_updated_at, r.generated_by, r.generated_at, p.updated_at = r.record_updated_at FROM report_verification r JOIN patient p ON p.patient_id = r.patient_id WHERE r.code=$1", code).
+		Scan(&verification.Code, &verification.TokenID, &verification.RecordUpdatedAt, &verification.GeneratedBy, &verification.GeneratedAt, &verification.Current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return verification, ErrReportNotFound
+		}
+		return verification, err
+	}
+	return verification, nil
+}
@@ -0,0 +1,621 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSlotUnavailable      = errors.New("requested time is not a bookable slot of the doctor")
+	ErrSlotTaken            = errors.New("doctor or patient already has an appointment at this time")
+	ErrAppointmentNotFound  = errors.New("no appointment found for provided ID")
+	ErrAppointmentNotBooked = errors.New("only booked appointments can be rescheduled or cancelled")
+	ErrLeaveNotFound        = errors.New("no leave found for provided ID")
+	ErrHolidayNotFound      = errors.New("no holiday found for provided date")
+)
+
+// Availability of a doctor on a day
+const (
+	DayWorking = "working"
+	DayOff     = "off"
+	DayLeave   = "on_leave"
+	DayHoliday = "holiday"
+)
+
+// wall clock layout used for TIMESTAMP parameters
+const timestampLayout = "2006-01-02 15:04:05"
+
+type slotResponse struct {
+	StartAt   string `json:"start_at"`
+	EndAt     string `json:"end_at"`
+	Available bool   `json:"available"`
+}
+
+type leaveQueryResponse struct {
+	LeaveID   string `json:"leave_id"`
+	DoctorID  string `json:"doctor_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+}
+
+type appointmentQueryResponse struct {
+	AppointmentID string `json:"appointment_id"`
+	DoctorID      string `json:"doctor_id"`
+	DoctorName    string `json:"doctor_name"`
+	TokenID       string `json:"token_id"`
+	PatientName   string `json:"patient_name"`
+	StartAt       string `json:"start_at"`
+	EndAt         string `json:"end_at"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+	CancelReason  string `json:"cancel_reason,omitempty"`
+	BookedBy      string `json:"booked_by"`
+	UpdatedAt     string `json:"updated_at"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type agendaResponse struct {
+	DoctorID     string                     `json:"doctor_id"`
+	Date         string                     `json:"date"`
+	Status       string                     `json:"status"`
+	Note         string                     `json:"note,omitempty"`
+	WorkingHours []models.WorkingHours      `json:"working_hours"`
+	Appointments []appointmentQueryResponse `json:"appointments"`
+	Slots        []slotResponse             `json:"slots"`
+}
+
+// Filters for listing appointments, zero values match everything
+type AppointmentFilter struct {
+	DoctorID uuid.NullUUID
+	TokenID  string
+	Date     string // YYYY-MM-DD
+	Status   string
+}
+
+// Implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type slot struct {
+	start time.Time
+	end   time.Time
+}
+
+// Splits working hours of a date into slots
+func daySlots(date time.Time, hours []models.WorkingHours) []slot {
+	var slots []slot
+	for _, window := range hours {
+		windowStart, _ := time.Parse(models.ClockLayout, window.StartTime)
+		windowEnd, _ := time.Parse(models.ClockLayout, window.EndTime)
+		start := date.Add(time.Duration(windowStart.Hour())*time.Hour + time.Duration(windowStart.Minute())*time.Minute)
+		end := date.Add(time.Duration(windowEnd.Hour())*time.Hour + time.Duration(windowEnd.Minute())*time.Minute)
+		length := time.Duration(window.SlotMinutes) * time.Minute
+
+		for slotStart := start; !slotStart.Add(length).After(end); slotStart = slotStart.Add(length) {
+			slots = append(slots, slot{start: slotStart, end: slotStart.Add(length)})
+		}
+	}
+	return slots
+}
+
+// Returns the slot starting exactly at start
+func slotAt(hours []models.WorkingHours, start time.Time) (slot, bool) {
+	date := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	for _, candidate := range daySlots(date, hours) {
+		if candidate.start.Equal(start) {
+			return candidate, true
+		}
+	}
+	return slot{}, false
+}
+
+// Reads availability and working hours of a doctor on a date
+func doctorDay(ctx context.Context, q querier, doctorID uuid.UUID, date time.Time) (string, string, []models.WorkingHours, error) {
+
+	var active bool
+	var note string
+	hours := make([]models.WorkingHours, 0)
+	day := date.Format(models.DateLayout)
+
+	err := q.QueryRowContext(ctx, "SELECT is_active FROM doctor WHERE doctor_id=$1", doctorID).Scan(&active)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil, err
+	}
+	if !active {
+		return "", "", nil, ErrDoctorNotFound
+	}
+
+	err = q.QueryRowContext(ctx, "SELECT name FROM clinic_holiday WHERE holiday_date=$1::date", day).Scan(&note)
+	if err == nil {
+		return DayHoliday, note, hours, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil, err
+	}
+
+	err = q.QueryRowContext(ctx, "SELECT reason FROM doctor_leave WHERE doctor_id=$1 AND $2::date BETWEEN start_date AND end_date LIMIT 1", doctorID, day).Scan(&note)
+	if err == nil {
+		return DayLeave, note, hours, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), slot_minutes FROM doctor_working_hours WHERE doctor_id=$1 AND weekday=$2 ORDER BY start_time", doctorID, int(date.Weekday()))
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var window models.WorkingHours
+		if err = rows.Scan(&window.Weekday, &window.StartTime, &window.EndTime, &window.SlotMinutes); err != nil {
+			return "", "", nil, err
+		}
+		hours = append(hours, window)
+	}
+
+	if len(hours) == 0 {
+		return DayOff, "", hours, nil
+	}
+	return DayWorking, "", hours, nil
+}
+
+// Maps exclusion constraint violations to ErrSlotTaken
+func appointmentError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23P01" {
+		return ErrSlotTaken
+	}
+	return err
+}
+
+// Queries weekly working hours of a doctor
+func (rec *Store) GetWorkingHours(doctorID uuid.UUID) ([]models.WorkingHours, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), slot_minutes FROM doctor_working_hours WHERE doctor_id=$1 ORDER BY weekday, start_time", doctorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := make([]models.WorkingHours, 0)
+	for rows.Next() {
+		var window models.WorkingHours
+		if err = rows.Scan(&window.Weekday, &window.StartTime, &window.EndTime, &window.SlotMinutes); err != nil {
+			return nil, err
+		}
+		hours = append(hours, window)
+	}
+	return hours, nil
+}
+
+// Replaces the weekly working hours of a doctor, booked appointments are kept
+func (rec *Store) SetWorkingHours(doctorID uuid.UUID, hours []models.WorkingHours) error {
+
+	var exists bool
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM doctor WHERE doctor_id=$1 AND is_active)", doctorID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err = ErrDoctorNotFound
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM doctor_working_hours WHERE doctor_id=$1", doctorID); err != nil {
+		return err
+	}
+
+	for _, window := range hours {
+		_, err = tx.ExecContext(ctx, "INSERT INTO doctor_working_hours (doctor_id, weekday, start_time, end_time, slot_minutes) VALUES ($1, $2, $3::time, $4::time, $5)",
+			doctorID, window.Weekday, window.StartTime, window.EndTime, window.SlotMinutes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Queries INSERT to record leave of a doctor
+func (rec *Store) AddLeave(doctorID uuid.UUID, leaveReq *models.Leave, createdBy uuid.UUID) (uuid.UUID, error) {
+
+	var leaveID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "INSERT INTO doctor_leave (doctor_id, start_date, end_date, reason, created_by) SELECT doctor_id, $2::date, $3::date, $4, $5 FROM doctor WHERE doctor_id=$1 AND is_active RETURNING leave_id",
+		doctorID, leaveReq.StartDate, leaveReq.EndDate, leaveReq.Reason, createdBy).Scan(&leaveID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrDoctorNotFound
+		}
+		return uuid.Nil, err
+	}
+	return leaveID, nil
+}
+
+// Queries leave of a doctor ending on or after fromDate
+func (rec *Store) GetLeaves(doctorID uuid.UUID, fromDate string) ([]leaveQueryResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT leave_id, doctor_id, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), reason, created_by, created_at FROM doctor_leave WHERE doctor_id=$1 AND end_date >= $2::date ORDER BY start_date", doctorID, fromDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leaves := make([]leaveQueryResponse, 0)
+	for rows.Next() {
+		var queryData leaveQueryResponse
+		if err = rows.Scan(&queryData.LeaveID, &queryData.DoctorID, &queryData.StartDate, &queryData.EndDate, &queryData.Reason, &queryData.CreatedBy, &queryData.CreatedAt); err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, queryData)
+	}
+	return leaves, nil
+}
+
+// Queries DELETE to remove leave of a doctor
+func (rec *Store) DeleteLeave(doctorID uuid.UUID, leaveID string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "DELETE FROM doctor_leave WHERE doctor_id=$1 AND leave_id::text=$2", doctorID, leaveID)
+	if err != nil {
+		return err
+	}
+	if rowAffected, err := result.RowsAffected(); err != nil || rowAffected == 0 {
+		if err == nil {
+			err = ErrLeaveNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Queries INSERT to add a clinic holiday, renames it when the date already exists
+func (rec *Store) AddHoliday(holidayReq *models.Holiday) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	_, err := rec.db.ExecContext(ctx, "INSERT INTO clinic_holiday (holiday_date, name) VALUES ($1::date, $2) ON CONFLICT (holiday_date) DO UPDATE SET name=EXCLUDED.name", holidayReq.Date, holidayReq.Name)
+	return err
+}
+
+// Queries clinic holidays on or after fromDate
+func (rec *Store) GetHolidays(fromDate string) ([]models.Holiday, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT to_char(holiday_date, 'YYYY-MM-DD'), name FROM clinic_holiday WHERE holiday_date >= $1::date ORDER BY holiday_date", fromDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := make([]models.Holiday, 0)
+	for rows.Next() {
+		var holiday models.Holiday
+		if err = rows.Scan(&holiday.Date, &holiday.Name); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, nil
+}
+
+// Queries DELETE to remove a clinic holiday
+func (rec *Store) DeleteHoliday(date string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	result, err := rec.db.ExecContext(ctx, "DELETE FROM clinic_holiday WHERE holiday_date=$1::date", date)
+	if err != nil {
+		return err
+	}
+	if rowAffected, err := result.RowsAffected(); err != nil || rowAffected == 0 {
+		if err == nil {
+			err = ErrHolidayNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+const appointmentSelect = `SELECT a.appointment_id, a.doctor_id, d.fullname, p.token_id, p.fullname, to_char(a.start_at, 'YYYY-MM-DD"T"HH24:MI'), to_char(a.end_at, 'YYYY-MM-DD"T"HH24:MI'),
+	a.status, a.reason, a.cancel_reason, a.booked_by, a.updated_at, a.created_at`
+
+const appointmentFrom = ` FROM appointment a JOIN doctor d ON d.doctor_id=a.doctor_id JOIN patient p ON p.patient_id=a.patient_id `
+
+func scanAppointment(row rowScanner, extra ...any) (appointmentQueryResponse, error) {
+	var queryData appointmentQueryResponse
+	dest := []any{&queryData.AppointmentID, &queryData.DoctorID, &queryData.DoctorName, &queryData.TokenID, &queryData.PatientName, &queryData.StartAt, &queryData.EndAt,
+		&queryData.Status, &queryData.Reason, &queryData.CancelReason, &queryData.BookedBy, &queryData.UpdatedAt, &queryData.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return queryData, err
+}
+
+// Queries free and booked slots of a doctor on a date, slots starting before notBefore are unavailable
+func (rec *Store) GetDoctorSlots(doctorID uuid.UUID, date time.Time, notBefore time.Time) (agendaResponse, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	agenda := agendaResponse{DoctorID: doctorID.String(), Date: date.Format(models.DateLayout), Appointments: []appointmentQueryResponse{}, Slots: []slotResponse{}}
+
+	status, note, hours, err := doctorDay(ctx, rec.db, doctorID, date)
+	if err != nil {
+		return agenda, err
+	}
+	agenda.Status, agenda.Note, agenda.WorkingHours = status, note, hours
+
+	// appointments are listed even when the day later became leave or a holiday
+	rows, err := rec.db.QueryContext(ctx, appointmentSelect+appointmentFrom+"WHERE a.doctor_id=$1 AND a.start_at >= $2::date AND a.start_at < $2::date + 1 ORDER BY a.start_at", doctorID, agenda.Date)
+	if err != nil {
+		return agenda, err
+	}
+	defer rows.Close()
+
+	var booked []slot
+	for rows.Next() {
+		queryData, err := scanAppointment(rows)
+		if err != nil {
+			return agenda, err
+		}
+		agenda.Appointments = append(agenda.Appointments, queryData)
+		if queryData.Status == models.AppointmentStatusBooked {
+			start, _ := time.Parse(models.DateTimeLayout, queryData.StartAt)
+			end, _ := time.Parse(models.DateTimeLayout, queryData.EndAt)
+			booked = append(booked, slot{start: start, end: end})
+		}
+	}
+
+	if status != DayWorking {
+		return agenda, nil
+	}
+
+	for _, candidate := range daySlots(date, hours) {
+		available := !candidate.start.Before(notBefore)
+		for _, taken := range booked {
+			if candidate.start.Before(taken.end) && taken.start.Before(candidate.end) {
+				available = false
+				break
+			}
+		}
+		agenda.Slots = append(agenda.Slots, slotResponse{StartAt: candidate.start.Format(models.DateTimeLayout), EndAt: candidate.end.Format(models.DateTimeLayout), Available: available})
+	}
+
+	return agenda, nil
+}
+
+// Queries INSERT to book a slot, overlapping bookings are rejected by the database
+func (rec *Store) BookAppointment(appointmentReq *models.Appointment, start time.Time, bookedBy uuid.UUID) (uuid.UUID, error) {
+
+	var appointmentID, patientID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT patient_id FROM patient WHERE token_id::text=$1", appointmentReq.TokenID).Scan(&patientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrPatientNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	booking, err := rec.bookableSlot(ctx, tx, appointmentReq.DoctorID, start)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO appointment (doctor_id, patient_id, start_at, end_at, reason, booked_by) VALUES ($1, $2, $3::timestamp, $4::timestamp, $5, $6) RETURNING appointment_id",
+		appointmentReq.DoctorID, patientID, booking.start.Format(timestampLayout), booking.end.Format(timestampLayout), appointmentReq.Reason, bookedBy).Scan(&appointmentID)
+	if err != nil {
+		err = appointmentError(err)
+		return uuid.Nil, err
+	}
+
+	return appointmentID, nil
+}
+
+// Checks that start is a slot of the doctor on a working day
+func (rec *Store) bookableSlot(ctx context.Context, tx *sql.Tx, doctorID uuid.UUID, start time.Time) (slot, error) {
+
+	date := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	status, note, hours, err := doctorDay(ctx, tx, doctorID, date)
+	if err != nil {
+		return slot{}, err
+	}
+	if status != DayWorking {
+		if note != "" {
+			return slot{}, fmt.Errorf("%w, doctor is %s (%s)", ErrSlotUnavailable, status, note)
+		}
+		return slot{}, fmt.Errorf("%w, doctor is %s", ErrSlotUnavailable, status)
+	}
+
+	booking, ok := slotAt(hours, start)
+	if !ok {
+		return slot{}, fmt.Errorf("%w, pick a slot from the doctor's slots", ErrSlotUnavailable)
+	}
+	return booking, nil
+}
+
+// Queries UPDATE to move a booked appointment to another slot of the same doctor
+func (rec *Store) RescheduleAppointment(appointmentID string, start time.Time) error {
+
+	var doctorID uuid.UUID
+	var status string
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Transaction rollback error: %v\n", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Printf("Transaction commit error: %v\n", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT doctor_id, status FROM appointment WHERE appointment_id::text=$1 FOR UPDATE", appointmentID).Scan(&doctorID, &status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAppointmentNotFound
+		}
+		return err
+	}
+	if status != models.AppointmentStatusBooked {
+		err = ErrAppointmentNotBooked
+		return err
+	}
+
+	booking, err := rec.bookableSlot(ctx, tx, doctorID, start)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE appointment SET start_at=$1::timestamp, end_at=$2::timestamp WHERE appointment_id::text=$3",
+		booking.start.Format(timestampLayout), booking.end.Format(timestampLayout), appointmentID)
+	if err != nil {
+		err = appointmentError(err)
+		return err
+	}
+
+	return nil
+}
+
+// Queries UPDATE to cancel a booked appointment, freeing its slot
+func (rec *Store) CancelAppointment(appointmentID string, reason string) error {
+
+	var cancelled int
+	var status sql.NullString
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// current sees the row as it was before the update
+	err := rec.db.QueryRowContext(ctx, `WITH current AS (SELECT status FROM appointment WHERE appointment_id::text=$1),
+		cancelled AS (UPDATE appointment SET status='cancelled', cancel_reason=$2 WHERE appointment_id::text=$1 AND status='booked' RETURNING appointment_id)
+		SELECT (SELECT count(*) FROM cancelled), (SELECT status FROM current)`, appointmentID, reason).Scan(&cancelled, &status)
+	if err != nil {
+		return err
+	}
+	if cancelled == 1 {
+		return nil
+	}
+	if !status.Valid {
+		return ErrAppointmentNotFound
+	}
+	return ErrAppointmentNotBooked
+}
+
+// Queries list of appointments ordered by time
+func (rec *Store) GetAppointments(filter AppointmentFilter, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := rec.db.QueryContext(ctx, appointmentSelect+", count(*) over() as total_records"+appointmentFrom+`
+		WHERE ($1::uuid IS NULL OR a.doctor_id=$1::uuid) AND ($2 = '' OR p.token_id::text=$2) AND ($3 = '' OR a.status=$3)
+		AND (NULLIF($4, '')::date IS NULL OR (a.start_at >= NULLIF($4, '')::date AND a.start_at < NULLIF($4, '')::date + 1))
+		ORDER BY a.start_at LIMIT $5 OFFSET $6`,
+		filter.DoctorID, filter.TokenID, filter.Status, filter.Date, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allAppointmentData := make([]appointmentQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanAppointment(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		allAppointmentData = append(allAppointmentData, queryData)
+	}
+
+	responseData[0] = map[string][]appointmentQueryResponse{"appointments_data": allAppointmentData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
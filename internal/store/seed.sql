@@ -1,140 +1,6 @@
--- Create ENUM type for staff role
--- CREATE TYPE IF NOT EXISTS role AS ENUM ('doctor', 'receptionist');
-DO $$ 
-BEGIN
-    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'role') THEN
-        CREATE TYPE role AS ENUM ('doctor', 'receptionist');
-    END IF;
-END $$;
-
--- Create ENUM type for patient's gender
--- CREATE TYPE IF NOT EXISTS gender AS ENUM ('male', 'female', 'other');
-DO $$ 
-BEGIN
-    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'gender') THEN
-        CREATE TYPE gender AS ENUM ('male', 'female', 'other');
-    END IF;
-END $$;
-
--- DROP TABLE IF EXISTS doctor;
-
--- Create table doctor
-CREATE TABLE IF NOT EXISTS doctor (
-    doctor_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
-    fullname VARCHAR(255) NOT NULL,
-    email VARCHAR(255) NOT NULL UNIQUE,
-    role   role NOT NULL DEFAULT 'doctor',
-    specialization TEXT NULL,
-    password_hash TEXT NOT NULL UNIQUE,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create trigger to update updated_at column for doctor table
-CREATE OR REPLACE FUNCTION doctor_set_updated_at()
-RETURNS TRIGGER AS $$
-BEGIN
-  NEW.updated_at := CURRENT_TIMESTAMP;
-  RETURN NEW;
-END;
-$$ LANGUAGE plpgsql;
-
--- Attach trigger to updated_at column for doctor table
-DO $$
-BEGIN
-    IF NOT EXISTS (
-        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_doctor_set_updated_at'
-    ) THEN
-        CREATE TRIGGER trigger_doctor_set_updated_at
-        BEFORE UPDATE ON doctor
-        FOR EACH ROW
-        EXECUTE FUNCTION doctor_set_updated_at();
-    END IF;
-END
-$$;
-
--- DROP TABLE IF EXISTS staff;
-
--- Create table staff
-CREATE TABLE IF NOT EXISTS staff (
-    staff_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
-    fullname VARCHAR(255) NOT NULL,
-    email VARCHAR(255) NOT NULL UNIQUE,
-    role   role NOT NULL DEFAULT 'receptionist',
-    password_hash TEXT NOT NULL UNIQUE,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create trigger to update updated_at column for staff table
-CREATE OR REPLACE FUNCTION staff_set_updated_at()
-RETURNS TRIGGER AS $$
-BEGIN
-  NEW.updated_at := CURRENT_TIMESTAMP;
-  RETURN NEW;
-END;
-$$ LANGUAGE plpgsql;
-
--- Attach trigger to updated_at column for staff table
-DO $$
-BEGIN
-    IF NOT EXISTS (
-        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_staff_set_updated_at'
-    ) THEN
-        CREATE TRIGGER trigger_staff_set_updated_at
-        BEFORE UPDATE ON staff
-        FOR EACH ROW
-        EXECUTE FUNCTION staff_set_updated_at();
-    END IF;
-END
-$$;
-
--- Create table patient
-CREATE TABLE IF NOT EXISTS patient (
-    patient_id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE PRIMARY KEY,
-    fullname VARCHAR(255) NOT NULL,
-    gender gender NOT NULL,
-    age INT NOT NULL,
-    contact VARCHAR(10) NOT NULL,
-    symptoms TEXT NULL,
-    treatment TEXT NULL DEFAULT '',
-    assigned_to UUID NOT NULL,
-    created_by UUID NOT NULL,
-    token_id INT NOT NULL UNIQUE DEFAULT floor(random() * 900000 + 100000)::int,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    CONSTRAINT fk_assigned_to FOREIGN KEY (assigned_to) REFERENCES doctor(doctor_id) ON DELETE CASCADE,
-    CONSTRAINT fk_created_by FOREIGN KEY (created_by) REFERENCES staff(staff_id) ON DELETE CASCADE
-);
-
--- Create trigger to update updated_at column for staff table
-CREATE OR REPLACE FUNCTION set_patient_updated_at()
-RETURNS TRIGGER AS $$
-BEGIN
-  NEW.updated_at := CURRENT_TIMESTAMP;
-  RETURN NEW;
-END;
-$$ LANGUAGE plpgsql;
-
--- Attach trigger to updated_at column for staff table
-DO $$
-BEGIN
-    IF NOT EXISTS (
-        SELECT 1 FROM pg_trigger WHERE tgname = 'trigger_set_patient_updated_at'
-    ) THEN
-        CREATE TRIGGER trigger_set_patient_updated_at
-        BEFORE UPDATE ON patient
-        FOR EACH ROW
-        EXECUTE FUNCTION set_patient_updated_at();
-    END IF;
-END
-$$;
-
-
--- Clear existing data before inserting new data
-TRUNCATE TABLE doctor CASCADE;
-TRUNCATE TABLE staff CASCADE;
-TRUNCATE TABLE patient CASCADE;
+-- Sample doctors, staff members and patients for local development.
+-- Loaded only when ENVIRONMENT is 'development', never in production.
+-- Rows that already exist are left untouched so the seed can run on every boot.
 
 -- Insert data into the doctor table
 
@@ -143,7 +9,8 @@ VALUES ('28844ae6-d482-441b-abd6-09db2a64c707', 'Harshit Raj', 'harshitraj@medi.
 -- harshit@medigo
 ('84576c8c-9e88-494d-bdd9-e855247e11df', 'Raj Sinha', 'rajsinha@medi.go', 'geriatrics', '$2a$10$EuzlK9mLYrvPEJl1pAujWO86eOVi1dlQ92YPXrCtkqUrQglA2uPGK', '2025-05-13 11:16:06.174262', '2025-05-13 11:16:06.174262'),
 -- rajsinha@medigo
-('e58056e6-28e1-43de-afda-8c6e9363ddda', 'Lucy Mountain', 'mountain.lucy@medi.go', 'pediatrics', '$2a$10$noNf.Xr7oDSs9RqO9SCo8.8bC0I1RJBLPcAh7Vsg1BRBrhFKRRbW.', '2025-05-13 11:16:06.174262', '2025-05-13 11:16:06.174262');
+('e58056e6-28e1-43de-afda-8c6e9363ddda', 'Lucy Mountain', 'mountain.lucy@medi.go', 'pediatrics', '$2a$10$noNf.Xr7oDSs9RqO9SCo8.8bC0I1RJBLPcAh7Vsg1BRBrhFKRRbW.', '2025-05-13 11:16:06.174262', '2025-05-13 11:16:06.174262')
+ON CONFLICT DO NOTHING;
 -- mountain.lucy@medigo
 
 
@@ -152,7 +19,8 @@ VALUES ('28844ae6-d482-441b-abd6-09db2a64c707', 'Harshit Raj', 'harshitraj@medi.
 INSERT INTO staff (staff_id, fullname, email, password_hash, updated_at, created_at) 
 VALUES ('f4a9c66b-8e38-419b-93c4-215d5cefb318', 'Kunal Kumar', 'kumarkunal@medi.go', '$2a$10$PK5bsZmREcQQzUjDMDerjedK5WnDqkEn65.qxQBKMkEa.gspzewOy', '2025-05-13 11:16:06.174262', '2025-05-13 11:16:06.174262'),
 -- kumarkunal@medigo
-('9746be12-07b7-42a3-b8ab-7d1f209b63d7', 'Priya Patel', 'priya@medi.go', '$2a$10$rKPPL4QzONHtY3sFxPS3.Oq5M/I.dDVZAClXeGptfLuTw59LxPvCu', '2025-05-13 11:16:06.174262', '2025-05-13 11:16:06.174262');
+('9746be12-07b7-42a3-b8ab-7d1f209b63d7', 'Priya Patel', 'priya@medi.go', '$2a$10$rKPPL4QzONHtY3sFxPS3.Oq5M/I.dDVZAClXeGptfLuTw59LxPvCu', '2025-05-13 11:16:06.174262', '2025-05-13 11:16:06.174262')
+ON CONFLICT DO NOTHING;
 -- priya@medigo
 
 
@@ -169,4 +37,34 @@ VALUES ('cc2c2a7d-2e21-4f59-b7b8-bd9e5e4cf04c', 'Ananya Desai', 'female', 8, '78
 
 ('1501120c-5f2e-4c83-9de3-01be35edbb5f', 'Ava Wilson', 'female', 45, '3625147894', 'Ava Wilson, a 45-year-old female, reports experiencing frequent episodes of heartburn and acid reflux over the past several weeks. She notes that the discomfort usually worsens after eating spicy or fatty foods and is often more noticeable at night when lying down. She occasionally feels a burning sensation in her chest and a sour taste in her mouth. Ava is requesting an appointment with a general physician to discuss her symptoms and explore possible treatment options.', '28844ae6-d482-441b-abd6-09db2a64c707', '9746be12-07b7-42a3-b8ab-7d1f209b63d7', 678720, '2025-05-13 11:16:06.174262','2025-05-13 11:16:06.174262'),
 
-('af188a46-236a-40e5-8186-edcdf6e34d9b', 'Benjamin Carter', 'male', 76, '9848751236', 'Benjamin Carter, a 76-year-old male, has been experiencing increasing joint pain and stiffness, particularly in his knees and lower back, over the past few months. He reports difficulty with mobility, especially in the mornings, and occasional swelling in his joints after prolonged sitting or walking. He also mentions feeling more fatigued than usual and experiencing trouble sleeping due to discomfort. Benjamin is seeking an evaluation with a geriatrics specialist to manage his symptoms and improve his quality of life.', '84576c8c-9e88-494d-bdd9-e855247e11df', '9746be12-07b7-42a3-b8ab-7d1f209b63d7', 477611, '2025-05-13 11:16:06.174262','2025-05-13 11:16:06.174262');
+('af188a46-236a-40e5-8186-edcdf6e34d9b', 'Benjamin Carter', 'male', 76, '9848751236', 'Benjamin Carter, a 76-year-old male, has been experiencing increasing joint pain and stiffness, particularly in his knees and lower back, over the past few months. He reports difficulty with mobility, especially in the mornings, and occasional swelling in his joints after prolonged sitting or walking. He also mentions feeling more fatigued than usual and experiencing trouble sleeping due to discomfort. Benjamin is seeking an evaluation with a geriatrics specialist to manage his symptoms and improve his quality of life.', '84576c8c-9e88-494d-bdd9-e855247e11df', '9746be12-07b7-42a3-b8ab-7d1f209b63d7', 477611, '2025-05-13 11:16:06.174262','2025-05-13 11:16:06.174262')
+ON CONFLICT DO NOTHING;
+
+
+-- Open a visit for every seeded patient that has none yet
+
+INSERT INTO encounter (patient_id, doctor_id, visit_date, chief_complaint, treatment, opened_by, updated_at, created_at)
+SELECT patient_id, assigned_to, created_at::date, COALESCE(symptoms, ''), COALESCE(treatment, ''), created_by, updated_at, created_at
+FROM patient
+WHERE NOT EXISTS (SELECT 1 FROM encounter WHERE encounter.patient_id = patient.patient_id);
+
+
+-- Insert a sample drug catalogue and interaction rules
+
+INSERT INTO drug (name, generic_name, drug_class, form, strength)
+VALUES ('Crocin', 'paracetamol', 'analgesic', 'tablet', '500 mg'),
+('Ecosprin', 'aspirin', 'nsaid', 'tablet', '75 mg'),
+('Brufen', 'ibuprofen', 'nsaid', 'tablet', '400 mg'),
+('Warf', 'warfarin', 'anticoagulant', 'tablet', '5 mg'),
+('Mox', 'amoxicillin', 'penicillin', 'capsule', '500 mg'),
+('Azee', 'azithromycin', 'macrolide', 'tablet', '500 mg'),
+('Pan', 'pantoprazole', 'proton pump inhibitor', 'tablet', '40 mg'),
+('Telma', 'telmisartan', 'angiotensin receptor blocker', 'tablet', '40 mg')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO drug_interaction (subject_a, subject_b, severity, description)
+VALUES ('aspirin', 'warfarin', 'major', 'Increased risk of bleeding'),
+('nsaid', 'warfarin', 'major', 'NSAIDs increase the risk of bleeding with warfarin'),
+('azithromycin', 'warfarin', 'moderate', 'May raise INR, monitor closely'),
+('angiotensin receptor blocker', 'nsaid', 'moderate', 'Reduced antihypertensive effect and risk of kidney injury')
+ON CONFLICT DO NOTHING;
@@ -5,23 +5,37 @@ import (
 	"embed"
 
 	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/filestore"
 	"github.com/redis/go-redis/v9"
 )
 
-//go:embed schema.sql
-var SchemaFS embed.FS
+// Numbered up/down schema migrations, applied in order by the migrator
+//
+//go:embed migrations/*.sql
+var MigrationsFS embed.FS
+
+// Sample data for local development
+//
+//go:embed seed.sql
+var SeedFS embed.FS
 
 type Store struct {
 	db  *sql.DB
 	rdb *redis.Client
+
+	queueEvents *queueNotifier
+	files       filestore.FileStorage // attachments, nil when not configured
 }
 
 // Constructor method patient store
 func NewStore(db *sql.DB, rdb *redis.Client) *Store {
-	return &Store{db: db, rdb: rdb}
+	return &Store{db: db, rdb: rdb, queueEvents: &queueNotifier{listeners: make(map[chan struct{}]struct{})}}
 }
 
 type LoginResponse struct {
 	UserID         uuid.UUID `json:"userid"`
 	HashedPassword string    `json:"hashpassword"`
+	Role           string    `json:"role"`
+	MFARequired    bool      `json:"mfa_required"`
+	MFAEnabled     bool      `json:"mfa_enabled"`
 }
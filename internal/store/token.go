@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/auth"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, token family revoked")
+)
+
+type RefreshTokenOwner struct {
+	UserID   uuid.UUID `json:"userid"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	FamilyID uuid.UUID `json:"family_id"`
+}
+
+// Queries INSERT to store first refresh token of a new token family
+func (rec *Store) SaveRefreshToken(tokenHash string, owner RefreshTokenOwner, expiresAt time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	_, err := rec.db.ExecContext(ctx, "INSERT INTO refresh_token (token_hash, family_id, user_id, email, role, expires_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		tokenHash, owner.FamilyID, owner.UserID, owner.Email, owner.Role, expiresAt)
+	if err != nil {
+		log.Println("Error while inserting refresh token ", err)
+		return err
+	}
+	return nil
+}
+
+// Exchanges a refresh token for a new one within the same family.
+// Presenting a token that was already rotated revokes the whole family.
+func (rec *Store) RotateRefreshToken(oldTokenHash string, newTokenHash string, expiresAt time.Time) (RefreshTokenOwner, error) {
+
+	var owner RefreshTokenOwner
+	var tokenExpiry time.Time
+	var rotatedAt, revokedAt sql.NullTime
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return owner, err
+	}
+
+	defer func() {
+		if err != nil && !errors.Is(err, ErrRefreshTokenReused) {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "SELECT family_id, user_id, email, role, expires_at, rotated_at, revoked_at FROM refresh_token WHERE token_hash=$1 FOR UPDATE", oldTokenHash).Scan(
+		&owner.FamilyID, &owner.UserID, &owner.Email, &owner.Role, &tokenExpiry, &rotatedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrRefreshTokenInvalid
+		}
+		return RefreshTokenOwner{}, err
+	}
+
+	// token was already exchanged once - treat as stolen and revoke the family
+	if rotatedAt.Valid && !revokedAt.Valid {
+		if err = rec.revokeFamily(ctx, tx, owner.FamilyID); err != nil {
+			return RefreshTokenOwner{}, err
+		}
+		log.Printf("Refresh token reuse detected for family %s", owner.FamilyID)
+		err = ErrRefreshTokenReused
+		return RefreshTokenOwner{}, err
+	}
+
+	if revokedAt.Valid || time.Now().After(tokenExpiry) {
+		err = ErrRefreshTokenInvalid
+		return RefreshTokenOwner{}, err
+	}
+
+	// role and email are read from the account, changes made since login apply on the next rotation
+	var active bool
+	err = tx.QueryRowContext(ctx, `SELECT role::text, email, is_active FROM doctor WHERE doctor_id=$1
+		UNION ALL SELECT role::text, email, is_active FROM staff WHERE staff_id=$1`, owner.UserID).Scan(&owner.Role, &owner.Email, &active)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrRefreshTokenInvalid
+		}
+		return RefreshTokenOwner{}, err
+	}
+	if !active {
+		err = ErrRefreshTokenInvalid
+		return RefreshTokenOwner{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_token SET rotated_at=CURRENT_TIMESTAMP WHERE token_hash=$1", oldTokenHash)
+	if err != nil {
+		return RefreshTokenOwner{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO refresh_token (token_hash, family_id, user_id, email, role, expires_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		newTokenHash, owner.FamilyID, owner.UserID, owner.Email, owner.Role, expiresAt)
+	if err != nil {
+		return RefreshTokenOwner{}, err
+	}
+
+	return owner, nil
+}
+
+// Revokes the token family a refresh token belongs to, only if it is owned by userID
+func (rec *Store) RevokeRefreshToken(tokenHash string, userID uuid.UUID) error {
+
+	var familyID uuid.UUID
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "SELECT family_id FROM refresh_token WHERE token_hash=$1 AND user_id=$2", tokenHash, userID).Scan(&familyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRefreshTokenInvalid
+		}
+		return err
+	}
+
+	return rec.RevokeTokenFamily(familyID)
+}
+
+// Revokes every refresh token of a family along with access tokens issued from it
+func (rec *Store) RevokeTokenFamily(familyID uuid.UUID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = rec.revokeFamily(ctx, tx, familyID); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Println("Transaction rollback error: ", rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Revokes every token family of an account within the caller's transaction
+func (rec *Store) revokeUserFamilies(ctx context.Context, tx *sql.Tx, userID string) error {
+
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT family_id FROM refresh_token WHERE user_id::text=$1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		return err
+	}
+	var familyIDs []uuid.UUID
+	for rows.Next() {
+		var familyID uuid.UUID
+		if err = rows.Scan(&familyID); err != nil {
+			rows.Close()
+			return err
+		}
+		familyIDs = append(familyIDs, familyID)
+	}
+	rows.Close()
+
+	for _, familyID := range familyIDs {
+		if err = rec.revokeFamily(ctx, tx, familyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rec *Store) revokeFamily(ctx context.Context, tx *sql.Tx, familyID uuid.UUID) error {
+
+	_, err := tx.ExecContext(ctx, "UPDATE refresh_token SET revoked_at=CURRENT_TIMESTAMP WHERE family_id=$1 AND revoked_at IS NULL", familyID)
+	if err != nil {
+		return err
+	}
+
+	// access tokens of the family stay denied until the longest one could expire
+	accessTTL, _ := auth.TokenLifetime()
+	return rec.revokeTokenID(ctx, tx, familyID.String(), time.Now().Add(accessTTL))
+}
+
+// Adds an access token ID to the denylist until it expires
+func (rec *Store) RevokeAccessToken(tokenID string, expiresAt time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = rec.revokeTokenID(ctx, tx, tokenID, expiresAt); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Println("Transaction rollback error: ", rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (rec *Store) revokeTokenID(ctx context.Context, tx *sql.Tx, tokenID string, expiresAt time.Time) error {
+
+	// clear out entries that can no longer match a valid token
+	_, err := tx.ExecContext(ctx, "DELETE FROM revoked_token WHERE expires_at < CURRENT_TIMESTAMP")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO revoked_token (token_id, expires_at) VALUES ($1, $2) ON CONFLICT (token_id) DO UPDATE SET expires_at = GREATEST(revoked_token.expires_at, EXCLUDED.expires_at)", tokenID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Checks whether any of the token IDs is on the denylist
+func (rec *Store) IsTokenRevoked(tokenIDs ...string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	var revoked bool
+	err := rec.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM revoked_token WHERE token_id = ANY($1) AND expires_at > CURRENT_TIMESTAMP)", pq.Array(tokenIDs)).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
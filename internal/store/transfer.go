@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version of the export format, bumped when records change incompatibly.
+// Version 2 adds the records of every clinical, scheduling and billing table.
+const ExportFormatVersion = 2
+
+// Tables exported row for row after the accounts, patients and visits, parents before children.
+// Sessions, login throttling, MFA secrets and API keys are not exported, attachment files stay in file storage.
+var exportTables = []string{
+	"doctor_working_hours", "doctor_leave", "clinic_holiday", "appointment", "queue_counter", "queue_entry",
+	"drug", "drug_interaction", "prescription", "prescription_item", "medication_override",
+	"patient_allergy", "patient_condition", "patient_surgery", "patient_family_history",
+	"vital_range", "vital_sign", "lab_order", "lab_result", "clinical_note", "clinical_note_version", "attachment",
+	"fee", "billing_counter", "invoice", "invoice_line", "payment", "report_verification",
+}
+
+// Counters keep the higher value so numbers issued after an import do not repeat imported ones
+var importConflicts = map[string]string{
+	"queue_counter":   "ON CONFLICT (doctor_id, queue_date) DO UPDATE SET last_number = GREATEST(queue_counter.last_number, EXCLUDED.last_number)",
+	"billing_counter": "ON CONFLICT (counter, year) DO UPDATE SET last_value = GREATEST(billing_counter.last_value, EXCLUDED.last_value)",
+}
+
+type DoctorRecord struct {
+	DoctorID       uuid.UUID `json:"doctor_id"`
+	Fullname       string    `json:"fullname"`
+	Email          string    `json:"email"`
+	Specialization string    `json:"specialization"`
+	QueuePrefix    string    `json:"queue_prefix"`
+	PasswordHash   string    `json:"password_hash"`
+	IsActive       bool      `json:"is_active"`
+	MFARequired    bool      `json:"mfa_required"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type StaffRecord struct {
+	StaffID      uuid.UUID `json:"staff_id"`
+	Fullname     string    `json:"fullname"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"password_hash"`
+	IsActive     bool      `json:"is_active"`
+	MFARequired  bool      `json:"mfa_required"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type PatientRecord struct {
+	PatientID  uuid.UUID `json:"patient_id"`
+	Fullname   string    `json:"fullname"`
+	Gender     string    `json:"gender"`
+	Age        int       `json:"age"`
+	Contact    string    `json:"contact"`
+	Symptoms   string    `json:"symptoms"`
+	Treatment  string    `json:"treatment"`
+	AssignedTo uuid.UUID `json:"assigned_to"`
+	CreatedBy  uuid.UUID `json:"created_by"`
+	TokenID    int       `json:"token_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type EncounterRecord struct {
+	EncounterID    uuid.UUID     `json:"encounter_id"`
+	PatientID      uuid.UUID     `json:"patient_id"`
+	DoctorID       uuid.UUID     `json:"doctor_id"`
+	VisitDate      string        `json:"visit_date"`
+	ChiefComplaint string        `json:"chief_complaint"`
+	Findings       string        `json:"findings"`
+	Treatment      string        `json:"treatment"`
+	Status         string        `json:"status"`
+	OpenedBy       uuid.UUID     `json:"opened_by"`
+	ClosedBy       uuid.NullUUID `json:"closed_by"`
+	ClosedAt       *time.Time    `json:"closed_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+type DataExport struct {
+	FormatVersion int               `json:"format_version"`
+	ExportedAt    time.Time         `json:"exported_at"`
+	Doctors       []DoctorRecord    `json:"doctors"`
+	Staff         []StaffRecord     `json:"staff"`
+	Patients      []PatientRecord   `json:"patients"`
+	Encounters    []EncounterRecord `json:"encounters"`
+	// rows of the other exported tables as JSON arrays, keyed by table name
+	Records map[string]json.RawMessage `json:"records"`
+}
+
+// Number of rows inserted per table, rows already present are skipped
+type ImportResult struct {
+	Doctors    int64            `json:"doctors"`
+	Staff      int64            `json:"staff"`
+	Patients   int64            `json:"patients"`
+	Encounters int64            `json:"encounters"`
+	Records    map[string]int64 `json:"records"`
+}
+
+// Reads accounts, patients, visits and the records of every exported table from a single consistent snapshot
+func (rec *Store) ExportData() (*DataExport, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tx, err := rec.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export := &DataExport{FormatVersion: ExportFormatVersion, ExportedAt: time.Now().UTC(),
+		Doctors: []DoctorRecord{}, Staff: []StaffRecord{}, Patients: []PatientRecord{}, Encounters: []EncounterRecord{}, Records: map[string]json.RawMessage{}}
+
+	rows, err := tx.QueryContext(ctx, "SELECT doctor_id, fullname, email, COALESCE(specialization, ''), COALESCE(queue_prefix, ''), password_hash, is_active, mfa_required, updated_at, created_at FROM doctor ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var record DoctorRecord
+		if err = rows.Scan(&record.DoctorID, &record.Fullname, &record.Email, &record.Specialization, &record.QueuePrefix, &record.PasswordHash, &record.IsActive, &record.MFARequired, &record.UpdatedAt, &record.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Doctors = append(export.Doctors, record)
+	}
+	rows.Close()
+
+	rows, err = tx.QueryContext(ctx, "SELECT staff_id, fullname, email, role, password_hash, is_active, mfa_required, updated_at, created_at FROM staff ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var record StaffRecord
+		if err = rows.Scan(&record.StaffID, &record.Fullname, &record.Email, &record.Role, &record.PasswordHash, &record.IsActive, &record.MFARequired, &record.UpdatedAt, &record.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Staff = append(export.Staff, record)
+	}
+	rows.Close()
+
+	rows, err = tx.QueryContext(ctx, "SELECT patient_id, fullname, gender, age, contact, COALESCE(symptoms, ''), COALESCE(treatment, ''), assigned_to, created_by, token_id, updated_at, created_at FROM patient ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var record PatientRecord
+		if err = rows.Scan(&record.PatientID, &record.Fullname, &record.Gender, &record.Age, &record.Contact, &record.Symptoms, &record.Treatment, &record.AssignedTo, &record.CreatedBy, &record.TokenID, &record.UpdatedAt, &record.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Patients = append(export.Patients, record)
+	}
+	rows.Close()
+
+	rows, err = tx.QueryContext(ctx, "SELECT encounter_id, patient_id, doctor_id, to_char(visit_date, 'YYYY-MM-DD'), chief_complaint, findings, treatment, status, opened_by, closed_by, closed_at, updated_at, created_at FROM encounter ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var record EncounterRecord
+		if err = rows.Scan(&record.EncounterID, &record.PatientID, &record.DoctorID, &record.VisitDate, &record.ChiefComplaint, &record.Findings, &record.Treatment, &record.Status,
+			&record.OpenedBy, &record.ClosedBy, &record.ClosedAt, &record.UpdatedAt, &record.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Encounters = append(export.Encounters, record)
+	}
+	rows.Close()
+
+	for _, table := range exportTables {
+		var records []byte
+		if err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(json_agg(t), '[]'::json) FROM %s t", table)).Scan(&records); err != nil {
+			return nil, fmt.Errorf("%s: %w", table, err)
+		}
+		export.Records[table] = records
+	}
+
+	return export, nil
+}
+
+// Inserts exported records in one transaction, skipping rows whose ID, email, token or other unique value already exist.
+// Files of format version 1 only hold accounts, patients and visits.
+func (rec *Store) ImportData(data *DataExport) (ImportResult, error) {
+
+	result := ImportResult{Records: map[string]int64{}}
+
+	if data.FormatVersion < 1 || data.FormatVersion > ExportFormatVersion {
+		return result, fmt.Errorf("unsupported export format version %d, expected %d or lower", data.FormatVersion, ExportFormatVersion)
+	}
+
+	known := make(map[string]bool, len(exportTables))
+	for _, table := range exportTables {
+		known[table] = true
+	}
+	for table := range data.Records {
+		if !known[table] {
+			return result, fmt.Errorf("export file has records of unknown table %q", table)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		}
+	}()
+
+	inserted := func(res sql.Result, execErr error) (int64, error) {
+		if execErr != nil {
+			return 0, execErr
+		}
+		return res.RowsAffected()
+	}
+
+	for _, record := range data.Doctors {
+		var count int64
+		count, err = inserted(tx.ExecContext(ctx, "INSERT INTO doctor (doctor_id, fullname, email, specialization, queue_prefix, password_hash, is_active, mfa_required, updated_at, created_at) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10) ON CONFLICT DO NOTHING",
+			record.DoctorID, record.Fullname, record.Email, record.Specialization, record.QueuePrefix, record.PasswordHash, record.IsActive, record.MFARequired, record.UpdatedAt, record.CreatedAt))
+		if err != nil {
+			err = fmt.Errorf("doctor %s: %w", record.DoctorID, err)
+			return result, err
+		}
+		result.Doctors += count
+	}
+
+	for _, record := range data.Staff {
+		var count int64
+		count, err = inserted(tx.ExecContext(ctx, "INSERT INTO staff (staff_id, fullname, email, role, password_hash, is_active, mfa_required, updated_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT DO NOTHING",
+			record.StaffID, record.Fullname, record.Email, record.Role, record.PasswordHash, record.IsActive, record.MFARequired, record.UpdatedAt, record.CreatedAt))
+		if err != nil {
+			err = fmt.Errorf("staff %s: %w", record.StaffID, err)
+			return result, err
+		}
+		result.Staff += count
+	}
+
+	for _, record := range data.Patients {
+		var count int64
+		count, err = inserted(tx.ExecContext(ctx, "INSERT INTO patient (patient_id, fullname, gender, age, contact, symptoms, treatment, assigned_to, created_by, token_id, updated_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT DO NOTHING",
+			record.PatientID, record.Fullname, record.Gender, record.Age, record.Contact, record.Symptoms, record.Treatment, record.AssignedTo, record.CreatedBy, record.TokenID, record.UpdatedAt, record.CreatedAt))
+		if err != nil {
+			err = fmt.Errorf("patient %s: %w", record.PatientID, err)
+			return result, err
+		}
+		result.Patients += count
+	}
+
+	for _, record := range data.Encounters {
+		var count int64
+		count, err = inserted(tx.ExecContext(ctx, "INSERT INTO encounter (encounter_id, patient_id, doctor_id, visit_date, chief_complaint, findings, treatment, status, opened_by, closed_by, closed_at, updated_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) ON CONFLICT DO NOTHING",
+			record.EncounterID, record.PatientID, record.DoctorID, record.VisitDate, record.ChiefComplaint, record.Findings, record.Treatment, record.Status,
+			record.OpenedBy, record.ClosedBy, record.ClosedAt, record.UpdatedAt, record.CreatedAt))
+		if err != nil {
+			err = fmt.Errorf("visit %s: %w", record.EncounterID, err)
+			return result, err
+		}
+		result.Encounters += count
+	}
+
+	for _, table := range exportTables {
+		records, ok := data.Records[table]
+		if !ok {
+			continue
+		}
+		conflict, ok := importConflicts[table]
+		if !ok {
+			conflict = "ON CONFLICT DO NOTHING"
+		}
+		var count int64
+		count, err = inserted(tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s SELECT * FROM json_populate_recordset(NULL::%s, $1::json) %s", table, table, conflict), string(records)))
+		if err != nil {
+			err = fmt.Errorf("%s: %w", table, err)
+			return result, err
+		}
+		result.Records[table] = count
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
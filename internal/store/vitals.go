@@ -0,0 +1,222 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshitrajsinha/medi-go/internal/models"
+	"github.com/lib/pq"
+)
+
+type vitalsQueryResponse struct {
+	VitalSignID string  `json:"vital_sign_id"`
+	EncounterID *string `json:"encounter_id"`
+	models.VitalReading
+	BMI        *float64           `json:"bmi"`
+	Flags      []models.VitalFlag `json:"flags"`
+	RecordedBy string             `json:"recorded_by"`
+	RecordedAt string             `json:"recorded_at"`
+}
+
+const vitalsColumns = "v.vital_sign_id, v.encounter_id, v.systolic, v.diastolic, v.temperature, v.pulse, v.spo2, v.weight, v.height, v.recorded_by, v.recorded_at"
+
+func scanVitals(row rowScanner, extra ...any) (vitalsQueryResponse, error) {
+	var queryData vitalsQueryResponse
+	dest := []any{&queryData.VitalSignID, &queryData.EncounterID, &queryData.Systolic, &queryData.Diastolic, &queryData.Temperature, &queryData.Pulse, &queryData.SpO2,
+		&queryData.Weight, &queryData.Height, &queryData.RecordedBy, &queryData.RecordedAt}
+	err := row.Scan(append(dest, extra...)...)
+	queryData.BMI = queryData.VitalReading.BMI()
+	return queryData, err
+}
+
+// Reads the configured normal ranges of vitals
+func loadVitalRanges(ctx context.Context, q querier) ([]models.VitalRange, error) {
+
+	rows, err := q.QueryContext(ctx, "SELECT vital, COALESCE(gender, ''), min_age, max_age, low, high FROM vital_range ORDER BY vital, min_age, gender NULLS FIRST")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranges := make([]models.VitalRange, 0)
+	for rows.Next() {
+		var vitalRange models.VitalRange
+		if err = rows.Scan(&vitalRange.Vital, &vitalRange.Gender, &vitalRange.MinAge, &vitalRange.MaxAge, &vitalRange.Low, &vitalRange.High); err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, vitalRange)
+	}
+	return ranges, nil
+}
+
+// Queries INSERT to record vitals of a patient, linked to the patient's open visit when there is one.
+// The saved reading is returned with values outside the normal ranges flagged.
+func (rec *Store) RecordVitals(tokenID string, reading *models.VitalReading, recordedBy uuid.UUID) (vitalsQueryResponse, error) {
+
+	var patientID uuid.UUID
+	var age int
+	var gender string
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	err := rec.db.QueryRowContext(ctx, "SELECT patient_id, age, gender FROM patient WHERE token_id::text=$1", tokenID).Scan(&patientID, &age, &gender)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return vitalsQueryResponse{}, ErrPatientNotFound
+		}
+		return vitalsQueryResponse{}, err
+	}
+
+	queryData, err := scanVitals(rec.db.QueryRowContext(ctx, `INSERT INTO vital_sign AS v (patient_id, encounter_id, systolic, diastolic, temperature, pulse, spo2, weight, height, recorded_by)
+		VALUES ($1, (SELECT encounter_id FROM encounter WHERE patient_id=$1 AND status='open'), $2, $3, $4, $5, $6, $7, $8, $9) RETURNING `+vitalsColumns,
+		patientID, reading.Systolic, reading.Diastolic, reading.Temperature, reading.Pulse, reading.SpO2, reading.Weight, reading.Height, recordedBy))
+	if err != nil {
+		return vitalsQueryResponse{}, err
+	}
+
+	ranges, err := loadVitalRanges(ctx, rec.db)
+	if err != nil {
+		return vitalsQueryResponse{}, err
+	}
+	queryData.Flags = models.FlagVitals(queryData.VitalReading, ranges, age, gender)
+
+	return queryData, nil
+}
+
+// Queries vitals of a patient newest first, restricted to one visit when encounterID is set
+func (rec *Store) GetVitals(tokenID string, encounterID string, limit int32, offset int32) (interface{}, error) {
+
+	var total_records int32
+	var age int
+	var gender string
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	err := rec.db.QueryRowContext(ctx, "SELECT age, gender FROM patient WHERE token_id::text=$1", tokenID).Scan(&age, &gender)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPatientNotFound
+		}
+		return nil, err
+	}
+
+	ranges, err := loadVitalRanges(ctx, rec.db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := rec.db.QueryContext(ctx, "SELECT "+vitalsColumns+", count(*) over() as total_records FROM vital_sign v JOIN patient p ON p.patient_id=v.patient_id WHERE p.token_id::text=$1 AND ($2 = '' OR v.encounter_id::text=$2) ORDER BY v.recorded_at DESC LIMIT $3 OFFSET $4",
+		tokenID, encounterID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// slice to store all rows
+	allVitalsData := make([]vitalsQueryResponse, 0)
+	responseData := make([]interface{}, 2)
+
+	// Get each row data into a slice
+	for rows.Next() {
+		queryData, err := scanVitals(rows, &total_records)
+		if err != nil {
+			return nil, err
+		}
+		queryData.Flags = models.FlagVitals(queryData.VitalReading, ranges, age, gender)
+		allVitalsData = append(allVitalsData, queryData)
+	}
+
+	responseData[0] = map[string][]vitalsQueryResponse{"vitals_data": allVitalsData}
+	responseData[1] = map[string]int32{"total_no_records": total_records}
+
+	return responseData, nil
+}
+
+// Flags the latest vitals of each listed patient, keyed by token ID
+func (rec *Store) latestVitalFlags(ctx context.Context, tokenIDs []string) (map[string][]models.VitalFlag, error) {
+
+	flags := make(map[string][]models.VitalFlag)
+	if len(tokenIDs) == 0 {
+		return flags, nil
+	}
+
+	ranges, err := loadVitalRanges(ctx, rec.db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := rec.db.QueryContext(ctx, `SELECT DISTINCT ON (v.patient_id) p.token_id, p.age, p.gender, v.systolic, v.diastolic, v.temperature, v.pulse, v.spo2, v.weight, v.height
+		FROM vital_sign v JOIN patient p ON p.patient_id=v.patient_id WHERE p.token_id::text = ANY($1) ORDER BY v.patient_id, v.recorded_at DESC`, pq.Array(tokenIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tokenID, gender string
+		var age int
+		var reading models.VitalReading
+		if err = rows.Scan(&tokenID, &age, &gender, &reading.Systolic, &reading.Diastolic, &reading.Temperature, &reading.Pulse, &reading.SpO2, &reading.Weight, &reading.Height); err != nil {
+			return nil, err
+		}
+		if patientFlags := models.FlagVitals(reading, ranges, age, gender); len(patientFlags) > 0 {
+			flags[tokenID] = patientFlags
+		}
+	}
+	return flags, nil
+}
+
+// Queries the configured normal ranges of vitals
+func (rec *Store) GetVitalRanges() ([]models.VitalRange, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	return loadVitalRanges(ctx, rec.db)
+}
+
+// Replaces all normal ranges of vitals in one transaction
+func (rec *Store) SetVitalRanges(ranges []models.VitalRange) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second) // if database takes too long, the query should be cancelled automatically after 45 seconds
+	defer cancel()
+
+	// Begin DB transaction
+	tx, err := rec.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Println("Transaction rollback error: ", rbErr)
+			}
+		} else {
+			if cmErr := tx.Commit(); cmErr != nil {
+				log.Println("Transaction commit error: ", cmErr)
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM vital_range"); err != nil {
+		return err
+	}
+
+	for _, vitalRange := range ranges {
+		_, err = tx.ExecContext(ctx, "INSERT INTO vital_range (vital, gender, min_age, max_age, low, high) VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)",
+			vitalRange.Vital, vitalRange.Gender, vitalRange.MinAge, vitalRange.MaxAge, vitalRange.Low, vitalRange.High)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}